@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"bookstore.com/services"
+)
+
+// adminKeyHeader carries the key giving access to the back office endpoints
+const adminKeyHeader = "X-Admin-Key"
+
+// adminKey is set once at startup by SetAdminKey; while it is empty the
+// back office endpoints are refused
+var adminKey string
+
+func SetAdminKey(key string) {
+	adminKey = key
+}
+
+// requireAdmin answers 403 unless the request carries the admin key
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !isAdmin(r) {
+		http.Error(w, "Admin key required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func isAdmin(r *http.Request) bool {
+	given := r.Header.Get(adminKeyHeader)
+	return adminKey != "" && subtle.ConstantTimeCompare([]byte(given), []byte(adminKey)) == 1
+}
+
+// actorOf names who makes a request for the audit log: the admin, the
+// customer signed in with a bearer token, or anonymous
+func actorOf(r *http.Request) string {
+	if isAdmin(r) {
+		return services.AdminActor
+	}
+	if token := bearerToken(r); token != "" && AuthInstance != nil {
+		if session, err := AuthInstance.AuthService.Authenticate(token); err == nil {
+			return services.CustomerActor(session.CustomerID)
+		}
+	}
+	return "anonymous"
+}
+
+// requireCustomer answers 401 unless the request carries the bearer token
+// of a session, and returns the customer signed in
+func requireCustomer(w http.ResponseWriter, r *http.Request) (customerID int, ok bool) {
+	if token := bearerToken(r); token != "" && AuthInstance != nil {
+		if session, err := AuthInstance.AuthService.Authenticate(token); err == nil {
+			return session.CustomerID, true
+		}
+	}
+	http.Error(w, "Login required", http.StatusUnauthorized)
+	return 0, false
+}
+
+// requireOwner lets the request through when it carries the admin key or the
+// bearer token of the customer given; otherwise it answers 401 without a
+// session and 403 with the session of another customer
+func requireOwner(w http.ResponseWriter, r *http.Request, customerID int) bool {
+	if isAdmin(r) {
+		return true
+	}
+	signedIn, ok := requireCustomer(w, r)
+	if !ok {
+		return false
+	}
+	if signedIn != customerID {
+		http.Error(w, "Not allowed for another customer", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireOrderOwner is requireOwner for the customer who placed the order;
+// it answers 404 to a signed in customer when the order does not exist
+func requireOrderOwner(w http.ResponseWriter, r *http.Request, orderID int) bool {
+	if isAdmin(r) {
+		return true
+	}
+	if _, ok := requireCustomer(w, r); !ok {
+		return false
+	}
+	order, err := OrderInstance.OrderService.GetOrder(orderID)
+	if err != nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return false
+	}
+	return requireOwner(w, r, order.Customer.ID)
+}
+
+// includeDeletedParam reads the include_deleted query parameter, which only
+// admins may set; it answers 403 itself and returns false when refused
+func includeDeletedParam(w http.ResponseWriter, r *http.Request) (includeDeleted bool, allowed bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+	if !requireAdmin(w, r) {
+		return false, false
+	}
+	return true, true
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuditHandler gives the admin read access to the audit log
+type AuditHandler struct {
+	AuditService *services.AuditService
+}
+
+var (
+	AuditInstance *AuditHandler
+	AuditOnce     sync.Once
+)
+
+// NewAuditHandler initializes a singleton instance of AuditHandler.
+func NewAuditHandler(AuditService *services.AuditService) *AuditHandler {
+	AuditOnce.Do(func() {
+		AuditInstance = &AuditHandler{AuditService: AuditService}
+	})
+	return AuditInstance
+}
+
+// GetAuditEntries lists the audit entries, oldest first, filtered by the
+// optional entity, id, actor, action, from and to query parameters
+func (h *AuditHandler) GetAuditEntries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("AuditHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	query, err := auditQuery(r)
+	if err != nil {
+		log.Printf("AuditHandler.Search: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.AuditService.SearchEntries(query)
+	if err != nil {
+		log.Printf("AuditHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Printf("AuditHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuditHandler.Search: success, returned %d entries, duration: %v", len(entries), time.Since(start))
+}
+
+// ExportAuditEntries streams the entries matching the same filters as
+// GetAuditEntries as NDJSON
+func (h *AuditHandler) ExportAuditEntries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("AuditHandler.Export: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	query, err := auditQuery(r)
+	if err != nil {
+		log.Printf("AuditHandler.Export: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	if err := h.AuditService.ExportEntries(w, query); err != nil {
+		log.Printf("AuditHandler.Export: export error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuditHandler.Export: success, duration: %v", time.Since(start))
+}
+
+// auditQuery builds the search criteria from the query parameters
+func auditQuery(r *http.Request) (models.SearchCriteria, error) {
+	query := models.SearchCriteria{Filters: make(map[string]interface{})}
+	for _, name := range []string{"entity", "actor", "action"} {
+		if value := r.URL.Query().Get(name); value != "" {
+			query.Filters[name] = value
+		}
+	}
+	if value := r.URL.Query().Get("id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return models.SearchCriteria{}, fmt.Errorf("Invalid id: %q", value)
+		}
+		query.Filters["entity_id"] = id
+	}
+	for _, name := range []string{"from", "to"} {
+		t, err := parseTimeParam(r, name)
+		if err != nil {
+			return models.SearchCriteria{}, err
+		}
+		if !t.IsZero() {
+			query.Filters[name] = t
+		}
+	}
+	return query, nil
+}
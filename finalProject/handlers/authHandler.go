@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuthHandler handles customer registration, login and password management.
+type AuthHandler struct {
+	AuthService *services.AuthService
+}
+
+var (
+	AuthInstance *AuthHandler
+	AuthOnce     sync.Once
+)
+
+// NewAuthHandler initializes a singleton instance of AuthHandler.
+func NewAuthHandler(AuthService *services.AuthService) *AuthHandler {
+	AuthOnce.Do(func() {
+		AuthInstance = &AuthHandler{AuthService: AuthService}
+	})
+	return AuthInstance
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var registration models.Registration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		log.Printf("AuthHandler.Register: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.AuthService.Register(registration)
+	if err != nil {
+		log.Printf("AuthHandler.Register: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		log.Printf("AuthHandler.Register: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuthHandler.Register: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var request models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("AuthHandler.Login: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.AuthService.Login(request)
+	if err != nil {
+		log.Printf("AuthHandler.Login: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		log.Printf("AuthHandler.Login: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuthHandler.Login: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if err := h.AuthService.Logout(bearerToken(r)); err != nil {
+		log.Printf("AuthHandler.Logout: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("AuthHandler.Logout: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var change models.PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		log.Printf("AuthHandler.ChangePassword: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.As(actorOf(r)).ChangePassword(bearerToken(r), change); err != nil {
+		log.Printf("AuthHandler.ChangePassword: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("AuthHandler.ChangePassword: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var change models.EmailChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		log.Printf("AuthHandler.ChangeEmail: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.AuthService.As(actorOf(r)).ChangeEmail(bearerToken(r), change)
+	if err != nil {
+		log.Printf("AuthHandler.ChangeEmail: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	setETag(w, customer.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		log.Printf("AuthHandler.ChangeEmail: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuthHandler.ChangeEmail: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var request models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("AuthHandler.RequestPasswordReset: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.As(actorOf(r)).RequestPasswordReset(request); err != nil {
+		log.Printf("AuthHandler.RequestPasswordReset: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	log.Printf("AuthHandler.RequestPasswordReset: success, duration: %v", time.Since(start))
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var confirmation models.PasswordResetConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&confirmation); err != nil {
+		log.Printf("AuthHandler.ResetPassword: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.As(actorOf(r)).ResetPassword(confirmation); err != nil {
+		log.Printf("AuthHandler.ResetPassword: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), authErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("AuthHandler.ResetPassword: success, duration: %v", time.Since(start))
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func authErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrInvalidSession):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrInvalidResetToken):
+		return http.StatusBadRequest
+	case errors.Is(err, repositories.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
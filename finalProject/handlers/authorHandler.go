@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 	"bookstore.com/services"
 	"github.com/julienschmidt/httprouter"
 )
@@ -39,7 +42,12 @@ func (h *AuthorHandler) CreateAuthor(w http.ResponseWriter, r *http.Request, _ h
 		return
 	}
 
-	createdAuthor, err := h.AuthorService.CreateAuthor(author)
+	createdAuthor, err := h.AuthorService.As(actorOf(r)).CreateAuthor(author)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("AuthorHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		log.Printf("AuthorHandler.Create: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
@@ -47,6 +55,7 @@ func (h *AuthorHandler) CreateAuthor(w http.ResponseWriter, r *http.Request, _ h
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdAuthor.Version)
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(createdAuthor); err != nil {
 		log.Printf("AuthorHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
@@ -73,6 +82,12 @@ func (h *AuthorHandler) GetAuthorById(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	setETag(w, author.Version)
+	if notModified(w, r, author.Version) {
+		log.Printf("AuthorHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(author); err != nil {
 		log.Printf("AuthorHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
@@ -85,7 +100,13 @@ func (h *AuthorHandler) GetAuthorById(w http.ResponseWriter, r *http.Request, ps
 func (h *AuthorHandler) GetAuthorsByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	start := time.Now()
 
-	var query = models.SearchCriteria{Filters: make(map[string]interface{})}
+	includeDeleted, allowed := includeDeletedParam(w, r)
+	if !allowed {
+		log.Printf("AuthorHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var query = models.SearchCriteria{Filters: make(map[string]interface{}), IncludeDeleted: includeDeleted}
 	if err := json.NewDecoder(r.Body).Decode(&query.Filters); err != nil {
 		query.Filters = make(map[string]interface{})
 		log.Printf("AuthorHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
@@ -117,6 +138,13 @@ func (h *AuthorHandler) UpdateAuthorById(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("AuthorHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
 	var author models.Author
 	if err = json.NewDecoder(r.Body).Decode(&author); err != nil {
 		log.Printf("AuthorHandler.Update: invalid input error: %v, duration: %v", err, time.Since(start))
@@ -124,14 +152,26 @@ func (h *AuthorHandler) UpdateAuthorById(w http.ResponseWriter, r *http.Request,
 		return
 	}
 	author.ID = id
+	author.Version = version
 
-	updatedAuthor, err := h.AuthorService.UpdateAuthor(author)
+	updatedAuthor, err := h.AuthorService.As(actorOf(r)).UpdateAuthor(author)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("AuthorHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("AuthorHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Author was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Printf("AuthorHandler.Update: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Author not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
+	setETag(w, updatedAuthor.Version)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(updatedAuthor); err != nil {
 		log.Printf("AuthorHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
@@ -141,6 +181,50 @@ func (h *AuthorHandler) UpdateAuthorById(w http.ResponseWriter, r *http.Request,
 	log.Printf("AuthorHandler.Update: success, duration: %v", time.Since(start))
 }
 
+// PatchAuthorById applies a JSON Merge Patch or JSON Patch to an author. If-Match is optional.
+func (h *AuthorHandler) PatchAuthorById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("AuthorHandler.Patch: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Author ID", http.StatusBadRequest)
+		return
+	}
+
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		if version, err = ifMatchVersion(r); err != nil {
+			log.Printf("AuthorHandler.Patch: precondition error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, err.Error(), preconditionStatus(err))
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("AuthorHandler.Patch: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	patchedAuthor, err := h.AuthorService.As(actorOf(r)).PatchAuthor(id, version, patch, r.Header.Get("Content-Type"))
+	if err != nil {
+		log.Printf("AuthorHandler.Patch: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), patchErrorStatus(w, err))
+		return
+	}
+
+	setETag(w, patchedAuthor.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(patchedAuthor); err != nil {
+		log.Printf("AuthorHandler.Patch: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuthorHandler.Patch: success, duration: %v", time.Since(start))
+}
+
 func (h *AuthorHandler) DeleteAuthorById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	start := time.Now()
 
@@ -151,7 +235,20 @@ func (h *AuthorHandler) DeleteAuthorById(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if err = h.AuthorService.DeleteAuthor(id); err != nil {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("AuthorHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.AuthorService.As(actorOf(r)).DeleteAuthor(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("AuthorHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Author was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
 		log.Printf("AuthorHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Author not found: "+err.Error(), http.StatusNotFound)
 		return
@@ -160,3 +257,37 @@ func (h *AuthorHandler) DeleteAuthorById(w http.ResponseWriter, r *http.Request,
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("AuthorHandler.Delete: success, duration: %v", time.Since(start))
 }
+
+// RestoreAuthorById brings back an author that was deleted and not purged yet;
+// only admins may restore
+func (h *AuthorHandler) RestoreAuthorById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("AuthorHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("AuthorHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Author ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.AuthorService.As(actorOf(r)).RestoreAuthor(id)
+	if err != nil {
+		log.Printf("AuthorHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Author not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(restored); err != nil {
+		log.Printf("AuthorHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("AuthorHandler.Restore: success, duration: %v", time.Since(start))
+}
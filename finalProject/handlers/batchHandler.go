@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	batchPath          = "/batch"
+	maxBatchOperations = 100
+)
+
+// Snapshotter captures the stores so an atomic batch can be rolled back.
+type Snapshotter interface {
+	Snapshot() (restore func())
+}
+
+// BatchHandler runs several sub-requests against the API in one call.
+type BatchHandler struct {
+	Router http.Handler
+	Store  Snapshotter
+	// gate keeps other requests and the background jobs out while an atomic
+	// batch runs, so that rolling back never drops their changes
+	gate *sync.RWMutex
+}
+
+var (
+	BatchInstance *BatchHandler
+	BatchOnce     sync.Once
+)
+
+// NewBatchHandler initializes a singleton instance of BatchHandler. Sub-requests
+// are served by router; the background jobs changing the stores hold gate
+// for reading while they run.
+func NewBatchHandler(router http.Handler, store Snapshotter, gate *sync.RWMutex) *BatchHandler {
+	BatchOnce.Do(func() {
+		BatchInstance = &BatchHandler{Router: router, Store: store, gate: gate}
+	})
+	return BatchInstance
+}
+
+// Guard wraps the API so that requests wait while an atomic batch runs.
+// Batches are not guarded here, RunBatch takes the gate itself: for writing
+// when atomic, for reading otherwise.
+func (h *BatchHandler) Guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == batchPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.gate.RLock()
+		defer h.gate.RUnlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *BatchHandler) RunBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	var batch models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		log.Printf("BatchHandler.RunBatch: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateBatch(batch); err != nil {
+		log.Printf("BatchHandler.RunBatch: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := models.BatchResponse{
+		Atomic:    batch.Atomic,
+		Committed: true,
+		Results:   make([]models.BatchResult, 0, len(batch.Operations)),
+	}
+	if batch.Atomic {
+		h.gate.Lock()
+		restore := h.Store.Snapshot()
+		for i, operation := range batch.Operations {
+			result := h.runOperation(r, operation)
+			response.Results = append(response.Results, result)
+			if result.Status >= http.StatusBadRequest {
+				restore()
+				response.Committed = false
+				for range batch.Operations[i+1:] {
+					response.Results = append(response.Results, models.BatchResult{
+						Status: http.StatusFailedDependency,
+						Body:   fmt.Sprintf("not run: operation %d failed", i),
+					})
+				}
+				break
+			}
+		}
+		h.gate.Unlock()
+	} else {
+		// the sub-requests skip Guard, so the batch holds the gate for them
+		h.gate.RLock()
+		for _, operation := range batch.Operations {
+			response.Results = append(response.Results, h.runOperation(r, operation))
+		}
+		h.gate.RUnlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("BatchHandler.RunBatch: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("BatchHandler.RunBatch: success, operations: %d, atomic: %v, committed: %v, duration: %v",
+		len(batch.Operations), batch.Atomic, response.Committed, time.Since(start))
+}
+
+func validateBatch(batch models.BatchRequest) error {
+	if len(batch.Operations) == 0 {
+		return errors.New("batch has no operations")
+	}
+	if len(batch.Operations) > maxBatchOperations {
+		return fmt.Errorf("batch has %d operations, at most %d are allowed", len(batch.Operations), maxBatchOperations)
+	}
+	for i, operation := range batch.Operations {
+		switch strings.ToUpper(operation.Method) {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return fmt.Errorf("operation %d: unsupported method %q", i, operation.Method)
+		}
+		if !strings.HasPrefix(operation.Path, "/") {
+			return fmt.Errorf("operation %d: path must start with /", i)
+		}
+		if path, _, _ := strings.Cut(operation.Path, "?"); path == batchPath {
+			return fmt.Errorf("operation %d: batches cannot be nested", i)
+		}
+	}
+	return nil
+}
+
+// runOperation sends one sub-request through the router. It carries the
+// headers and client address of the batch request, so authentication,
+// preconditions and rate limits behave as for a standalone call.
+func (h *BatchHandler) runOperation(r *http.Request, operation models.BatchOperation) models.BatchResult {
+	body := []byte(operation.Body)
+	var text string
+	if json.Unmarshal(operation.Body, &text) == nil {
+		body = []byte(text)
+	}
+
+	request, err := http.NewRequestWithContext(r.Context(), strings.ToUpper(operation.Method), operation.Path, bytes.NewReader(body))
+	if err != nil {
+		return models.BatchResult{Status: http.StatusBadRequest, Body: "Invalid operation: " + err.Error()}
+	}
+	request.RemoteAddr = r.RemoteAddr
+	for name, values := range r.Header {
+		if name != "Content-Type" && name != "Content-Length" {
+			request.Header[name] = values
+		}
+	}
+	if len(body) > 0 {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range operation.Headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := newBatchRecorder()
+	h.Router.ServeHTTP(recorder, request)
+	return recorder.result()
+}
+
+// batchRecorder collects a sub-request response. DispatcherWrapper may give
+// up on a slow handler that keeps writing, hence the lock.
+type batchRecorder struct {
+	mu     sync.Mutex
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(p []byte) (int, error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
+
+func (rec *batchRecorder) result() models.BatchResult {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	result := models.BatchResult{Status: rec.status, Headers: make(map[string]string)}
+	if result.Status == 0 {
+		// DispatcherWrapper gave up on the handler before it answered
+		result.Status = http.StatusGatewayTimeout
+		result.Body = "operation did not finish in time"
+		return result
+	}
+	for name := range rec.header {
+		result.Headers[name] = rec.header.Get(name)
+	}
+
+	raw, _ := io.ReadAll(&rec.body)
+	switch {
+	case len(raw) == 0:
+	case strings.HasPrefix(rec.header.Get("Content-Type"), "application/json") && json.Valid(raw):
+		result.Body = json.RawMessage(raw)
+	default:
+		result.Body = strings.TrimSuffix(string(raw), "\n")
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// newBatchTestHandler serves the sub-requests with a router creating authors
+// in the in-memory store, and failing on /fail
+func newBatchTestHandler(t *testing.T) (*BatchHandler, *memory.InMemoryAuthorStore) {
+	t.Helper()
+
+	store, err := memory.NewInMemoryStore()
+	if err != nil {
+		t.Fatalf("NewInMemoryStore() error = %v", err)
+	}
+
+	router := httprouter.New()
+	router.POST("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var author models.Author
+		if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, _ := store.AuthorStore.Create(author)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	})
+	router.POST("/fail", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		http.Error(w, "conflict", http.StatusConflict)
+	})
+
+	// not the singleton, so every test gets its own handler
+	return &BatchHandler{Router: router, Store: store, gate: &sync.RWMutex{}}, store.AuthorStore
+}
+
+func TestRunBatch(t *testing.T) {
+	createAuthor := models.BatchOperation{Method: "POST", Path: "/authors", Body: json.RawMessage(`{"first_name":"Ann"}`)}
+	fail := models.BatchOperation{Method: "POST", Path: "/fail"}
+
+	tests := []struct {
+		name          string
+		batch         models.BatchRequest
+		wantStatuses  []int
+		wantCommitted bool
+		wantCreated   int
+	}{
+		{
+			name:          "atomic batch succeeding",
+			batch:         models.BatchRequest{Atomic: true, Operations: []models.BatchOperation{createAuthor, createAuthor}},
+			wantStatuses:  []int{http.StatusCreated, http.StatusCreated},
+			wantCommitted: true,
+			wantCreated:   2,
+		},
+		{
+			name:          "atomic batch rolled back",
+			batch:         models.BatchRequest{Atomic: true, Operations: []models.BatchOperation{createAuthor, fail, createAuthor}},
+			wantStatuses:  []int{http.StatusCreated, http.StatusConflict, http.StatusFailedDependency},
+			wantCommitted: false,
+			wantCreated:   0,
+		},
+		{
+			name:          "failure at the first operation",
+			batch:         models.BatchRequest{Atomic: true, Operations: []models.BatchOperation{fail, createAuthor}},
+			wantStatuses:  []int{http.StatusConflict, http.StatusFailedDependency},
+			wantCommitted: false,
+			wantCreated:   0,
+		},
+		{
+			name:          "non atomic batch keeps going",
+			batch:         models.BatchRequest{Operations: []models.BatchOperation{createAuthor, fail, createAuthor}},
+			wantStatuses:  []int{http.StatusCreated, http.StatusConflict, http.StatusCreated},
+			wantCommitted: true,
+			wantCreated:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, authors := newBatchTestHandler(t)
+			before, _ := authors.Search(models.SearchCriteria{})
+
+			body, _ := json.Marshal(tt.batch)
+			w := httptest.NewRecorder()
+			handler.RunBatch(w, httptest.NewRequest(http.MethodPost, batchPath, strings.NewReader(string(body))), nil)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("RunBatch() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			var response models.BatchResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			statuses := make([]int, 0, len(response.Results))
+			for _, result := range response.Results {
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.wantStatuses)
+			}
+			if response.Committed != tt.wantCommitted {
+				t.Errorf("committed = %v, want %v", response.Committed, tt.wantCommitted)
+			}
+
+			after, _ := authors.Search(models.SearchCriteria{})
+			if created := len(after) - len(before); created != tt.wantCreated {
+				t.Errorf("%d authors created, want %d", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestBatchRecorderGaveUp(t *testing.T) {
+	result := newBatchRecorder().result()
+	if result.Status != http.StatusGatewayTimeout {
+		t.Errorf("result() status = %d, want %d", result.Status, http.StatusGatewayTimeout)
+	}
+}
@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 	"bookstore.com/services"
 	"github.com/julienschmidt/httprouter"
 )
@@ -39,7 +43,12 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request, _ httpr
 		return
 	}
 
-	createdBook, err := h.bookService.CreateBook(book)
+	createdBook, err := h.bookService.As(actorOf(r)).CreateBook(book)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("BookHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		log.Printf("BookHandler.Create: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
@@ -47,8 +56,9 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request, _ httpr
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdBook.Version)
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(createdBook); err != nil {
+	if err := json.NewEncoder(w).Encode(bookView(r, createdBook)); err != nil {
 		log.Printf("BookHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -73,8 +83,14 @@ func (h *BookHandler) GetBookById(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	setETag(w, book.Version)
+	if notModified(w, r, book.Version) {
+		log.Printf("BookHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(book); err != nil {
+	if err := json.NewEncoder(w).Encode(bookView(r, book)); err != nil {
 		log.Printf("BookHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -82,16 +98,29 @@ func (h *BookHandler) GetBookById(w http.ResponseWriter, r *http.Request, ps htt
 	log.Printf("BookHandler.GetById: success, duration: %v", time.Since(start))
 }
 
+// GetBooksByCriteria lists the books matching the filters of the JSON body,
+// ordered by ID or by the sort query parameter (rating or rating_count)
 func (h *BookHandler) GetBooksByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	start := time.Now()
 
-	var query = models.SearchCriteria{Filters: make(map[string]interface{})}
+	includeDeleted, allowed := includeDeletedParam(w, r)
+	if !allowed {
+		log.Printf("BookHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var query = models.SearchCriteria{Filters: make(map[string]interface{}), IncludeDeleted: includeDeleted}
 	if err := json.NewDecoder(r.Body).Decode(&query.Filters); err != nil {
 		query.Filters = make(map[string]interface{})
 		log.Printf("BookHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
 	}
 
-	books, err := h.bookService.SearchBooks(query)
+	books, err := h.bookService.SearchBooks(query, r.URL.Query().Get("sort"))
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("BookHandler.Search: invalid sort error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("BookHandler.Search: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
@@ -99,7 +128,7 @@ func (h *BookHandler) GetBooksByCriteria(w http.ResponseWriter, r *http.Request,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(books); err != nil {
+	if err := json.NewEncoder(w).Encode(booksView(r, books)); err != nil {
 		log.Printf("BookHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -117,6 +146,13 @@ func (h *BookHandler) UpdateBookById(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("BookHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
 	var book models.Book
 	if err = json.NewDecoder(r.Body).Decode(&book); err != nil {
 		log.Printf("BookHandler.Update: invalid input error: %v, duration: %v", err, time.Since(start))
@@ -124,16 +160,28 @@ func (h *BookHandler) UpdateBookById(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 	book.ID = id
+	book.Version = version
 
-	updatedBook, err := h.bookService.UpdateBook(book)
+	updatedBook, err := h.bookService.As(actorOf(r)).UpdateBook(book)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("BookHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("BookHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Book was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Printf("BookHandler.Update: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Book not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
+	setETag(w, updatedBook.Version)
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedBook); err != nil {
+	if err := json.NewEncoder(w).Encode(bookView(r, updatedBook)); err != nil {
 		log.Printf("BookHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -141,6 +189,50 @@ func (h *BookHandler) UpdateBookById(w http.ResponseWriter, r *http.Request, ps
 	log.Printf("BookHandler.Update: success, duration: %v", time.Since(start))
 }
 
+// PatchBookById applies a JSON Merge Patch or JSON Patch to a book. If-Match is optional.
+func (h *BookHandler) PatchBookById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("BookHandler.Patch: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		if version, err = ifMatchVersion(r); err != nil {
+			log.Printf("BookHandler.Patch: precondition error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, err.Error(), preconditionStatus(err))
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("BookHandler.Patch: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	patchedBook, err := h.bookService.As(actorOf(r)).PatchBook(id, version, patch, r.Header.Get("Content-Type"))
+	if err != nil {
+		log.Printf("BookHandler.Patch: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), patchErrorStatus(w, err))
+		return
+	}
+
+	setETag(w, patchedBook.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bookView(r, patchedBook)); err != nil {
+		log.Printf("BookHandler.Patch: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("BookHandler.Patch: success, duration: %v", time.Since(start))
+}
+
 func (h *BookHandler) DeleteBookById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	start := time.Now()
 
@@ -151,7 +243,20 @@ func (h *BookHandler) DeleteBookById(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
-	if err = h.bookService.DeleteBook(id); err != nil {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("BookHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.bookService.As(actorOf(r)).DeleteBook(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("BookHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Book was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
 		log.Printf("BookHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Book not found: "+err.Error(), http.StatusNotFound)
 		return
@@ -160,3 +265,126 @@ func (h *BookHandler) DeleteBookById(w http.ResponseWriter, r *http.Request, ps
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("BookHandler.Delete: success, duration: %v", time.Since(start))
 }
+
+// maxImportSize caps the body of a catalog import
+const maxImportSize = 32 << 20
+
+// ImportBooks creates or updates books from a CSV or NDJSON body. The format
+// comes from ?format= or the Content-Type; ?dry_run=true only checks the rows.
+func (h *BookHandler) ImportBooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = transferFormat(r.Header.Get("Content-Type"))
+	}
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			log.Printf("BookHandler.Import: invalid dry_run error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, "Invalid dry_run value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.bookService.As(actorOf(r)).ImportBooks(http.MaxBytesReader(w, r.Body, maxImportSize), format, dryRun)
+	if errors.Is(err, services.ErrUnsupportedFormat) {
+		log.Printf("BookHandler.Import: unsupported format error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error()+", use csv or ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("BookHandler.Import: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		// Rows read before the failure have been imported, report them along with the error
+		log.Printf("BookHandler.Import: read error after %d rows: %v, duration: %v", report.Rows, err, time.Since(start))
+		report.Results = append(report.Results, models.ImportRowResult{Row: -1, Action: "aborted", Error: err.Error()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("BookHandler.Import: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("BookHandler.Import: success, %d created, %d updated, %d failed, duration: %v", report.Created, report.Updated, report.Failed, time.Since(start))
+}
+
+// ExportBooks streams the whole catalog as ?format=csv (default) or ndjson
+func (h *BookHandler) ExportBooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.FormatCSV
+	}
+	switch format {
+	case services.FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case services.FormatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		log.Printf("BookHandler.Export: unsupported format %q, duration: %v", format, time.Since(start))
+		http.Error(w, "Unsupported format, use csv or ndjson", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="books.`+format+`"`)
+
+	if err := h.bookService.As(actorOf(r)).ExportBooks(w, format); err != nil {
+		log.Printf("BookHandler.Export: export error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("BookHandler.Export: success, duration: %v", time.Since(start))
+}
+
+// transferFormat maps the media type of an import body to its format name
+func transferFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return services.FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return services.FormatNDJSON
+	default:
+		return mediaType
+	}
+}
+
+// RestoreBookById brings back a book that was deleted and not purged yet;
+// only admins may restore
+func (h *BookHandler) RestoreBookById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("BookHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("BookHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.bookService.As(actorOf(r)).RestoreBook(id)
+	if err != nil {
+		log.Printf("BookHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Book not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(bookView(r, restored)); err != nil {
+		log.Printf("BookHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("BookHandler.Restore: success, duration: %v", time.Since(start))
+}
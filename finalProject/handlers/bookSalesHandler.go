@@ -2,12 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
-	"sort"
 	"strconv"
 	"sync"
-	"time"
 
 	"bookstore.com/models"
 	"bookstore.com/services"
@@ -120,61 +117,3 @@ func (h *BookSaleHandler) DeleteBookSaleById(w http.ResponseWriter, r *http.Requ
 
 	w.WriteHeader(http.StatusNoContent)
 }
-
-func (h *BookSaleHandler) GenerateReports(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	var query = models.SearchCriteria{
-		Filters: make(map[string]interface{}),
-	}
-
-	// Fetch all book sales data
-	bookSales, err := h.BookSaleService.SearchBookSales(query)
-	if err != nil {
-		http.Error(w, "Error fetching sales data", http.StatusInternalServerError)
-		return
-	}
-
-	// Initialize variables to calculate total revenue and orders
-	totalRevenue := 0.0
-	totalOrders := len(bookSales)
-	bookSalesMap := make(map[string]*models.BookSale)
-
-	// Aggregate sales data
-	for _, sale := range bookSales {
-		totalRevenue += float64(sale.Quantity) * sale.Book.Price
-
-		// Aggregate sales by book for top-selling books
-		if existingSale, exists := bookSalesMap[sale.Book.Title]; exists {
-			existingSale.Quantity += sale.Quantity
-		} else {
-			bookSalesMap[sale.Book.Title] = &models.BookSale{
-				Book:     sale.Book,
-				Quantity: sale.Quantity,
-			}
-		}
-	}
-
-	// Convert map to slice and sort by quantity sold
-	var topSellingBooks []models.BookSale
-	for _, sale := range bookSalesMap {
-		topSellingBooks = append(topSellingBooks, *sale)
-	}
-	sort.Slice(topSellingBooks, func(i, j int) bool {
-		return topSellingBooks[i].Quantity > topSellingBooks[j].Quantity
-	})
-
-	// Create the SalesReport object
-	report := models.SalesReport{
-		Timestamp:       time.Now(),
-		TotalRevenue:    totalRevenue,
-		TotalOrders:     totalOrders,
-		TopSellingBooks: topSellingBooks,
-	}
-
-	// Respond with the report data as JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(report); err != nil {
-		log.Printf("Error encoding report: %v", err)
-		http.Error(w, "Error generating report", http.StatusInternalServerError)
-		return
-	}
-}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CartHandler handles the shopping cart of a customer.
+type CartHandler struct {
+	CartService *services.CartService
+}
+
+var (
+	CartInstance *CartHandler
+	CartOnce     sync.Once
+)
+
+// NewCartHandler initializes a singleton instance of CartHandler.
+func NewCartHandler(CartService *services.CartService) *CartHandler {
+	CartOnce.Do(func() {
+		CartInstance = &CartHandler{CartService: CartService}
+	})
+	return CartInstance
+}
+
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.Get: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.Get: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	cart, err := h.CartService.GetCart(customerID)
+	if err != nil {
+		log.Printf("CartHandler.Get: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	writeCart(w, http.StatusOK, cart)
+	log.Printf("CartHandler.Get: success, duration: %v", time.Since(start))
+}
+
+func (h *CartHandler) AddCartItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.AddItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.AddItem: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var request models.CartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("CartHandler.AddItem: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.CartService.As(actorOf(r)).AddItem(customerID, request)
+	if err != nil {
+		log.Printf("CartHandler.AddItem: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	writeCart(w, http.StatusOK, cart)
+	log.Printf("CartHandler.AddItem: success, duration: %v", time.Since(start))
+}
+
+func (h *CartHandler) UpdateCartItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.UpdateItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.UpdateItem: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("bookId"))
+	if err != nil {
+		log.Printf("CartHandler.UpdateItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.CartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("CartHandler.UpdateItem: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.CartService.As(actorOf(r)).UpdateItem(customerID, bookID, request.Quantity)
+	if err != nil {
+		log.Printf("CartHandler.UpdateItem: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	writeCart(w, http.StatusOK, cart)
+	log.Printf("CartHandler.UpdateItem: success, duration: %v", time.Since(start))
+}
+
+func (h *CartHandler) RemoveCartItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.RemoveItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.RemoveItem: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("bookId"))
+	if err != nil {
+		log.Printf("CartHandler.RemoveItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.CartService.As(actorOf(r)).RemoveItem(customerID, bookID)
+	if err != nil {
+		log.Printf("CartHandler.RemoveItem: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	writeCart(w, http.StatusOK, cart)
+	log.Printf("CartHandler.RemoveItem: success, duration: %v", time.Since(start))
+}
+
+func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.Clear: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.Clear: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	if err := h.CartService.As(actorOf(r)).ClearCart(customerID); err != nil {
+		log.Printf("CartHandler.Clear: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("CartHandler.Clear: success, duration: %v", time.Since(start))
+}
+
+func (h *CartHandler) CheckoutCart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CartHandler.Checkout: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("CartHandler.Checkout: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	// The promotion codes are optional, an empty body checks out without any
+	var codes models.PromotionCodes
+	if err := json.NewDecoder(r.Body).Decode(&codes); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("CartHandler.Checkout: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.CartService.As(actorOf(r)).Checkout(customerID, codes.Codes)
+	if err != nil {
+		log.Printf("CartHandler.Checkout: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), cartErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, order.Version)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(orderView(r, order)); err != nil {
+		log.Printf("CartHandler.Checkout: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CartHandler.Checkout: success, order: %d, duration: %v", order.ID, time.Since(start))
+}
+
+func writeCart(w http.ResponseWriter, status int, cart models.Cart) {
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, cart.Version)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(cart); err != nil {
+		log.Printf("CartHandler: encoding error: %v", err)
+	}
+}
+
+// cartErrorStatus maps cart service errors to HTTP status codes
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrCartItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bookstore.com/models"
+)
+
+// The cost price of the books tells what the store paid for them, so only
+// the admin sees it; these helpers clear it from the books and orders sent
+// to anyone else
+
+func bookView(r *http.Request, book models.Book) models.Book {
+	if !isAdmin(r) {
+		book.CostPrice = 0
+	}
+	return book
+}
+
+func booksView(r *http.Request, books []models.Book) []models.Book {
+	if isAdmin(r) {
+		return books
+	}
+	viewed := make([]models.Book, len(books))
+	for i, book := range books {
+		viewed[i] = bookView(r, book)
+	}
+	return viewed
+}
+
+func orderView(r *http.Request, order models.Order) models.Order {
+	if isAdmin(r) || order.Items == nil {
+		return order
+	}
+	items := make([]models.OrderItem, len(order.Items))
+	for i, item := range order.Items {
+		item.Book = bookView(r, item.Book)
+		items[i] = item
+	}
+	order.Items = items
+	return order
+}
+
+func ordersView(r *http.Request, orders []models.Order) []models.Order {
+	if isAdmin(r) {
+		return orders
+	}
+	viewed := make([]models.Order, len(orders))
+	for i, order := range orders {
+		viewed[i] = orderView(r, order)
+	}
+	return viewed
+}
+
+func recommendationsView(r *http.Request, recommendations []models.Recommendation) []models.Recommendation {
+	if isAdmin(r) {
+		return recommendations
+	}
+	viewed := make([]models.Recommendation, len(recommendations))
+	for i, recommendation := range recommendations {
+		recommendation.Book = bookView(r, recommendation.Book)
+		viewed[i] = recommendation
+	}
+	return viewed
+}
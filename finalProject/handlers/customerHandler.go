@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 	"bookstore.com/services"
 	"github.com/julienschmidt/httprouter"
 )
@@ -42,7 +45,17 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	createdCustomer, err := h.CustomerService.CreateCustomer(Customer)
+	createdCustomer, err := h.CustomerService.As(actorOf(r)).CreateCustomer(Customer)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("CustomerHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		log.Printf("CustomerHandler.Create: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("CustomerHandler.Create: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
@@ -50,6 +63,7 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdCustomer.Version)
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(createdCustomer); err != nil {
 		log.Printf("CustomerHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
@@ -76,6 +90,12 @@ func (h *CustomerHandler) GetCustomerById(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	setETag(w, Customer.Version)
+	if notModified(w, r, Customer.Version) {
+		log.Printf("CustomerHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(Customer); err != nil {
 		log.Printf("CustomerHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
@@ -85,15 +105,24 @@ func (h *CustomerHandler) GetCustomerById(w http.ResponseWriter, r *http.Request
 	log.Printf("CustomerHandler.GetById: success, duration: %v", time.Since(start))
 }
 
+// GetCustomersByCriteria lists the customers, filtered by the optional name,
+// email, city, country, from and to query parameters
 func (h *CustomerHandler) GetCustomersByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	start := time.Now()
 
-	var query = models.SearchCriteria{Filters: make(map[string]interface{})}
-	err := json.NewDecoder(r.Body).Decode(&query.Filters)
+	includeDeleted, allowed := includeDeletedParam(w, r)
+	if !allowed {
+		log.Printf("CustomerHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	query, err := customerSearchCriteria(r)
 	if err != nil {
-		query.Filters = make(map[string]interface{})
 		log.Printf("CustomerHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	query.IncludeDeleted = includeDeleted
 
 	Customers, err := h.CustomerService.SearchCustomers(query)
 	if err != nil {
@@ -111,6 +140,27 @@ func (h *CustomerHandler) GetCustomersByCriteria(w http.ResponseWriter, r *http.
 	log.Printf("CustomerHandler.Search: success, returned %d customers, duration: %v", len(Customers), time.Since(start))
 }
 
+// customerSearchCriteria reads the customer filters from the query string;
+// from and to bound the creation time
+func customerSearchCriteria(r *http.Request) (models.SearchCriteria, error) {
+	query := models.SearchCriteria{Filters: make(map[string]interface{})}
+	for _, name := range []string{"name", "email", "city", "country"} {
+		if value := r.URL.Query().Get(name); value != "" {
+			query.Filters[name] = value
+		}
+	}
+	for _, name := range []string{"from", "to"} {
+		t, err := parseTimeParam(r, name)
+		if err != nil {
+			return query, err
+		}
+		if !t.IsZero() {
+			query.Filters[name] = t
+		}
+	}
+	return query, nil
+}
+
 func (h *CustomerHandler) UpdateCustomerById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	start := time.Now()
 
@@ -120,6 +170,17 @@ func (h *CustomerHandler) UpdateCustomerById(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
 		return
 	}
+	if !requireOwner(w, r, id) {
+		log.Printf("CustomerHandler.Update: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("CustomerHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
 
 	var Customer models.Customer
 	err = json.NewDecoder(r.Body).Decode(&Customer)
@@ -129,14 +190,31 @@ func (h *CustomerHandler) UpdateCustomerById(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	Customer.ID = id
+	Customer.Version = version
 
-	updatedCustomer, err := h.CustomerService.UpdateCustomer(Customer)
+	updatedCustomer, err := h.CustomerService.As(actorOf(r)).UpdateCustomer(Customer)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("CustomerHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("CustomerHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Customer was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		log.Printf("CustomerHandler.Update: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("CustomerHandler.Update: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Customer not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
+	setETag(w, updatedCustomer.Version)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(updatedCustomer); err != nil {
 		log.Printf("CustomerHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
@@ -146,6 +224,54 @@ func (h *CustomerHandler) UpdateCustomerById(w http.ResponseWriter, r *http.Requ
 	log.Printf("CustomerHandler.Update: success, duration: %v", time.Since(start))
 }
 
+// PatchCustomerById applies a JSON Merge Patch or JSON Patch to a customer. If-Match is optional.
+func (h *CustomerHandler) PatchCustomerById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CustomerHandler.Patch: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, id) {
+		log.Printf("CustomerHandler.Patch: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		if version, err = ifMatchVersion(r); err != nil {
+			log.Printf("CustomerHandler.Patch: precondition error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, err.Error(), preconditionStatus(err))
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("CustomerHandler.Patch: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	patchedCustomer, err := h.CustomerService.As(actorOf(r)).PatchCustomer(id, version, patch, r.Header.Get("Content-Type"))
+	if err != nil {
+		log.Printf("CustomerHandler.Patch: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), patchErrorStatus(w, err))
+		return
+	}
+
+	setETag(w, patchedCustomer.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(patchedCustomer); err != nil {
+		log.Printf("CustomerHandler.Patch: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Patch: success, duration: %v", time.Since(start))
+}
+
 func (h *CustomerHandler) DeleteCustomerById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	start := time.Now()
 
@@ -155,8 +281,24 @@ func (h *CustomerHandler) DeleteCustomerById(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
 		return
 	}
+	if !requireOwner(w, r, id) {
+		log.Printf("CustomerHandler.Delete: forbidden, duration: %v", time.Since(start))
+		return
+	}
 
-	err = h.CustomerService.DeleteCustomer(id)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("CustomerHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.CustomerService.As(actorOf(r)).DeleteCustomer(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("CustomerHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Customer was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Printf("CustomerHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Customer not found: "+err.Error(), http.StatusNotFound)
@@ -166,3 +308,208 @@ func (h *CustomerHandler) DeleteCustomerById(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("CustomerHandler.Delete: success, duration: %v", time.Since(start))
 }
+
+// RestoreCustomerById brings back a customer that was deleted and not purged yet;
+// only admins may restore
+func (h *CustomerHandler) RestoreCustomerById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("CustomerHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CustomerHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.CustomerService.As(actorOf(r)).RestoreCustomer(id)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		log.Printf("CustomerHandler.Restore: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("CustomerHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Customer not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(restored); err != nil {
+		log.Printf("CustomerHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Restore: success, duration: %v", time.Since(start))
+}
+
+// GetCustomerOrders lists the orders of a customer, newest first, a page at a
+// time; the status query parameter keeps the orders in that status
+func (h *CustomerHandler) GetCustomerOrders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CustomerHandler.Orders: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, id) {
+		log.Printf("CustomerHandler.Orders: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		log.Printf("CustomerHandler.Orders: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, err := h.CustomerService.GetCustomerOrders(id, r.URL.Query().Get("status"), page, perPage)
+	if err != nil {
+		log.Printf("CustomerHandler.Orders: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), customerHistoryErrorStatus(err))
+		return
+	}
+
+	orders.Orders = ordersView(r, orders.Orders)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		log.Printf("CustomerHandler.Orders: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Orders: success, returned %d of %d orders, duration: %v", len(orders.Orders), orders.Total, time.Since(start))
+}
+
+// GetCustomerSummary returns the order count, lifetime spend, first and last
+// order dates and favourite genres of a customer
+func (h *CustomerHandler) GetCustomerSummary(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CustomerHandler.Summary: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, id) {
+		log.Printf("CustomerHandler.Summary: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	summary, err := h.CustomerService.GetCustomerSummary(id)
+	if err != nil {
+		log.Printf("CustomerHandler.Summary: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), customerHistoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.Printf("CustomerHandler.Summary: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Summary: success, %d orders, duration: %v", summary.OrderCount, time.Since(start))
+}
+
+// customerHistoryErrorStatus maps the errors of the order history of a
+// customer to HTTP status codes
+func customerHistoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FindDuplicateCustomers lists the pairs of customers that are likely the
+// same person; only admins may look for them
+func (h *CustomerHandler) FindDuplicateCustomers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("CustomerHandler.Duplicates: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	duplicates, err := h.CustomerService.FindDuplicateCustomers()
+	if err != nil {
+		log.Printf("CustomerHandler.Duplicates: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(duplicates); err != nil {
+		log.Printf("CustomerHandler.Duplicates: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Duplicates: success, returned %d pairs, duration: %v", len(duplicates), time.Since(start))
+}
+
+// MergeCustomers merges the customer given as duplicate_id into the one of
+// the path: its orders are moved over and it is deleted. Only admins may merge.
+func (h *CustomerHandler) MergeCustomers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("CustomerHandler.Merge: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("CustomerHandler.Merge: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		DuplicateID int `json:"duplicate_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("CustomerHandler.Merge: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	merge, err := h.CustomerService.As(actorOf(r)).MergeCustomers(id, request.DuplicateID)
+	if err != nil {
+		log.Printf("CustomerHandler.Merge: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), customerMergeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(merge); err != nil {
+		log.Printf("CustomerHandler.Merge: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("CustomerHandler.Merge: success, customer %d merged into %d, %d orders moved, duration: %v", request.DuplicateID, id, len(merge.MovedOrders), time.Since(start))
+}
+
+// customerMergeErrorStatus maps the errors of a customer merge to HTTP status codes
+func customerMergeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
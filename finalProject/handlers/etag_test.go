@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		want       int
+		wantErr    error
+		wantStatus int
+	}{
+		{name: "missing", wantErr: errMissingIfMatch, wantStatus: http.StatusPreconditionRequired},
+		{name: "blank", header: "  ", wantErr: errMissingIfMatch, wantStatus: http.StatusPreconditionRequired},
+		{name: "any version", header: "*", want: 0},
+		{name: "strong tag", header: `"3"`, want: 3},
+		{name: "surrounding spaces", header: ` "12" `, want: 12},
+		{name: "unquoted", header: "3", wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "weak tag", header: `W/"3"`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "empty tag", header: `""`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "zero", header: `"0"`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "negative", header: `"-1"`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "not a number", header: `"abc"`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+		{name: "several tags", header: `"3", "4"`, wantErr: errInvalidIfMatch, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/books/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := ifMatchVersion(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ifMatchVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if status := preconditionStatus(err); status != tt.wantStatus {
+					t.Errorf("preconditionStatus() = %d, want %d", status, tt.wantStatus)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ifMatchVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// InventoryHandler lets the warehouse record stock movements, check the
+// stock history of books and follow the low stock alerts
+type InventoryHandler struct {
+	InventoryService  *services.InventoryService
+	StockAlertService *services.StockAlertService
+}
+
+var (
+	InventoryInstance *InventoryHandler
+	InventoryOnce     sync.Once
+)
+
+// NewInventoryHandler initializes a singleton instance of InventoryHandler.
+func NewInventoryHandler(InventoryService *services.InventoryService, StockAlertService *services.StockAlertService) *InventoryHandler {
+	InventoryOnce.Do(func() {
+		InventoryInstance = &InventoryHandler{InventoryService: InventoryService, StockAlertService: StockAlertService}
+	})
+	return InventoryInstance
+}
+
+// RecordStockMovement records a receipt, an adjustment or damaged copies
+func (h *InventoryHandler) RecordStockMovement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("InventoryHandler.Record: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("InventoryHandler.Record: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.StockMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("InventoryHandler.Record: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	movement, err := h.InventoryService.As(actorOf(r)).RecordMovement(bookID, request)
+	if err != nil {
+		log.Printf("InventoryHandler.Record: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(movement); err != nil {
+		log.Printf("InventoryHandler.Record: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("InventoryHandler.Record: success, movement: %d, duration: %v", movement.ID, time.Since(start))
+}
+
+// GetStockHistory lists the stock movements of a book reconciled against
+// its orders
+func (h *InventoryHandler) GetStockHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("InventoryHandler.History: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("InventoryHandler.History: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.InventoryService.GetStockHistory(bookID)
+	if err != nil {
+		log.Printf("InventoryHandler.History: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		log.Printf("InventoryHandler.History: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("InventoryHandler.History: success, %d movements, duration: %v", len(history.Movements), time.Since(start))
+}
+
+// GetStockAlerts lists the books below their reorder threshold with the
+// quantity suggested to reorder
+func (h *InventoryHandler) GetStockAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("InventoryHandler.Alerts: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	alerts, err := h.StockAlertService.GetAlerts(time.Now())
+	if err != nil {
+		log.Printf("InventoryHandler.Alerts: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(alerts); err != nil {
+		log.Printf("InventoryHandler.Alerts: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("InventoryHandler.Alerts: success, returned %d alerts, duration: %v", len(alerts), time.Since(start))
+}
+
+// inventoryErrorStatus maps stock movement errors to HTTP status codes
+func inventoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
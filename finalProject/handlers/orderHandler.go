@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,6 +12,7 @@ import (
 	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 	"bookstore.com/services"
 	"github.com/julienschmidt/httprouter"
 )
@@ -42,7 +46,17 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
-	createdOrder, err := h.OrderService.CreateOrder(Order)
+	createdOrder, err := h.OrderService.As(actorOf(r)).CreateOrder(Order)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("OrderHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, services.ErrInsufficientStock) {
+		log.Printf("OrderHandler.Create: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("OrderHandler.Create: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
@@ -50,8 +64,9 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request, _ htt
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdOrder.Version)
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(createdOrder); err != nil {
+	if err := json.NewEncoder(w).Encode(orderView(r, createdOrder)); err != nil {
 		log.Printf("OrderHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -76,8 +91,14 @@ func (h *OrderHandler) GetOrderById(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
+	setETag(w, Order.Version)
+	if notModified(w, r, Order.Version) {
+		log.Printf("OrderHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(Order); err != nil {
+	if err := json.NewEncoder(w).Encode(orderView(r, Order)); err != nil {
 		log.Printf("OrderHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -85,15 +106,24 @@ func (h *OrderHandler) GetOrderById(w http.ResponseWriter, r *http.Request, ps h
 	log.Printf("OrderHandler.GetById: success, duration: %v", time.Since(start))
 }
 
+// GetOrdersByCriteria lists the orders matching the optional customer_id,
+// status, from, to, min_total, max_total and book_id query parameters
 func (h *OrderHandler) GetOrdersByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	start := time.Now()
 
-	var query = models.SearchCriteria{Filters: make(map[string]interface{})}
-	err := json.NewDecoder(r.Body).Decode(&query.Filters)
+	includeDeleted, allowed := includeDeletedParam(w, r)
+	if !allowed {
+		log.Printf("OrderHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	query, err := orderSearchCriteria(r)
 	if err != nil {
-		query.Filters = make(map[string]interface{})
 		log.Printf("OrderHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	query.IncludeDeleted = includeDeleted
 
 	Orders, err := h.OrderService.SearchOrders(query)
 	if err != nil {
@@ -103,7 +133,7 @@ func (h *OrderHandler) GetOrdersByCriteria(w http.ResponseWriter, r *http.Reques
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(Orders); err != nil {
+	if err := json.NewEncoder(w).Encode(ordersView(r, Orders)); err != nil {
 		log.Printf("OrderHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -121,6 +151,13 @@ func (h *OrderHandler) UpdateOrderById(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("OrderHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
 	var Order models.Order
 	err = json.NewDecoder(r.Body).Decode(&Order)
 	if err != nil {
@@ -129,16 +166,28 @@ func (h *OrderHandler) UpdateOrderById(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 	Order.ID = id
+	Order.Version = version
 
-	updatedOrder, err := h.OrderService.UpdateOrder(Order)
+	updatedOrder, err := h.OrderService.As(actorOf(r)).UpdateOrder(Order)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("OrderHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("OrderHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Order was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Printf("OrderHandler.Update: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Order not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
+	setETag(w, updatedOrder.Version)
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedOrder); err != nil {
+	if err := json.NewEncoder(w).Encode(orderView(r, updatedOrder)); err != nil {
 		log.Printf("OrderHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
 		return
 	}
@@ -146,6 +195,50 @@ func (h *OrderHandler) UpdateOrderById(w http.ResponseWriter, r *http.Request, p
 	log.Printf("OrderHandler.Update: success, duration: %v", time.Since(start))
 }
 
+// PatchOrderById applies a JSON Merge Patch or JSON Patch to an order. If-Match is optional.
+func (h *OrderHandler) PatchOrderById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("OrderHandler.Patch: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		if version, err = ifMatchVersion(r); err != nil {
+			log.Printf("OrderHandler.Patch: precondition error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, err.Error(), preconditionStatus(err))
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("OrderHandler.Patch: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	patchedOrder, err := h.OrderService.As(actorOf(r)).PatchOrder(id, version, patch, r.Header.Get("Content-Type"))
+	if err != nil {
+		log.Printf("OrderHandler.Patch: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), patchErrorStatus(w, err))
+		return
+	}
+
+	setETag(w, patchedOrder.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orderView(r, patchedOrder)); err != nil {
+		log.Printf("OrderHandler.Patch: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("OrderHandler.Patch: success, duration: %v", time.Since(start))
+}
+
 func (h *OrderHandler) DeleteOrderById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	start := time.Now()
 
@@ -156,7 +249,19 @@ func (h *OrderHandler) DeleteOrderById(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
-	err = h.OrderService.DeleteOrder(id)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("OrderHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.OrderService.As(actorOf(r)).DeleteOrder(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("OrderHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Order was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Printf("OrderHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
 		http.Error(w, "Order not found: "+err.Error(), http.StatusNotFound)
@@ -166,3 +271,82 @@ func (h *OrderHandler) DeleteOrderById(w http.ResponseWriter, r *http.Request, p
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("OrderHandler.Delete: success, duration: %v", time.Since(start))
 }
+
+// RestoreOrderById brings back an order that was deleted and not purged yet;
+// only admins may restore
+func (h *OrderHandler) RestoreOrderById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("OrderHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("OrderHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.OrderService.As(actorOf(r)).RestoreOrder(id)
+	if err != nil {
+		log.Printf("OrderHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Order not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(orderView(r, restored)); err != nil {
+		log.Printf("OrderHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("OrderHandler.Restore: success, duration: %v", time.Since(start))
+}
+
+// orderSearchCriteria reads the order filters from the query parameters:
+// from and to bound the creation time as RFC 3339 times or dates, min_total
+// and max_total the total price
+func orderSearchCriteria(r *http.Request) (models.SearchCriteria, error) {
+	query := models.SearchCriteria{Filters: make(map[string]interface{})}
+	params := r.URL.Query()
+
+	if status := params.Get("status"); status != "" {
+		query.Filters["status"] = status
+	}
+	for _, name := range []string{"customer_id", "book_id"} {
+		if value := params.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return query, fmt.Errorf("Invalid %s %q", name, value)
+			}
+			query.Filters[name] = id
+		}
+	}
+	for _, name := range []string{"from", "to"} {
+		t, err := parseTimeParam(r, name)
+		if err != nil {
+			return query, err
+		}
+		if !t.IsZero() {
+			query.Filters[name] = t
+		}
+	}
+	for _, name := range []string{"min_total", "max_total"} {
+		if value := params.Get(name); value != "" {
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil || amount < 0 {
+				return query, fmt.Errorf("Invalid %s %q", name, value)
+			}
+			query.Filters[name] = amount
+		}
+	}
+	if minTotal, exists := query.Filters["min_total"].(float64); exists {
+		if maxTotal, exists := query.Filters["max_total"].(float64); exists && minTotal > maxTotal {
+			return query, fmt.Errorf("min_total %v is above max_total %v", minTotal, maxTotal)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// defaultPerPage is the page size of paginated lists without per_page
+const defaultPerPage = 20
+
+// pageParams reads the page and per_page query parameters, the first page
+// of defaultPerPage items when they are absent
+func pageParams(r *http.Request) (page int, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+	if value := r.URL.Query().Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("Invalid page %q", value)
+		}
+	}
+	if value := r.URL.Query().Get("per_page"); value != "" {
+		if perPage, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("Invalid per_page %q", value)
+		}
+	}
+	return page, perPage, nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+)
+
+// acceptedPatchTypes is advertised in Accept-Patch when a PATCH body has an unsupported media type
+var acceptedPatchTypes = services.MergePatchContentType + ", " + services.JSONPatchContentType
+
+// patchErrorStatus maps the errors of the Patch* service methods to HTTP statuses
+func patchErrorStatus(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, services.ErrUnsupportedPatch):
+		w.Header().Set("Accept-Patch", acceptedPatchTypes)
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrInvalidPatch):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPatchTestFailed), errors.Is(err, repositories.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusNotFound
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PaymentHandler handles the payment of orders.
+type PaymentHandler struct {
+	PaymentService *services.PaymentService
+}
+
+var (
+	PaymentInstance *PaymentHandler
+	PaymentOnce     sync.Once
+)
+
+// NewPaymentHandler initializes a singleton instance of PaymentHandler.
+func NewPaymentHandler(PaymentService *services.PaymentService) *PaymentHandler {
+	PaymentOnce.Do(func() {
+		PaymentInstance = &PaymentHandler{PaymentService: PaymentService}
+	})
+	return PaymentInstance
+}
+
+func (h *PaymentHandler) AuthorizePayment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	orderID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PaymentHandler.Authorize: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOrderOwner(w, r, orderID) {
+		log.Printf("PaymentHandler.Authorize: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var request models.PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("PaymentHandler.Authorize: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.PaymentService.As(actorOf(r)).Authorize(orderID, request)
+	if err != nil {
+		log.Printf("PaymentHandler.Authorize: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(payment); err != nil {
+		log.Printf("PaymentHandler.Authorize: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PaymentHandler.Authorize: success, payment: %d, duration: %v", payment.ID, time.Since(start))
+}
+
+func (h *PaymentHandler) GetPayments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	orderID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PaymentHandler.Get: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOrderOwner(w, r, orderID) {
+		log.Printf("PaymentHandler.Get: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	payments, err := h.PaymentService.GetPayments(orderID)
+	if err != nil {
+		log.Printf("PaymentHandler.Get: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(payments); err != nil {
+		log.Printf("PaymentHandler.Get: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PaymentHandler.Get: success, returned %d payments, duration: %v", len(payments), time.Since(start))
+}
+
+func (h *PaymentHandler) CapturePayment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.settle(w, r, ps, "Capture", h.PaymentService.As(actorOf(r)).Capture)
+}
+
+func (h *PaymentHandler) VoidPayment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.settle(w, r, ps, "Void", h.PaymentService.As(actorOf(r)).Void)
+}
+
+// settle runs an operation on the current payment of the order; only admins
+// capture or void payments
+func (h *PaymentHandler) settle(w http.ResponseWriter, r *http.Request, ps httprouter.Params, name string, operation func(orderID int) (models.Payment, error)) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PaymentHandler.%s: forbidden, duration: %v", name, time.Since(start))
+		return
+	}
+
+	orderID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PaymentHandler.%s: invalid id error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := operation(orderID)
+	if err != nil {
+		log.Printf("PaymentHandler.%s: service error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(payment); err != nil {
+		log.Printf("PaymentHandler.%s: encoding error: %v, duration: %v", name, err, time.Since(start))
+		return
+	}
+
+	log.Printf("PaymentHandler.%s: success, payment: %d, duration: %v", name, payment.ID, time.Since(start))
+}
+
+// paymentErrorStatus maps payment service errors to HTTP status codes
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, services.ErrOrderStatus), errors.Is(err, services.ErrAuthorizationExpired),
+		errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
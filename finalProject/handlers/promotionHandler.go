@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PromotionHandler manages the promotions and discount codes.
+type PromotionHandler struct {
+	PromotionService *services.PromotionService
+}
+
+var (
+	PromotionInstance *PromotionHandler
+	PromotionOnce     sync.Once
+)
+
+// NewPromotionHandler initializes a singleton instance of PromotionHandler.
+func NewPromotionHandler(PromotionService *services.PromotionService) *PromotionHandler {
+	PromotionOnce.Do(func() {
+		PromotionInstance = &PromotionHandler{PromotionService: PromotionService}
+	})
+	return PromotionInstance
+}
+
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PromotionHandler.Create: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		log.Printf("PromotionHandler.Create: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createdPromotion, err := h.PromotionService.As(actorOf(r)).CreatePromotion(promotion)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("PromotionHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrCodeTaken) {
+		log.Printf("PromotionHandler.Create: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionHandler.Create: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdPromotion.Version)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(createdPromotion); err != nil {
+		log.Printf("PromotionHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PromotionHandler.Create: success, duration: %v", time.Since(start))
+}
+
+func (h *PromotionHandler) GetPromotionById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PromotionHandler.GetById: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.PromotionService.GetPromotion(id)
+	if err != nil {
+		log.Printf("PromotionHandler.GetById: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	setETag(w, promotion.Version)
+	if notModified(w, r, promotion.Version) {
+		log.Printf("PromotionHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(promotion); err != nil {
+		log.Printf("PromotionHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PromotionHandler.GetById: success, duration: %v", time.Since(start))
+}
+
+func (h *PromotionHandler) GetPromotionsByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	includeDeleted, allowed := includeDeletedParam(w, r)
+	if !allowed {
+		log.Printf("PromotionHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var query = models.SearchCriteria{Filters: make(map[string]interface{}), IncludeDeleted: includeDeleted}
+	if err := json.NewDecoder(r.Body).Decode(&query.Filters); err != nil {
+		query.Filters = make(map[string]interface{})
+		log.Printf("PromotionHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
+	}
+
+	promotions, err := h.PromotionService.SearchPromotions(query)
+	if err != nil {
+		log.Printf("PromotionHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(promotions); err != nil {
+		log.Printf("PromotionHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PromotionHandler.Search: success, returned %d promotions, duration: %v", len(promotions), time.Since(start))
+}
+
+func (h *PromotionHandler) UpdatePromotionById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PromotionHandler.Update: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PromotionHandler.Update: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("PromotionHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	var promotion models.Promotion
+	if err = json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		log.Printf("PromotionHandler.Update: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	promotion.ID = id
+	promotion.Version = version
+
+	updatedPromotion, err := h.PromotionService.As(actorOf(r)).UpdatePromotion(promotion)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("PromotionHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("PromotionHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, repositories.ErrCodeTaken) {
+		log.Printf("PromotionHandler.Update: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionHandler.Update: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	setETag(w, updatedPromotion.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updatedPromotion); err != nil {
+		log.Printf("PromotionHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PromotionHandler.Update: success, duration: %v", time.Since(start))
+}
+
+func (h *PromotionHandler) DeletePromotionById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PromotionHandler.Delete: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PromotionHandler.Delete: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("PromotionHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.PromotionService.As(actorOf(r)).DeletePromotion(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("PromotionHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("PromotionHandler.Delete: success, duration: %v", time.Since(start))
+}
+
+// RestorePromotionById brings back a promotion that was deleted and not purged yet;
+// only admins may restore
+func (h *PromotionHandler) RestorePromotionById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PromotionHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PromotionHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.PromotionService.As(actorOf(r)).RestorePromotion(id)
+	if errors.Is(err, repositories.ErrCodeTaken) {
+		log.Printf("PromotionHandler.Restore: conflict error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Promotion not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(restored); err != nil {
+		log.Printf("PromotionHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PromotionHandler.Restore: success, duration: %v", time.Since(start))
+}
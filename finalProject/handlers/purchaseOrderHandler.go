@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PurchaseOrderHandler lets admins order books from suppliers and receive
+// the deliveries into stock
+type PurchaseOrderHandler struct {
+	PurchaseOrderService *services.PurchaseOrderService
+}
+
+var (
+	PurchaseOrderInstance *PurchaseOrderHandler
+	PurchaseOrderOnce     sync.Once
+)
+
+// NewPurchaseOrderHandler initializes a singleton instance of PurchaseOrderHandler.
+func NewPurchaseOrderHandler(PurchaseOrderService *services.PurchaseOrderService) *PurchaseOrderHandler {
+	PurchaseOrderOnce.Do(func() {
+		PurchaseOrderInstance = &PurchaseOrderHandler{PurchaseOrderService: PurchaseOrderService}
+	})
+	return PurchaseOrderInstance
+}
+
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PurchaseOrderHandler.Create: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var order models.PurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		log.Printf("PurchaseOrderHandler.Create: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.PurchaseOrderService.As(actorOf(r)).CreatePurchaseOrder(order)
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.Create: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), purchaseOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("PurchaseOrderHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PurchaseOrderHandler.Create: success, purchase order: %d, duration: %v", created.ID, time.Since(start))
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrderById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PurchaseOrderHandler.GetById: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.GetById: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Purchase Order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.PurchaseOrderService.GetPurchaseOrder(id)
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.GetById: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), purchaseOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.Printf("PurchaseOrderHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PurchaseOrderHandler.GetById: success, duration: %v", time.Since(start))
+}
+
+// GetPurchaseOrdersByCriteria lists the purchase orders, filtered by the
+// optional supplier_id, status and book_id query parameters
+func (h *PurchaseOrderHandler) GetPurchaseOrdersByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("PurchaseOrderHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	query := models.SearchCriteria{Filters: make(map[string]interface{})}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query.Filters["status"] = status
+	}
+	for _, name := range []string{"supplier_id", "book_id"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("PurchaseOrderHandler.Search: invalid input error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		query.Filters[name] = id
+	}
+
+	orders, err := h.PurchaseOrderService.SearchPurchaseOrders(query)
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		log.Printf("PurchaseOrderHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("PurchaseOrderHandler.Search: success, returned %d purchase orders, duration: %v", len(orders), time.Since(start))
+}
+
+// ReceivePurchaseOrder adds the copies of a delivery to the stock; a
+// delivery may bring only part of the order
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !requireAdmin(w, r) {
+		log.Printf("PurchaseOrderHandler.Receive: forbidden")
+		return
+	}
+	var receipt models.PurchaseOrderReceipt
+	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+		log.Printf("PurchaseOrderHandler.Receive: invalid input error: %v", err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.transition(w, ps, "Receive", func(id int) (models.PurchaseOrder, error) {
+		return h.PurchaseOrderService.As(actorOf(r)).ReceivePurchaseOrder(id, receipt)
+	})
+}
+
+func (h *PurchaseOrderHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !requireAdmin(w, r) {
+		log.Printf("PurchaseOrderHandler.Cancel: forbidden")
+		return
+	}
+	h.transition(w, ps, "Cancel", func(id int) (models.PurchaseOrder, error) {
+		return h.PurchaseOrderService.As(actorOf(r)).CancelPurchaseOrder(id)
+	})
+}
+
+// transition runs an operation changing the status of a purchase order
+func (h *PurchaseOrderHandler) transition(w http.ResponseWriter, ps httprouter.Params, name string, operation func(id int) (models.PurchaseOrder, error)) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.%s: invalid id error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, "Invalid Purchase Order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := operation(id)
+	if err != nil {
+		log.Printf("PurchaseOrderHandler.%s: service error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, err.Error(), purchaseOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.Printf("PurchaseOrderHandler.%s: encoding error: %v, duration: %v", name, err, time.Since(start))
+		return
+	}
+
+	log.Printf("PurchaseOrderHandler.%s: success, purchase order: %d, status: %s, duration: %v", name, order.ID, order.Status, time.Since(start))
+}
+
+// purchaseOrderErrorStatus maps purchase order errors to HTTP status codes
+func purchaseOrderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPurchaseOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPurchaseOrderStatus), errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultRecommendations is the number of books suggested when limit is not given
+const defaultRecommendations = 10
+
+// RecommendationHandler suggests books to readers
+type RecommendationHandler struct {
+	RecommendationService *services.RecommendationService
+}
+
+var (
+	RecommendationInstance *RecommendationHandler
+	RecommendationOnce     sync.Once
+)
+
+// NewRecommendationHandler initializes a singleton instance of RecommendationHandler.
+func NewRecommendationHandler(RecommendationService *services.RecommendationService) *RecommendationHandler {
+	RecommendationOnce.Do(func() {
+		RecommendationInstance = &RecommendationHandler{RecommendationService: RecommendationService}
+	})
+	return RecommendationInstance
+}
+
+// GetBookRecommendations lists the books bought along with a book, completed
+// with books of its author and of its genres
+func (h *RecommendationHandler) GetBookRecommendations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("RecommendationHandler.Book: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+	limit, err := limitParam(r)
+	if err != nil {
+		log.Printf("RecommendationHandler.Book: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.RecommendationService.RecommendBooks(id, limit)
+	if err != nil {
+		log.Printf("RecommendationHandler.Book: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), recommendationErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(recommendationsView(r, recommendations)); err != nil {
+		log.Printf("RecommendationHandler.Book: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("RecommendationHandler.Book: success, returned %d books, duration: %v", len(recommendations), time.Since(start))
+}
+
+// GetCustomerRecommendations lists the books matching the purchase history
+// of a customer; diversity, from 0 to 1, favours variety over the best matches
+func (h *RecommendationHandler) GetCustomerRecommendations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("RecommendationHandler.Customer: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, id) {
+		log.Printf("RecommendationHandler.Customer: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	limit, err := limitParam(r)
+	if err != nil {
+		log.Printf("RecommendationHandler.Customer: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	diversity := 0.0
+	if value := r.URL.Query().Get("diversity"); value != "" {
+		if diversity, err = strconv.ParseFloat(value, 64); err != nil {
+			log.Printf("RecommendationHandler.Customer: invalid input error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, fmt.Sprintf("Invalid diversity %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	recommendations, err := h.RecommendationService.RecommendForCustomer(id, limit, diversity)
+	if err != nil {
+		log.Printf("RecommendationHandler.Customer: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), recommendationErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(recommendationsView(r, recommendations)); err != nil {
+		log.Printf("RecommendationHandler.Customer: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("RecommendationHandler.Customer: success, returned %d books, duration: %v", len(recommendations), time.Since(start))
+}
+
+// limitParam reads the number of recommendations asked for
+func limitParam(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultRecommendations, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid limit %q", value)
+	}
+	return limit, nil
+}
+
+// recommendationErrorStatus maps recommendation errors to HTTP status codes
+func recommendationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBookNotFound), errors.Is(err, services.ErrCustomerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
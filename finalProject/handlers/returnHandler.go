@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ReturnHandler handles the return of ordered books. Customers request
+// returns, an admin approves or rejects them.
+type ReturnHandler struct {
+	ReturnService *services.ReturnService
+}
+
+var (
+	ReturnInstance *ReturnHandler
+	ReturnOnce     sync.Once
+)
+
+// NewReturnHandler initializes a singleton instance of ReturnHandler.
+func NewReturnHandler(ReturnService *services.ReturnService) *ReturnHandler {
+	ReturnOnce.Do(func() {
+		ReturnInstance = &ReturnHandler{ReturnService: ReturnService}
+	})
+	return ReturnInstance
+}
+
+func (h *ReturnHandler) RequestReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	orderID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReturnHandler.Request: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOrderOwner(w, r, orderID) {
+		log.Printf("ReturnHandler.Request: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var request models.ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ReturnHandler.Request: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.ReturnService.As(actorOf(r)).RequestReturn(orderID, request)
+	if err != nil {
+		log.Printf("ReturnHandler.Request: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), returnErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		log.Printf("ReturnHandler.Request: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReturnHandler.Request: success, return: %d, duration: %v", ret.ID, time.Since(start))
+}
+
+func (h *ReturnHandler) GetOrderReturns(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	orderID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReturnHandler.GetByOrder: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOrderOwner(w, r, orderID) {
+		log.Printf("ReturnHandler.GetByOrder: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	returns, err := h.ReturnService.GetOrderReturns(orderID)
+	if err != nil {
+		log.Printf("ReturnHandler.GetByOrder: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), returnErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(returns); err != nil {
+		log.Printf("ReturnHandler.GetByOrder: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReturnHandler.GetByOrder: success, returned %d returns, duration: %v", len(returns), time.Since(start))
+}
+
+func (h *ReturnHandler) GetReturnById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReturnHandler.GetById: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Return ID", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.ReturnService.GetReturn(id)
+	if err != nil {
+		log.Printf("ReturnHandler.GetById: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), returnErrorStatus(err))
+		return
+	}
+	if !requireOrderOwner(w, r, ret.OrderID) {
+		log.Printf("ReturnHandler.GetById: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		log.Printf("ReturnHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReturnHandler.GetById: success, duration: %v", time.Since(start))
+}
+
+// GetReturnsByCriteria lists the returns for the admin, filtered by the
+// optional status and order_id query parameters
+func (h *ReturnHandler) GetReturnsByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("ReturnHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	query := models.SearchCriteria{Filters: make(map[string]interface{})}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query.Filters["status"] = status
+	}
+	if value := r.URL.Query().Get("order_id"); value != "" {
+		orderID, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("ReturnHandler.Search: invalid input error: %v, duration: %v", err, time.Since(start))
+			http.Error(w, "Invalid order_id", http.StatusBadRequest)
+			return
+		}
+		query.Filters["order_id"] = orderID
+	}
+
+	returns, err := h.ReturnService.SearchReturns(query)
+	if err != nil {
+		log.Printf("ReturnHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(returns); err != nil {
+		log.Printf("ReturnHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReturnHandler.Search: success, returned %d returns, duration: %v", len(returns), time.Since(start))
+}
+
+func (h *ReturnHandler) ApproveReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !requireAdmin(w, r) {
+		log.Printf("ReturnHandler.Approve: forbidden")
+		return
+	}
+	h.decide(w, ps, "Approve", func(id int) (models.Return, error) {
+		return h.ReturnService.As(actorOf(r)).ApproveReturn(id)
+	})
+}
+
+// RejectReturn takes an optional body giving the reason of the rejection
+func (h *ReturnHandler) RejectReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !requireAdmin(w, r) {
+		log.Printf("ReturnHandler.Reject: forbidden")
+		return
+	}
+	var decision models.ReturnDecision
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("ReturnHandler.Reject: invalid input error: %v", err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.decide(w, ps, "Reject", func(id int) (models.Return, error) {
+		return h.ReturnService.As(actorOf(r)).RejectReturn(id, decision)
+	})
+}
+
+// decide runs an admin decision on a requested return
+func (h *ReturnHandler) decide(w http.ResponseWriter, ps httprouter.Params, name string, decision func(id int) (models.Return, error)) {
+	start := time.Now()
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReturnHandler.%s: invalid id error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, "Invalid Return ID", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := decision(id)
+	if err != nil {
+		log.Printf("ReturnHandler.%s: service error: %v, duration: %v", name, err, time.Since(start))
+		http.Error(w, err.Error(), returnErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		log.Printf("ReturnHandler.%s: encoding error: %v, duration: %v", name, err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReturnHandler.%s: success, return: %d, duration: %v", name, ret.ID, time.Since(start))
+}
+
+// returnErrorStatus maps return and refund errors to HTTP status codes
+func returnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrReturnNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderStatus), errors.Is(err, services.ErrReturnStatus),
+		errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ReviewHandler lets customers review and rate books
+type ReviewHandler struct {
+	ReviewService *services.ReviewService
+}
+
+var (
+	ReviewInstance *ReviewHandler
+	ReviewOnce     sync.Once
+)
+
+// NewReviewHandler initializes a singleton instance of ReviewHandler.
+func NewReviewHandler(ReviewService *services.ReviewService) *ReviewHandler {
+	ReviewOnce.Do(func() {
+		ReviewInstance = &ReviewHandler{ReviewService: ReviewService}
+	})
+	return ReviewInstance
+}
+
+// CreateReview adds a review of the customer signed in
+func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, ok := requireCustomer(w, r)
+	if !ok {
+		log.Printf("ReviewHandler.Create: unauthorized, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReviewHandler.Create: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	var review models.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		log.Printf("ReviewHandler.Create: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	review.CustomerID = customerID
+
+	created, err := h.ReviewService.As(actorOf(r)).CreateReview(bookID, review)
+	if err != nil {
+		log.Printf("ReviewHandler.Create: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("ReviewHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReviewHandler.Create: success, review: %d, duration: %v", created.ID, time.Since(start))
+}
+
+// GetReviews lists the reviews of a book, newest first, a page at a time
+func (h *ReviewHandler) GetReviews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	bookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("ReviewHandler.Search: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		log.Printf("ReviewHandler.Search: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, err := h.ReviewService.GetReviews(bookID, page, perPage)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("ReviewHandler.Search: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("ReviewHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reviews); err != nil {
+		log.Printf("ReviewHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReviewHandler.Search: success, returned %d of %d reviews, duration: %v", len(reviews.Reviews), reviews.Total, time.Since(start))
+}
+
+// UpdateReviewById changes the rating and text of a review; only the
+// customer who wrote it may, signed in
+func (h *ReviewHandler) UpdateReviewById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, ok := requireCustomer(w, r)
+	if !ok {
+		log.Printf("ReviewHandler.Update: unauthorized, duration: %v", time.Since(start))
+		return
+	}
+	bookID, reviewID, err := reviewPathIDs(ps)
+	if err != nil {
+		log.Printf("ReviewHandler.Update: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var review models.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		log.Printf("ReviewHandler.Update: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	review.ID, review.CustomerID = reviewID, customerID
+
+	updated, err := h.ReviewService.As(actorOf(r)).UpdateReview(bookID, review)
+	if err != nil {
+		log.Printf("ReviewHandler.Update: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		log.Printf("ReviewHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("ReviewHandler.Update: success, review: %d, duration: %v", updated.ID, time.Since(start))
+}
+
+// DeleteReviewById removes a review; only admins may, to moderate reviews
+func (h *ReviewHandler) DeleteReviewById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("ReviewHandler.Delete: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, reviewID, err := reviewPathIDs(ps)
+	if err != nil {
+		log.Printf("ReviewHandler.Delete: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ReviewService.As(actorOf(r)).DeleteReview(bookID, reviewID); err != nil {
+		log.Printf("ReviewHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("ReviewHandler.Delete: success, duration: %v", time.Since(start))
+}
+
+func reviewPathIDs(ps httprouter.Params) (bookID, reviewID int, err error) {
+	if bookID, err = strconv.Atoi(ps.ByName("id")); err != nil {
+		return 0, 0, errors.New("Invalid Book ID")
+	}
+	if reviewID, err = strconv.Atoi(ps.ByName("reviewId")); err != nil {
+		return 0, 0, errors.New("Invalid Review ID")
+	}
+	return bookID, reviewID, nil
+}
+
+// reviewErrorStatus maps review errors to HTTP status codes
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBookNotFound), errors.Is(err, services.ErrReviewNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrReviewNotOwned):
+		return http.StatusForbidden
+	case errors.Is(err, repositories.ErrAlreadyReviewed), errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
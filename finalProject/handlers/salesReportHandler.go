@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SalesReportHandler generates sales reports from the orders.
+type SalesReportHandler struct {
+	SalesReportService *services.SalesReportService
+}
+
+var (
+	SalesReportInstance *SalesReportHandler
+	SalesReportOnce     sync.Once
+)
+
+// NewSalesReportHandler initializes a singleton instance of SalesReportHandler.
+func NewSalesReportHandler(SalesReportService *services.SalesReportService) *SalesReportHandler {
+	SalesReportOnce.Do(func() {
+		SalesReportInstance = &SalesReportHandler{SalesReportService: SalesReportService}
+	})
+	return SalesReportInstance
+}
+
+// GenerateSalesReport reports on the orders created between the optional
+// from and to query parameters, given as RFC 3339 times or dates. The report
+// tells margins and what customers spent, so only admins may generate it.
+func (h *SalesReportHandler) GenerateSalesReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SalesReportHandler.Generate: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	from, err := parseTimeParam(r, "from")
+	if err != nil {
+		log.Printf("SalesReportHandler.Generate: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(r, "to")
+	if err != nil {
+		log.Printf("SalesReportHandler.Generate: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		var acceptable bool
+		if format, acceptable = acceptedReportFormat(r.Header.Get("Accept")); !acceptable {
+			log.Printf("SalesReportHandler.Generate: not acceptable: %q, duration: %v", r.Header.Get("Accept"), time.Since(start))
+			http.Error(w, "Reports are available as application/json, text/csv, application/vnd.ms-excel or text/html", http.StatusNotAcceptable)
+			return
+		}
+	} else if !slices.Contains(reportFormats, format) {
+		log.Printf("SalesReportHandler.Generate: unsupported format %q, duration: %v", format, time.Since(start))
+		http.Error(w, "Unsupported format, use json, csv, excel or html", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.SalesReportService.GenerateSalesReport(from, to)
+	if err != nil {
+		log.Printf("SalesReportHandler.Generate: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case services.FormatJSON:
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+	case services.FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = h.SalesReportService.ExportSalesReport(w, report, format)
+	default:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="sales-report.csv"`)
+		err = h.SalesReportService.ExportSalesReport(w, report, format)
+	}
+	if err != nil {
+		log.Printf("SalesReportHandler.Generate: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SalesReportHandler.Generate: success, %d orders as %s, duration: %v", report.TotalOrders, format, time.Since(start))
+}
+
+// reportMediaTypes maps the media types a report is available in to its
+// formats; spreadsheets get CSV they open as is
+var reportMediaTypes = map[string]string{
+	"application/json":         services.FormatJSON,
+	"text/csv":                 services.FormatCSV,
+	"application/vnd.ms-excel": services.FormatExcel,
+	"text/html":                services.FormatHTML,
+}
+
+// reportFormats are the values of the ?format query parameter, which takes
+// precedence over the Accept header
+var reportFormats = []string{services.FormatJSON, services.FormatCSV, services.FormatExcel, services.FormatHTML}
+
+// acceptedReportFormat picks the report format the Accept header prefers,
+// JSON when there is none. It returns false when no format is acceptable.
+func acceptedReportFormat(accept string) (string, bool) {
+	if accept == "" {
+		return services.FormatJSON, true
+	}
+	format, best := "", 0.0
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, exists := params["q"]; exists {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		candidate, known := reportMediaTypes[mediaType]
+		if mediaType == "*/*" || mediaType == "application/*" {
+			candidate, known = services.FormatJSON, true
+		}
+		if known && quality > best {
+			format, best = candidate, quality
+		}
+	}
+	return format, format != ""
+}
+
+// parseTimeParam reads an optional query parameter holding an RFC 3339 time
+// or a date; it returns the zero time when the parameter is absent
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s: %q is neither an RFC 3339 time nor a date", name, value)
+	}
+	return t, nil
+}
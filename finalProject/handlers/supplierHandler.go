@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SupplierHandler manages the suppliers the books are purchased from; it
+// is part of the back office and only admins may use it
+type SupplierHandler struct {
+	SupplierService *services.SupplierService
+}
+
+var (
+	SupplierInstance *SupplierHandler
+	SupplierOnce     sync.Once
+)
+
+// NewSupplierHandler initializes a singleton instance of SupplierHandler.
+func NewSupplierHandler(SupplierService *services.SupplierService) *SupplierHandler {
+	SupplierOnce.Do(func() {
+		SupplierInstance = &SupplierHandler{SupplierService: SupplierService}
+	})
+	return SupplierInstance
+}
+
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.Create: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var supplier models.Supplier
+	if err := json.NewDecoder(r.Body).Decode(&supplier); err != nil {
+		log.Printf("SupplierHandler.Create: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createdSupplier, err := h.SupplierService.As(actorOf(r)).CreateSupplier(supplier)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("SupplierHandler.Create: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("SupplierHandler.Create: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, createdSupplier.Version)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(createdSupplier); err != nil {
+		log.Printf("SupplierHandler.Create: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SupplierHandler.Create: success, duration: %v", time.Since(start))
+}
+
+func (h *SupplierHandler) GetSupplierById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.GetById: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("SupplierHandler.GetById: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Supplier ID", http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := h.SupplierService.GetSupplier(id)
+	if err != nil {
+		log.Printf("SupplierHandler.GetById: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	setETag(w, supplier.Version)
+	if notModified(w, r, supplier.Version) {
+		log.Printf("SupplierHandler.GetById: not modified, duration: %v", time.Since(start))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(supplier); err != nil {
+		log.Printf("SupplierHandler.GetById: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SupplierHandler.GetById: success, duration: %v", time.Since(start))
+}
+
+func (h *SupplierHandler) GetSuppliersByCriteria(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.Search: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var query = models.SearchCriteria{
+		Filters:        make(map[string]interface{}),
+		IncludeDeleted: r.URL.Query().Get("include_deleted") == "true",
+	}
+	if err := json.NewDecoder(r.Body).Decode(&query.Filters); err != nil {
+		query.Filters = make(map[string]interface{})
+		log.Printf("SupplierHandler.Search: invalid criteria error: %v, duration: %v", err, time.Since(start))
+	}
+
+	suppliers, err := h.SupplierService.SearchSuppliers(query)
+	if err != nil {
+		log.Printf("SupplierHandler.Search: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(suppliers); err != nil {
+		log.Printf("SupplierHandler.Search: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SupplierHandler.Search: success, returned %d suppliers, duration: %v", len(suppliers), time.Since(start))
+}
+
+func (h *SupplierHandler) UpdateSupplierById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.Update: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("SupplierHandler.Update: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Supplier ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("SupplierHandler.Update: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	var supplier models.Supplier
+	if err = json.NewDecoder(r.Body).Decode(&supplier); err != nil {
+		log.Printf("SupplierHandler.Update: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	supplier.ID = id
+	supplier.Version = version
+
+	updatedSupplier, err := h.SupplierService.As(actorOf(r)).UpdateSupplier(supplier)
+	if errors.Is(err, services.ErrValidation) {
+		log.Printf("SupplierHandler.Update: validation error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("SupplierHandler.Update: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		log.Printf("SupplierHandler.Update: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	setETag(w, updatedSupplier.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updatedSupplier); err != nil {
+		log.Printf("SupplierHandler.Update: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SupplierHandler.Update: success, duration: %v", time.Since(start))
+}
+
+func (h *SupplierHandler) DeleteSupplierById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.Delete: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("SupplierHandler.Delete: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Supplier ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Printf("SupplierHandler.Delete: precondition error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), preconditionStatus(err))
+		return
+	}
+
+	err = h.SupplierService.As(actorOf(r)).DeleteSupplier(id, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("SupplierHandler.Delete: precondition failed: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier was modified by another request", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		log.Printf("SupplierHandler.Delete: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("SupplierHandler.Delete: success, duration: %v", time.Since(start))
+}
+
+// RestoreSupplierById brings back a supplier that was deleted and not purged yet;
+// only admins may restore
+func (h *SupplierHandler) RestoreSupplierById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	if !requireAdmin(w, r) {
+		log.Printf("SupplierHandler.Restore: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("SupplierHandler.Restore: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Supplier ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.SupplierService.As(actorOf(r)).RestoreSupplier(id)
+	if err != nil {
+		log.Printf("SupplierHandler.Restore: not found error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Supplier not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, restored.Version)
+	if err := json.NewEncoder(w).Encode(restored); err != nil {
+		log.Printf("SupplierHandler.Restore: encoding error: %v, duration: %v", err, time.Since(start))
+		return
+	}
+
+	log.Printf("SupplierHandler.Restore: success, duration: %v", time.Since(start))
+}
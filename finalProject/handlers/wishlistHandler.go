@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+	"bookstore.com/services"
+	"github.com/julienschmidt/httprouter"
+)
+
+// WishlistHandler handles the wishlist of a customer.
+type WishlistHandler struct {
+	WishlistService *services.WishlistService
+}
+
+var (
+	WishlistInstance *WishlistHandler
+	WishlistOnce     sync.Once
+)
+
+// NewWishlistHandler initializes a singleton instance of WishlistHandler.
+func NewWishlistHandler(WishlistService *services.WishlistService) *WishlistHandler {
+	WishlistOnce.Do(func() {
+		WishlistInstance = &WishlistHandler{WishlistService: WishlistService}
+	})
+	return WishlistInstance
+}
+
+func (h *WishlistHandler) GetWishlist(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("WishlistHandler.Get: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("WishlistHandler.Get: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	wishlist, err := h.WishlistService.GetWishlist(customerID)
+	if err != nil {
+		log.Printf("WishlistHandler.Get: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), wishlistErrorStatus(err))
+		return
+	}
+
+	writeWishlist(w, wishlist)
+	log.Printf("WishlistHandler.Get: success, duration: %v", time.Since(start))
+}
+
+func (h *WishlistHandler) AddWishlistItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("WishlistHandler.AddItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("WishlistHandler.AddItem: forbidden, duration: %v", time.Since(start))
+		return
+	}
+
+	var request models.WishlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WishlistHandler.AddItem: invalid input error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wishlist, err := h.WishlistService.As(actorOf(r)).AddItem(customerID, request)
+	if err != nil {
+		log.Printf("WishlistHandler.AddItem: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), wishlistErrorStatus(err))
+		return
+	}
+
+	writeWishlist(w, wishlist)
+	log.Printf("WishlistHandler.AddItem: success, duration: %v", time.Since(start))
+}
+
+func (h *WishlistHandler) RemoveWishlistItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+
+	customerID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		log.Printf("WishlistHandler.RemoveItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+	if !requireOwner(w, r, customerID) {
+		log.Printf("WishlistHandler.RemoveItem: forbidden, duration: %v", time.Since(start))
+		return
+	}
+	bookID, err := strconv.Atoi(ps.ByName("bookId"))
+	if err != nil {
+		log.Printf("WishlistHandler.RemoveItem: invalid id error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
+		return
+	}
+
+	wishlist, err := h.WishlistService.As(actorOf(r)).RemoveItem(customerID, bookID)
+	if err != nil {
+		log.Printf("WishlistHandler.RemoveItem: service error: %v, duration: %v", err, time.Since(start))
+		http.Error(w, err.Error(), wishlistErrorStatus(err))
+		return
+	}
+
+	writeWishlist(w, wishlist)
+	log.Printf("WishlistHandler.RemoveItem: success, duration: %v", time.Since(start))
+}
+
+func writeWishlist(w http.ResponseWriter, wishlist models.Wishlist) {
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, wishlist.Version)
+	if err := json.NewEncoder(w).Encode(wishlist); err != nil {
+		log.Printf("WishlistHandler: encoding error: %v", err)
+	}
+}
+
+// wishlistErrorStatus maps wishlist service errors to HTTP status codes
+func wishlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrWishlistItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repositories.ErrVersionConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"bookstore.com/handlers"
 	"bookstore.com/memory"
+	"bookstore.com/middleware"
 	"bookstore.com/services"
 	"github.com/julienschmidt/httprouter"
 )
@@ -15,6 +21,55 @@ import (
 var database *memory.InMemoryStore
 var err error
 
+// storeGate is taken for writing by atomic batches while they may roll the
+// stores back; requests and background jobs hold it for reading
+var storeGate sync.RWMutex
+
+// Token bucket per client for each route group, see routeGroup
+var rateLimits = map[string]middleware.RateLimit{
+	"catalog": {Rate: 20, Burst: 40},
+	"orders":  {Rate: 1, Burst: 5},
+	"auth":    {Rate: 0.2, Burst: 5},
+	"default": {Rate: 10, Burst: 20},
+}
+
+// Rate tables and shipping zones used to price orders, relative to the
+// working directory like database.json
+const (
+	taxRatesFile      = "config/taxRates.json"
+	shippingZonesFile = "config/shippingZones.json"
+)
+
+// How long a payment authorization holds the stock of an order, and how
+// often expired authorizations are looked for
+const (
+	paymentAuthorizationLifetime = 7 * 24 * time.Hour
+	paymentExpiryInterval        = time.Minute
+)
+
+// Deleted records can be restored for deletedRetention, the purge job
+// removes them for good after that
+const (
+	deletedRetention = 30 * 24 * time.Hour
+	purgeInterval    = time.Hour
+)
+
+// notificationInterval is how often the queued notifications are delivered
+const notificationInterval = 5 * time.Second
+
+// notificationFileVariable names the environment variable holding the file
+// notifications are appended to; without it they are written to the log
+const notificationFileVariable = "BOOKSTORE_NOTIFICATIONS_FILE"
+
+// apiKeysVariable names the environment variable listing the API keys given
+// out to clients, separated by commas. Clients sending one in the X-API-Key
+// header are rate limited by key, all others by IP address.
+const apiKeysVariable = "BOOKSTORE_API_KEYS"
+
+// adminKeyVariable names the environment variable holding the key of the
+// back office endpoints, sent by admins in the X-Admin-Key header
+const adminKeyVariable = "BOOKSTORE_ADMIN_KEY"
+
 // Initialize database
 func init() {
 	database, err = memory.NewInMemoryStore()
@@ -48,22 +103,155 @@ func DispatcherWrapper(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 
 func main() {
 	// Initialize the book service with the in-memory store
-	bookHandler := handlers.NewBookHandler(services.NewBookService(&database.BookStore))
-	authorHandler := handlers.NewAuthorHandler(services.NewAuthorService(&database.AuthorStore))
-	customerHandler := handlers.NewCustomerHandler(services.NewCustomerService(&database.CustomerStore))
-	orderHandler := handlers.NewOrderHandler(services.NewOrderService(&database.OrderStore))
+	bookHandler := handlers.NewBookHandler(services.NewBookService(database.BookStore))
+	authorHandler := handlers.NewAuthorHandler(services.NewAuthorService(database.AuthorStore))
+	customerHandler := handlers.NewCustomerHandler(services.NewCustomerService(database.CustomerStore))
+	taxes, shipping := loadPricing()
+	orderHandler := handlers.NewOrderHandler(services.NewOrderService(database.OrderStore).WithCalculators(taxes, shipping))
+	notifier := newNotifier()
+	authHandler := handlers.NewAuthHandler(services.NewAuthService(database.AuthStore, database.CustomerStore).WithNotifier(notifier))
+	paymentProvider := services.NewFakePaymentProvider(paymentAuthorizationLifetime)
+	if err := paymentProvider.Resume(database.PaymentStore); err != nil {
+		log.Fatalf("Error resuming the payment provider: %v", err)
+	}
+	paymentService := services.NewPaymentService(database.PaymentStore, orderHandler.OrderService, paymentProvider)
+	paymentService.StartExpiryWatcher(paymentExpiryInterval, storeGate.RLocker())
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	returnHandler := handlers.NewReturnHandler(services.NewReturnService(database.ReturnStore, orderHandler.OrderService, paymentService))
+	salesReportHandler := handlers.NewSalesReportHandler(services.NewSalesReportService(database.SalesReport))
+	promotionHandler := handlers.NewPromotionHandler(services.NewPromotionService(database.PromotionStore))
+	cartHandler := handlers.NewCartHandler(services.NewCartService(database.CartStore, orderHandler.OrderService))
+	auditHandler := handlers.NewAuditHandler(services.NewAuditService(database.AuditStore))
+	inventoryService := services.NewInventoryService(database.StockStore)
+	if err := inventoryService.OpenLedger(); err != nil {
+		log.Fatalf("Error opening the inventory ledger: %v", err)
+	}
+	stockAlertService := services.NewStockAlertService(database.AlertStore)
+	if err := stockAlertService.CheckAll(time.Now()); err != nil {
+		log.Fatalf("Error checking the stock alerts: %v", err)
+	}
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService, stockAlertService)
+	supplierHandler := handlers.NewSupplierHandler(services.NewSupplierService(database.SupplierStore))
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(services.NewPurchaseOrderService(database.PurchaseStore))
+	recommendationHandler := handlers.NewRecommendationHandler(services.NewRecommendationService(database.CoPurchases))
+	reviewHandler := handlers.NewReviewHandler(services.NewReviewService(database.ReviewStore))
+	wishlistHandler := handlers.NewWishlistHandler(services.NewWishlistService(database.WishlistStore))
+	services.NewNotificationService(database.Notifications, notifier).StartDispatcher(notificationInterval, storeGate.RLocker())
+	services.NewRetentionService(deletedRetention, map[string]services.Purger{
+		"books":      database.BookStore,
+		"authors":    database.AuthorStore,
+		"customers":  database.CustomerStore,
+		"orders":     database.OrderStore,
+		"promotions": database.PromotionStore,
+		"suppliers":  database.SupplierStore,
+	}).StartPurgeJob(purgeInterval, storeGate.RLocker())
+	handlers.SetAdminKey(os.Getenv(adminKeyVariable))
+	if os.Getenv(adminKeyVariable) == "" {
+		log.Printf("%s is not set, admin endpoints are disabled", adminKeyVariable)
+	}
 	// Set up router
 	router := httprouter.New()
 	handleBookRequests(router, bookHandler)
 	handleAuthorRequests(router, authorHandler)
 	handleCustomerRequests(router, customerHandler)
 	handleOrderRequests(router, orderHandler)
+	handlePaymentRequests(router, paymentHandler)
+	handleReturnRequests(router, returnHandler)
+	handleAuthRequests(router, authHandler)
+	handleCartRequests(router, cartHandler)
+	handleWishlistRequests(router, wishlistHandler)
+	handlePromotionRequests(router, promotionHandler)
+	handleReportRequests(router, salesReportHandler)
+	handleAuditRequests(router, auditHandler)
+	handleInventoryRequests(router, inventoryHandler)
+	handleSupplierRequests(router, supplierHandler)
+	handlePurchaseOrderRequests(router, purchaseOrderHandler)
+	handleRecommendationRequests(router, recommendationHandler)
+	handleReviewRequests(router, reviewHandler)
 
 	//database.Schedule()
 
+	// httprouter cannot register "/books:import" next to "/books/:id", so
+	// these collection methods are matched by a ServeMux in front of it
+	mux := http.NewServeMux()
+	mux.Handle("/", router)
+	handleBookTransferRequests(mux, bookHandler)
+	handleAuditExportRequests(mux, auditHandler)
+	handleCustomerDuplicateRequests(mux, customerHandler)
+
+	// Sub-requests of a batch go through the limiter too, each charged to
+	// its own route group
+	limiter := middleware.NewRateLimiter(rateLimits, routeGroup, 10*time.Minute, apiKeys())
+	batchHandler := handlers.NewBatchHandler(limiter.Middleware(mux), database, &storeGate)
+	handleBatchRequests(router, batchHandler)
+
 	// Start the HTTP server
 	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	log.Fatal(http.ListenAndServe(":8080", limiter.Middleware(batchHandler.Guard(mux))))
+}
+
+// loadPricing reads the tax and shipping configuration. A missing file
+// leaves orders untaxed or shipped for free, an invalid one stops the server.
+func loadPricing() (services.TaxCalculator, services.ShippingCalculator) {
+	var taxes services.TaxCalculator = &services.TaxTable{}
+	table, err := services.LoadTaxTable(taxRatesFile)
+	switch {
+	case err == nil:
+		taxes = table
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("%s not found, orders are not taxed", taxRatesFile)
+	default:
+		log.Fatal(err)
+	}
+
+	var shipping services.ShippingCalculator = &services.ShippingZones{}
+	zones, err := services.LoadShippingZones(shippingZonesFile)
+	switch {
+	case err == nil:
+		shipping = zones
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("%s not found, shipping is free", shippingZonesFile)
+	default:
+		log.Fatal(err)
+	}
+	return taxes, shipping
+}
+
+// apiKeys reads the API keys from apiKeysVariable
+func apiKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(os.Getenv(apiKeysVariable), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// newNotifier appends the notifications to the file named by
+// notificationFileVariable, or writes them to the log when it is not set
+func newNotifier() services.Notifier {
+	if path := os.Getenv(notificationFileVariable); path != "" {
+		return services.NewFileNotifier(path)
+	}
+	log.Printf("%s is not set, notifications are written to the log", notificationFileVariable)
+	return services.NewLogNotifier()
+}
+
+// routeGroup sorts requests into the rate limit groups: catalog reads,
+// order writes, authentication and everything else
+func routeGroup(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/auth/"):
+		return "auth"
+	case strings.HasPrefix(r.URL.Path, "/orders") && r.Method != http.MethodGet,
+		strings.HasSuffix(r.URL.Path, "/cart/checkout"):
+		return "orders"
+	case (strings.HasPrefix(r.URL.Path, "/books") || strings.HasPrefix(r.URL.Path, "/authors")) && r.Method == http.MethodGet:
+		return "catalog"
+	default:
+		return "default"
+	}
 }
 
 func handleBookRequests(router *httprouter.Router, bookHandler *handlers.BookHandler) {
@@ -79,9 +267,79 @@ func handleBookRequests(router *httprouter.Router, bookHandler *handlers.BookHan
 	router.PUT("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, bookHandler.UpdateBookById)
 	})
+	router.PATCH("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, bookHandler.PatchBookById)
+	})
 	router.DELETE("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, bookHandler.DeleteBookById)
 	})
+	router.POST("/books/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, bookHandler.RestoreBookById)
+	})
+
+}
+
+func handleBookTransferRequests(mux *http.ServeMux, bookHandler *handlers.BookHandler) {
+	mux.HandleFunc("POST /books:import", func(w http.ResponseWriter, r *http.Request) {
+		DispatcherWrapper(w, r, nil, bookHandler.ImportBooks)
+	})
+	mux.HandleFunc("GET /books:export", func(w http.ResponseWriter, r *http.Request) {
+		DispatcherWrapper(w, r, nil, bookHandler.ExportBooks)
+	})
+
+}
+
+func handleRecommendationRequests(router *httprouter.Router, recommendationHandler *handlers.RecommendationHandler) {
+	router.GET("/books/:id/recommendations", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, recommendationHandler.GetBookRecommendations)
+	})
+	router.GET("/customers/:id/recommendations", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, recommendationHandler.GetCustomerRecommendations)
+	})
+
+}
+
+func handleReviewRequests(router *httprouter.Router, reviewHandler *handlers.ReviewHandler) {
+	router.POST("/books/:id/reviews", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, reviewHandler.CreateReview)
+	})
+	router.GET("/books/:id/reviews", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, reviewHandler.GetReviews)
+	})
+	router.PUT("/books/:id/reviews/:reviewId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, reviewHandler.UpdateReviewById)
+	})
+	router.DELETE("/books/:id/reviews/:reviewId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, reviewHandler.DeleteReviewById)
+	})
+
+}
+
+func handleCustomerDuplicateRequests(mux *http.ServeMux, customerHandler *handlers.CustomerHandler) {
+	mux.HandleFunc("GET /customers:duplicates", func(w http.ResponseWriter, r *http.Request) {
+		DispatcherWrapper(w, r, nil, customerHandler.FindDuplicateCustomers)
+	})
+
+}
+
+func handleAuditRequests(router *httprouter.Router, auditHandler *handlers.AuditHandler) {
+	router.GET("/audit", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, auditHandler.GetAuditEntries)
+	})
+
+}
+
+func handleAuditExportRequests(mux *http.ServeMux, auditHandler *handlers.AuditHandler) {
+	mux.HandleFunc("GET /audit:export", func(w http.ResponseWriter, r *http.Request) {
+		DispatcherWrapper(w, r, nil, auditHandler.ExportAuditEntries)
+	})
+
+}
+
+func handleBatchRequests(router *httprouter.Router, batchHandler *handlers.BatchHandler) {
+	router.POST("/batch", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, batchHandler.RunBatch)
+	})
 
 }
 
@@ -98,9 +356,15 @@ func handleAuthorRequests(router *httprouter.Router, authorHandler *handlers.Aut
 	router.PUT("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, authorHandler.UpdateAuthorById)
 	})
+	router.PATCH("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authorHandler.PatchAuthorById)
+	})
 	router.DELETE("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, authorHandler.DeleteAuthorById)
 	})
+	router.POST("/authors/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authorHandler.RestoreAuthorById)
+	})
 
 }
 
@@ -117,11 +381,181 @@ func handleCustomerRequests(router *httprouter.Router, customerHandler *handlers
 	router.PUT("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, customerHandler.UpdateCustomerById)
 	})
+	router.PATCH("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, customerHandler.PatchCustomerById)
+	})
 	router.DELETE("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, customerHandler.DeleteCustomerById)
 	})
+	router.POST("/customers/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, customerHandler.RestoreCustomerById)
+	})
+	router.GET("/customers/:id/orders", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, customerHandler.GetCustomerOrders)
+	})
+	router.GET("/customers/:id/summary", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, customerHandler.GetCustomerSummary)
+	})
+	router.POST("/customers/:id/merge", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, customerHandler.MergeCustomers)
+	})
 
 }
+func handleCartRequests(router *httprouter.Router, cartHandler *handlers.CartHandler) {
+	router.GET("/customers/:id/cart", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.GetCart)
+	})
+	router.DELETE("/customers/:id/cart", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.ClearCart)
+	})
+	router.POST("/customers/:id/cart/items", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.AddCartItem)
+	})
+	router.PUT("/customers/:id/cart/items/:bookId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.UpdateCartItem)
+	})
+	router.DELETE("/customers/:id/cart/items/:bookId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.RemoveCartItem)
+	})
+	router.POST("/customers/:id/cart/checkout", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, cartHandler.CheckoutCart)
+	})
+
+}
+
+func handleWishlistRequests(router *httprouter.Router, wishlistHandler *handlers.WishlistHandler) {
+	router.GET("/customers/:id/wishlist", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, wishlistHandler.GetWishlist)
+	})
+	router.POST("/customers/:id/wishlist/items", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, wishlistHandler.AddWishlistItem)
+	})
+	router.DELETE("/customers/:id/wishlist/items/:bookId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, wishlistHandler.RemoveWishlistItem)
+	})
+}
+
+func handlePaymentRequests(router *httprouter.Router, paymentHandler *handlers.PaymentHandler) {
+	router.POST("/orders/:id/payments", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, paymentHandler.AuthorizePayment)
+	})
+	router.GET("/orders/:id/payments", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, paymentHandler.GetPayments)
+	})
+	router.POST("/orders/:id/payments/capture", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, paymentHandler.CapturePayment)
+	})
+	router.POST("/orders/:id/payments/void", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, paymentHandler.VoidPayment)
+	})
+
+}
+
+func handleInventoryRequests(router *httprouter.Router, inventoryHandler *handlers.InventoryHandler) {
+	router.POST("/books/:id/stock-movements", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, inventoryHandler.RecordStockMovement)
+	})
+	router.GET("/books/:id/stock-history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, inventoryHandler.GetStockHistory)
+	})
+	router.GET("/inventory/alerts", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, inventoryHandler.GetStockAlerts)
+	})
+
+}
+
+func handleReturnRequests(router *httprouter.Router, returnHandler *handlers.ReturnHandler) {
+	router.POST("/orders/:id/returns", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.RequestReturn)
+	})
+	router.GET("/orders/:id/returns", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.GetOrderReturns)
+	})
+	router.GET("/returns", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.GetReturnsByCriteria)
+	})
+	router.GET("/returns/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.GetReturnById)
+	})
+	router.POST("/returns/:id/approve", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.ApproveReturn)
+	})
+	router.POST("/returns/:id/reject", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, returnHandler.RejectReturn)
+	})
+
+}
+
+func handlePromotionRequests(router *httprouter.Router, promotionHandler *handlers.PromotionHandler) {
+	router.POST("/promotions", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.CreatePromotion)
+	})
+	router.GET("/promotions/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.GetPromotionById)
+	})
+	router.GET("/promotions", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.GetPromotionsByCriteria)
+	})
+	router.PUT("/promotions/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.UpdatePromotionById)
+	})
+	router.DELETE("/promotions/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.DeletePromotionById)
+	})
+	router.POST("/promotions/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, promotionHandler.RestorePromotionById)
+	})
+
+}
+
+func handleSupplierRequests(router *httprouter.Router, supplierHandler *handlers.SupplierHandler) {
+	router.POST("/suppliers", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.CreateSupplier)
+	})
+	router.GET("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.GetSupplierById)
+	})
+	router.GET("/suppliers", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.GetSuppliersByCriteria)
+	})
+	router.PUT("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.UpdateSupplierById)
+	})
+	router.DELETE("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.DeleteSupplierById)
+	})
+	router.POST("/suppliers/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, supplierHandler.RestoreSupplierById)
+	})
+
+}
+
+func handlePurchaseOrderRequests(router *httprouter.Router, purchaseOrderHandler *handlers.PurchaseOrderHandler) {
+	router.POST("/purchase-orders", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, purchaseOrderHandler.CreatePurchaseOrder)
+	})
+	router.GET("/purchase-orders", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, purchaseOrderHandler.GetPurchaseOrdersByCriteria)
+	})
+	router.GET("/purchase-orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, purchaseOrderHandler.GetPurchaseOrderById)
+	})
+	router.POST("/purchase-orders/:id/receive", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, purchaseOrderHandler.ReceivePurchaseOrder)
+	})
+	router.POST("/purchase-orders/:id/cancel", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, purchaseOrderHandler.CancelPurchaseOrder)
+	})
+
+}
+
+func handleReportRequests(router *httprouter.Router, salesReportHandler *handlers.SalesReportHandler) {
+	router.GET("/reports/sales", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, salesReportHandler.GenerateSalesReport)
+	})
+
+}
+
 func handleOrderRequests(router *httprouter.Router, orderHandler *handlers.OrderHandler) {
 	router.POST("/orders", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, orderHandler.CreateOrder)
@@ -135,8 +569,39 @@ func handleOrderRequests(router *httprouter.Router, orderHandler *handlers.Order
 	router.PUT("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, orderHandler.UpdateOrderById)
 	})
+	router.PATCH("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, orderHandler.PatchOrderById)
+	})
 	router.DELETE("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		DispatcherWrapper(w, r, ps, orderHandler.DeleteOrderById)
 	})
+	router.POST("/orders/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, orderHandler.RestoreOrderById)
+	})
+
+}
+
+func handleAuthRequests(router *httprouter.Router, authHandler *handlers.AuthHandler) {
+	router.POST("/auth/register", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.Register)
+	})
+	router.POST("/auth/login", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.Login)
+	})
+	router.POST("/auth/logout", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.Logout)
+	})
+	router.PUT("/auth/password", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.ChangePassword)
+	})
+	router.PUT("/auth/email", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.ChangeEmail)
+	})
+	router.POST("/auth/password/forgot", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.RequestPasswordReset)
+	})
+	router.POST("/auth/password/reset", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DispatcherWrapper(w, r, ps, authHandler.ResetPassword)
+	})
 
 }
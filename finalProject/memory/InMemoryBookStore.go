@@ -3,10 +3,13 @@ package memory
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
+	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 )
 
 type InMemoryBookStore struct {
@@ -38,6 +41,8 @@ func (s *InMemoryBookStore) Create(book models.Book) (models.Book, error) {
 	defer s.mu.Unlock()
 
 	book.ID = s.nextID
+	book.Version = 1
+	book.DeletedAt = nil
 	s.Books[s.nextID] = book
 	fmt.Println(s.Books)
 	s.nextID++
@@ -50,7 +55,7 @@ func (s *InMemoryBookStore) Get(id int) (models.Book, error) {
 	defer s.mu.Unlock()
 
 	book, exists := s.Books[id]
-	if !exists {
+	if !exists || book.DeletedAt != nil {
 		return models.Book{}, errors.New("book not found")
 	}
 	return book, nil
@@ -61,38 +66,89 @@ func (s *InMemoryBookStore) Update(book models.Book) (models.Book, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Books[book.ID]
-	if !exists {
+	current, exists := s.Books[book.ID]
+	if !exists || current.DeletedAt != nil {
 		return models.Book{}, errors.New("book not found")
 	}
+	if book.Version != 0 && book.Version != current.Version {
+		return models.Book{}, repositories.ErrVersionConflict
+	}
+	book.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore
+	book.DeletedAt = current.DeletedAt
 	s.Books[book.ID] = book
 	return book, nil
 }
 
-// Delete removes a book by ID
-func (s *InMemoryBookStore) Delete(id int) error {
+// Delete marks a book as deleted; it is kept until purged. A non-zero
+// version must match the stored one.
+func (s *InMemoryBookStore) Delete(id int, version int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Books[id]
-	if !exists {
+	book, exists := s.Books[id]
+	if !exists || book.DeletedAt != nil {
 		return errors.New("book not found")
 	}
-	delete(s.Books, id)
+	if version != 0 && book.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	book.DeletedAt = &now
+	book.Version++
+	s.Books[id] = book
 	return nil
 }
 
+// Restore brings back a deleted book; restoring a book that is not deleted
+// returns it unchanged
+func (s *InMemoryBookStore) Restore(id int) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, exists := s.Books[id]
+	if !exists {
+		return models.Book{}, errors.New("book not found")
+	}
+	if book.DeletedAt != nil {
+		book.DeletedAt = nil
+		book.Version++
+		s.Books[id] = book
+	}
+	return book, nil
+}
+
+// Purge removes for good the books deleted before the given time
+func (s *InMemoryBookStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, book := range s.Books {
+		if book.DeletedAt != nil && book.DeletedAt.Before(before) {
+			delete(s.Books, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
 // Search filters books based on the search criteria
 func (s *InMemoryBookStore) Search(query models.SearchCriteria) ([]models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	var results []models.Book
 	if len(query.Filters) == 0 {
 		for _, book := range s.Books {
-			results = append(results, book)
+			if book.DeletedAt == nil || query.IncludeDeleted {
+				results = append(results, book)
+			}
 		}
 		return results, nil
 	}
 	for _, book := range s.Books {
-		match := true
+		match := book.DeletedAt == nil || query.IncludeDeleted
 
 		if title, exists := query.Filters["title"]; exists {
 			if !strings.Contains(book.Title, title.(string)) {
@@ -132,3 +188,18 @@ func (s *InMemoryBookStore) Search(query models.SearchCriteria) ([]models.Book,
 
 	return results, nil
 }
+
+func (s *InMemoryBookStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	books := maps.Clone(s.Books)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Books = books
+		s.nextID = nextID
+	}
+}
@@ -1,7 +1,10 @@
 package memory
 
 import (
+	"cmp"
 	"errors"
+	"maps"
+	"slices"
 	"sync"
 
 	"bookstore.com/models"
@@ -79,14 +82,35 @@ func (s *InMemoryOrderItemStore) Delete(id int) error {
 	return nil
 }
 
-// Search filters order items based on the search criteria
+// Search filters order items by book_id, ordered by ID
 func (s *InMemoryOrderItemStore) Search(query models.SearchCriteria) ([]models.OrderItem, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var results []models.OrderItem
+	results := []models.OrderItem{}
 	for _, OrderItem := range s.OrderItems {
+		if bookID, exists := query.Filters["book_id"]; exists && OrderItem.Book.ID != bookID {
+			continue
+		}
 		results = append(results, OrderItem)
 	}
+	slices.SortFunc(results, func(a, b models.OrderItem) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
 	return results, nil
 }
+
+func (s *InMemoryOrderItemStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orderItems := maps.Clone(s.OrderItems)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.OrderItems = orderItems
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+)
+
+type InMemoryAuditStore struct {
+	mu      sync.Mutex
+	Entries []models.AuditEntry
+}
+
+var (
+	auditStoreInstance *InMemoryAuditStore
+	auditStoreOnce     sync.Once
+)
+
+// NewInMemoryAuditStore returns the singleton instance of InMemoryAuditStore
+func NewInMemoryAuditStore() *InMemoryAuditStore {
+	auditStoreOnce.Do(func() {
+		auditStoreInstance = &InMemoryAuditStore{}
+	})
+	return auditStoreInstance
+}
+
+// Append adds an entry at the end of the log, numbered after the last one
+func (s *InMemoryAuditStore) Append(entry models.AuditEntry) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = 1
+	if len(s.Entries) > 0 {
+		entry.ID = s.Entries[len(s.Entries)-1].ID + 1
+	}
+	s.Entries = append(s.Entries, entry)
+	return entry, nil
+}
+
+// Search filters entries by entity, entity_id, actor, action and a
+// timestamp range (from inclusive, to exclusive), oldest first
+func (s *InMemoryAuditStore) Search(query models.SearchCriteria) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.AuditEntry{}
+	for _, entry := range s.Entries {
+		if entity, exists := query.Filters["entity"]; exists && entry.Entity != entity {
+			continue
+		}
+		if entityID, exists := query.Filters["entity_id"]; exists && entry.EntityID != entityID {
+			continue
+		}
+		if actor, exists := query.Filters["actor"]; exists && entry.Actor != actor {
+			continue
+		}
+		if action, exists := query.Filters["action"]; exists && entry.Action != action {
+			continue
+		}
+		if from, exists := query.Filters["from"].(time.Time); exists && entry.Timestamp.Before(from) {
+			continue
+		}
+		if to, exists := query.Filters["to"].(time.Time); exists && !entry.Timestamp.Before(to) {
+			continue
+		}
+		results = append(results, entry)
+	}
+	return results, nil
+}
+
+func (s *InMemoryAuditStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := slices.Clone(s.Entries)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Entries = entries
+	}
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryAuthStore struct {
+	mu             sync.Mutex
+	Credentials    map[int]models.Credential
+	Sessions       map[string]models.Session
+	PasswordResets map[string]models.PasswordReset
+	LoginAttempts  map[string]models.LoginAttempt
+}
+
+var (
+	authStoreInstance *InMemoryAuthStore
+	authStoreOnce     sync.Once
+)
+
+// NewInMemoryAuthStore returns the singleton instance of InMemoryAuthStore
+func NewInMemoryAuthStore() *InMemoryAuthStore {
+	authStoreOnce.Do(func() {
+		authStoreInstance = &InMemoryAuthStore{
+			Credentials:    make(map[int]models.Credential),
+			Sessions:       make(map[string]models.Session),
+			PasswordResets: make(map[string]models.PasswordReset),
+			LoginAttempts:  make(map[string]models.LoginAttempt),
+		}
+	})
+	return authStoreInstance
+}
+
+// SaveCredential creates or replaces the password hash of a customer
+func (s *InMemoryAuthStore) SaveCredential(credential models.Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Credentials[credential.CustomerID] = credential
+	return nil
+}
+
+// GetCredential retrieves the password hash of a customer
+func (s *InMemoryAuthStore) GetCredential(customerID int) (models.Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, exists := s.Credentials[customerID]
+	if !exists {
+		return models.Credential{}, repositories.ErrNotFound
+	}
+	return credential, nil
+}
+
+// CreateSession stores a new session
+func (s *InMemoryAuthStore) CreateSession(session models.Session) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sessions[session.ID] = session
+	return session, nil
+}
+
+// GetSession retrieves a session, dropping it if it has expired
+func (s *InMemoryAuthStore) GetSession(id string) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.Sessions[id]
+	if !exists {
+		return models.Session{}, repositories.ErrNotFound
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.Sessions, id)
+		return models.Session{}, repositories.ErrNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession removes a single session
+func (s *InMemoryAuthStore) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Sessions[id]; !exists {
+		return repositories.ErrNotFound
+	}
+	delete(s.Sessions, id)
+	return nil
+}
+
+// DeleteCustomerSessions removes every session of a customer
+func (s *InMemoryAuthStore) DeleteCustomerSessions(customerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.Sessions {
+		if session.CustomerID == customerID {
+			delete(s.Sessions, id)
+		}
+	}
+	return nil
+}
+
+// CreatePasswordReset stores a reset token, replacing any pending one for the customer
+func (s *InMemoryAuthStore) CreatePasswordReset(reset models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, pending := range s.PasswordResets {
+		if pending.CustomerID == reset.CustomerID {
+			delete(s.PasswordResets, id)
+		}
+	}
+	s.PasswordResets[reset.ID] = reset
+	return nil
+}
+
+// ConsumePasswordReset removes a reset token and returns it if it was still valid
+func (s *InMemoryAuthStore) ConsumePasswordReset(id string) (models.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, exists := s.PasswordResets[id]
+	if !exists {
+		return models.PasswordReset{}, repositories.ErrNotFound
+	}
+	delete(s.PasswordResets, id)
+	if time.Now().After(reset.ExpiresAt) {
+		return models.PasswordReset{}, repositories.ErrNotFound
+	}
+	return reset, nil
+}
+
+// GetLoginAttempt returns the failed login counter of an email address
+func (s *InMemoryAuthStore) GetLoginAttempt(email string) models.LoginAttempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(strings.TrimSpace(email))
+	attempt, exists := s.LoginAttempts[key]
+	if !exists {
+		return models.LoginAttempt{Email: key}
+	}
+	return attempt
+}
+
+func (s *InMemoryAuthStore) SaveLoginAttempt(attempt models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt.Email = strings.ToLower(strings.TrimSpace(attempt.Email))
+	s.LoginAttempts[attempt.Email] = attempt
+	return nil
+}
+
+func (s *InMemoryAuthStore) ClearLoginAttempt(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.LoginAttempts, strings.ToLower(strings.TrimSpace(email)))
+	return nil
+}
+
+// PurgeLoginAttempts drops the counters whose failures started and whose
+// lockout ended before the given time, and returns how many it dropped
+func (s *InMemoryAuthStore) PurgeLoginAttempts(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for email, attempt := range s.LoginAttempts {
+		if attempt.FirstFailure.Before(before) && attempt.LockedUntil.Before(before) {
+			delete(s.LoginAttempts, email)
+			purged++
+		}
+	}
+	return purged
+}
+
+func (s *InMemoryAuthStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credentials := maps.Clone(s.Credentials)
+	sessions := maps.Clone(s.Sessions)
+	passwordResets := maps.Clone(s.PasswordResets)
+	loginAttempts := maps.Clone(s.LoginAttempts)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Credentials = credentials
+		s.Sessions = sessions
+		s.PasswordResets = passwordResets
+		s.LoginAttempts = loginAttempts
+	}
+}
@@ -3,10 +3,13 @@ package memory
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
+	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 )
 
 type InMemoryAuthorStore struct {
@@ -35,6 +38,8 @@ func (s *InMemoryAuthorStore) Create(Author models.Author) (models.Author, error
 	defer s.mu.Unlock()
 
 	Author.ID = s.nextID
+	Author.Version = 1
+	Author.DeletedAt = nil
 	s.Authors[s.nextID] = Author
 	s.nextID++
 	return Author, nil
@@ -46,7 +51,7 @@ func (s *InMemoryAuthorStore) Get(id int) (models.Author, error) {
 
 	Author, exists := s.Authors[id]
 	fmt.Println(s.Authors)
-	if !exists {
+	if !exists || Author.DeletedAt != nil {
 		return models.Author{}, errors.New("Author not found")
 	}
 	return Author, nil
@@ -56,36 +61,88 @@ func (s *InMemoryAuthorStore) Update(Author models.Author) (models.Author, error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Authors[Author.ID]
-	if !exists {
+	current, exists := s.Authors[Author.ID]
+	if !exists || current.DeletedAt != nil {
 		return models.Author{}, errors.New("Author not found")
 	}
+	if Author.Version != 0 && Author.Version != current.Version {
+		return models.Author{}, repositories.ErrVersionConflict
+	}
+	Author.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore
+	Author.DeletedAt = current.DeletedAt
 	s.Authors[Author.ID] = Author
 	return Author, nil
 }
 
-func (s *InMemoryAuthorStore) Delete(id int) error {
+// Delete marks an author as deleted; it is kept until purged. A non-zero
+// version must match the stored one.
+func (s *InMemoryAuthorStore) Delete(id int, version int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Authors[id]
-	if !exists {
+	Author, exists := s.Authors[id]
+	if !exists || Author.DeletedAt != nil {
 		return errors.New("Author not found")
 	}
-	delete(s.Authors, id)
+	if version != 0 && Author.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	Author.DeletedAt = &now
+	Author.Version++
+	s.Authors[id] = Author
 	return nil
 }
 
+// Restore brings back a deleted author; restoring an author that is not
+// deleted returns it unchanged
+func (s *InMemoryAuthorStore) Restore(id int) (models.Author, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	Author, exists := s.Authors[id]
+	if !exists {
+		return models.Author{}, errors.New("Author not found")
+	}
+	if Author.DeletedAt != nil {
+		Author.DeletedAt = nil
+		Author.Version++
+		s.Authors[id] = Author
+	}
+	return Author, nil
+}
+
+// Purge removes for good the authors deleted before the given time
+func (s *InMemoryAuthorStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, Author := range s.Authors {
+		if Author.DeletedAt != nil && Author.DeletedAt.Before(before) {
+			delete(s.Authors, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
 func (s *InMemoryAuthorStore) Search(query models.SearchCriteria) ([]models.Author, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	var results []models.Author
 	if len(query.Filters) == 0 {
 		for _, book := range s.Authors {
-			results = append(results, book)
+			if book.DeletedAt == nil || query.IncludeDeleted {
+				results = append(results, book)
+			}
 		}
 		return results, nil
 	}
 	for _, author := range s.Authors {
-		match := true
+		match := author.DeletedAt == nil || query.IncludeDeleted
 
 		if firstName, exists := query.Filters["firstName"]; exists {
 			if !strings.Contains(author.FirstName, firstName.(string)) {
@@ -113,3 +170,18 @@ func (s *InMemoryAuthorStore) Search(query models.SearchCriteria) ([]models.Auth
 
 	return results, nil
 }
+
+func (s *InMemoryAuthorStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authors := maps.Clone(s.Authors)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Authors = authors
+		s.nextID = nextID
+	}
+}
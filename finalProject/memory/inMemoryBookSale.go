@@ -2,6 +2,7 @@ package memory
 
 import (
 	"errors"
+	"maps"
 	"strings"
 	"sync"
 
@@ -78,6 +79,9 @@ func (s *InMemoryBookSaleStore) Delete(id int) error {
 }
 
 func (s *InMemoryBookSaleStore) Search(query models.SearchCriteria) ([]models.BookSale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	var results []models.BookSale
 	if len(query.Filters) == 0 {
 		for _, bookSale := range s.bookSales {
@@ -89,6 +93,10 @@ func (s *InMemoryBookSaleStore) Search(query models.SearchCriteria) ([]models.Bo
 	for _, bookSale := range s.bookSales {
 		match := true
 
+		if bookID, exists := query.Filters["book_id"]; exists && bookSale.Book.ID != bookID {
+			match = false
+		}
+
 		// Filter by title
 		if title, exists := query.Filters["title"]; exists {
 			if !strings.Contains(bookSale.Book.Title, title.(string)) {
@@ -132,3 +140,18 @@ func (s *InMemoryBookSaleStore) Search(query models.SearchCriteria) ([]models.Bo
 
 	return results, nil
 }
+
+func (s *InMemoryBookSaleStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookSales := maps.Clone(s.bookSales)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.bookSales = bookSales
+		s.nextID = nextID
+	}
+}
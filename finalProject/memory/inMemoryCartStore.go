@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryCartStore struct {
+	mu    sync.Mutex
+	Carts map[int]models.Cart
+}
+
+var (
+	cartStoreInstance *InMemoryCartStore
+	cartStoreOnce     sync.Once
+)
+
+// NewInMemoryCartStore returns the singleton instance of InMemoryCartStore
+func NewInMemoryCartStore() *InMemoryCartStore {
+	cartStoreOnce.Do(func() {
+		cartStoreInstance = &InMemoryCartStore{
+			Carts: make(map[int]models.Cart),
+		}
+	})
+	return cartStoreInstance
+}
+
+// Get retrieves the cart of a customer, dropping it if it has expired
+func (s *InMemoryCartStore) Get(customerID int) (models.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, exists := s.Carts[customerID]
+	if !exists {
+		return models.Cart{}, repositories.ErrNotFound
+	}
+	if time.Now().After(cart.ExpiresAt) {
+		delete(s.Carts, customerID)
+		return models.Cart{}, repositories.ErrNotFound
+	}
+	return cart, nil
+}
+
+// Save creates or replaces the cart of a customer. A non-zero version must
+// match the stored cart; a new cart is saved with version 0, which fails if
+// the customer has a cart already.
+func (s *InMemoryCartStore) Save(cart models.Cart) (models.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Carts[cart.CustomerID]
+	if exists && time.Now().After(current.ExpiresAt) {
+		exists = false
+	}
+	if cart.Version != 0 && (!exists || cart.Version != current.Version) {
+		return models.Cart{}, repositories.ErrVersionConflict
+	}
+	if cart.Version == 0 && exists {
+		return models.Cart{}, repositories.ErrVersionConflict
+	}
+	cart.Version = 1
+	if exists {
+		cart.Version = current.Version + 1
+	}
+	s.Carts[cart.CustomerID] = cart
+	return cart, nil
+}
+
+// Delete removes the cart of a customer; a non-zero version must match the
+// stored cart
+func (s *InMemoryCartStore) Delete(customerID int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Carts[customerID]
+	if !exists {
+		return repositories.ErrNotFound
+	}
+	if version != 0 && current.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	delete(s.Carts, customerID)
+	return nil
+}
+
+func (s *InMemoryCartStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	carts := maps.Clone(s.Carts)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Carts = carts
+	}
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+)
+
+// InMemoryCoPurchaseStore counts, for every pair of books, the orders
+// holding both. The counts derive from the orders, so they are not saved
+// but rebuilt when the orders are loaded.
+type InMemoryCoPurchaseStore struct {
+	mu    sync.Mutex
+	Pairs map[int]map[int]int
+}
+
+var (
+	coPurchaseStoreInstance *InMemoryCoPurchaseStore
+	coPurchaseStoreOnce     sync.Once
+)
+
+// NewInMemoryCoPurchaseStore returns the singleton instance of InMemoryCoPurchaseStore
+func NewInMemoryCoPurchaseStore() *InMemoryCoPurchaseStore {
+	coPurchaseStoreOnce.Do(func() {
+		coPurchaseStoreInstance = &InMemoryCoPurchaseStore{
+			Pairs: make(map[int]map[int]int),
+		}
+	})
+	return coPurchaseStoreInstance
+}
+
+// Record counts an order holding the given books; a book listed twice
+// counts once
+func (s *InMemoryCoPurchaseStore) Record(bookIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.add(bookIDs, 1)
+	return nil
+}
+
+// Remove uncounts an order holding the given books
+func (s *InMemoryCoPurchaseStore) Remove(bookIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.add(bookIDs, -1)
+	return nil
+}
+
+// Related returns the books bought along with a book and in how many orders
+func (s *InMemoryCoPurchaseStore) Related(bookID int) (map[int]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.Pairs[bookID]), nil
+}
+
+// rebuild counts the given orders from scratch, leaving out the deleted
+// ones and those whose copies went back to stock
+func (s *InMemoryCoPurchaseStore) rebuild(orders map[int]models.Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Pairs = make(map[int]map[int]int)
+	for _, order := range orders {
+		if order.DeletedAt != nil || slices.Contains(models.ReleasedOrderStatuses, order.Status) {
+			continue
+		}
+		bookIDs := make([]int, 0, len(order.Items))
+		for _, item := range order.Items {
+			bookIDs = append(bookIDs, item.Book.ID)
+		}
+		s.add(bookIDs, 1)
+	}
+}
+
+// add adds an order to the counts, or takes it out with a delta of -1;
+// callers hold the lock
+func (s *InMemoryCoPurchaseStore) add(bookIDs []int, delta int) {
+	seen := make(map[int]bool, len(bookIDs))
+	distinct := make([]int, 0, len(bookIDs))
+	for _, id := range bookIDs {
+		if !seen[id] {
+			seen[id] = true
+			distinct = append(distinct, id)
+		}
+	}
+	for _, a := range distinct {
+		for _, b := range distinct {
+			if a == b {
+				continue
+			}
+			if s.Pairs[a] == nil {
+				s.Pairs[a] = make(map[int]int)
+			}
+			s.Pairs[a][b] += delta
+			if s.Pairs[a][b] <= 0 {
+				delete(s.Pairs[a], b)
+			}
+		}
+		if len(s.Pairs[a]) == 0 {
+			delete(s.Pairs, a)
+		}
+	}
+}
+
+func (s *InMemoryCoPurchaseStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairs := make(map[int]map[int]int, len(s.Pairs))
+	for id, related := range s.Pairs {
+		pairs[id] = maps.Clone(related)
+	}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Pairs = pairs
+	}
+}
@@ -1,11 +1,17 @@
 package memory
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 )
 
 type InMemoryCustomerStore struct {
@@ -36,7 +42,13 @@ func (s *InMemoryCustomerStore) Create(Customer models.Customer) (models.Custome
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.emailTaken(Customer.Email, 0) {
+		return models.Customer{}, repositories.ErrEmailTaken
+	}
+
 	Customer.ID = s.nextID
+	Customer.Version = 1
+	Customer.DeletedAt = nil
 	s.Customers[s.nextID] = Customer
 	s.nextID++
 	return Customer, nil
@@ -49,46 +61,174 @@ func (s *InMemoryCustomerStore) Get(id int) (models.Customer, error) {
 
 	fmt.Println(s.Customers)
 	Customer, exists := s.Customers[id]
-	if !exists {
+	if !exists || Customer.DeletedAt != nil {
 		return models.Customer{}, errors.New("Customer not found")
 	}
 	return Customer, nil
 }
 
+// FindByEmail retrieves a customer by email, ignoring case and surrounding spaces
+func (s *InMemoryCustomerStore) FindByEmail(email string) (models.Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, Customer := range s.Customers {
+		if Customer.DeletedAt == nil && normalizeEmail(Customer.Email) == normalizeEmail(email) {
+			return Customer, nil
+		}
+	}
+	return models.Customer{}, errors.New("Customer not found")
+}
+
 // Update modifies an existing customer in the store
 func (s *InMemoryCustomerStore) Update(Customer models.Customer) (models.Customer, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Customers[Customer.ID]
-	if !exists {
+	current, exists := s.Customers[Customer.ID]
+	if !exists || current.DeletedAt != nil {
 		return models.Customer{}, errors.New("Customer not found")
 	}
+	if Customer.Version != 0 && Customer.Version != current.Version {
+		return models.Customer{}, repositories.ErrVersionConflict
+	}
+	Customer.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore, and the
+	// creation date is set once
+	Customer.DeletedAt, Customer.CreatedAt = current.DeletedAt, current.CreatedAt
+	if s.emailTaken(Customer.Email, Customer.ID) {
+		return models.Customer{}, repositories.ErrEmailTaken
+	}
 	s.Customers[Customer.ID] = Customer
 	return Customer, nil
 }
 
-// Delete removes a customer by ID
-func (s *InMemoryCustomerStore) Delete(id int) error {
+// Delete marks a customer as deleted; it is kept until purged, but its
+// email can be registered again meanwhile. A non-zero version must match
+// the stored one.
+func (s *InMemoryCustomerStore) Delete(id int, version int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Customers[id]
-	if !exists {
+	Customer, exists := s.Customers[id]
+	if !exists || Customer.DeletedAt != nil {
 		return errors.New("Customer not found")
 	}
-	delete(s.Customers, id)
+	if version != 0 && Customer.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	Customer.DeletedAt = &now
+	Customer.Version++
+	s.Customers[id] = Customer
 	return nil
 }
 
-// Search filters customers based on the search criteria
+// Restore brings back a deleted customer, unless its email was taken since.
+// Restoring a customer that is not deleted returns it unchanged.
+func (s *InMemoryCustomerStore) Restore(id int) (models.Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	Customer, exists := s.Customers[id]
+	if !exists {
+		return models.Customer{}, errors.New("Customer not found")
+	}
+	if Customer.DeletedAt != nil {
+		if s.emailTaken(Customer.Email, id) {
+			return models.Customer{}, repositories.ErrEmailTaken
+		}
+		Customer.DeletedAt = nil
+		Customer.Version++
+		s.Customers[id] = Customer
+	}
+	return Customer, nil
+}
+
+// Purge removes for good the customers deleted before the given time
+func (s *InMemoryCustomerStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, Customer := range s.Customers {
+		if Customer.DeletedAt != nil && Customer.DeletedAt.Before(before) {
+			delete(s.Customers, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Search filters customers by name and email, which match when they contain
+// the filter, by city and country, matched whole, all ignoring case, and by
+// creation time in [from, to). Customers are returned by ID.
 func (s *InMemoryCustomerStore) Search(query models.SearchCriteria) ([]models.Customer, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var results []models.Customer
+	contains := func(value, filter string) bool {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(strings.TrimSpace(filter)))
+	}
+	results := []models.Customer{}
 	for _, Customer := range s.Customers {
+		if Customer.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if name, exists := query.Filters["name"].(string); exists && !contains(Customer.Name, name) {
+			continue
+		}
+		if email, exists := query.Filters["email"].(string); exists && !contains(Customer.Email, email) {
+			continue
+		}
+		if city, exists := query.Filters["city"].(string); exists && !strings.EqualFold(strings.TrimSpace(Customer.Address.City), strings.TrimSpace(city)) {
+			continue
+		}
+		if country, exists := query.Filters["country"].(string); exists && !strings.EqualFold(strings.TrimSpace(Customer.Address.Country), strings.TrimSpace(country)) {
+			continue
+		}
+		if from, exists := query.Filters["from"].(time.Time); exists && Customer.CreatedAt.Before(from) {
+			continue
+		}
+		if to, exists := query.Filters["to"].(time.Time); exists && !Customer.CreatedAt.Before(to) {
+			continue
+		}
 		results = append(results, Customer)
 	}
+	slices.SortFunc(results, func(a, b models.Customer) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
 	return results, nil
 }
+
+// emailTaken reports whether another customer already uses the email; callers hold the lock
+func (s *InMemoryCustomerStore) emailTaken(email string, exceptID int) bool {
+	if normalizeEmail(email) == "" {
+		return false
+	}
+	for id, Customer := range s.Customers {
+		if id != exceptID && Customer.DeletedAt == nil && normalizeEmail(Customer.Email) == normalizeEmail(email) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *InMemoryCustomerStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customers := maps.Clone(s.Customers)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Customers = customers
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryNotificationStore struct {
+	mu            sync.Mutex
+	Notifications map[int]models.Notification
+	nextID        int
+}
+
+var (
+	notificationStoreInstance *InMemoryNotificationStore
+	notificationStoreOnce     sync.Once
+)
+
+// NewInMemoryNotificationStore returns the singleton instance of InMemoryNotificationStore
+func NewInMemoryNotificationStore() *InMemoryNotificationStore {
+	notificationStoreOnce.Do(func() {
+		notificationStoreInstance = &InMemoryNotificationStore{
+			Notifications: make(map[int]models.Notification),
+			nextID:        1,
+		}
+	})
+	return notificationStoreInstance
+}
+
+func (s *InMemoryNotificationStore) Create(notification models.Notification) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification.ID = s.nextID
+	notification.Version = 1
+	s.Notifications[s.nextID] = notification
+	s.nextID++
+	return notification, nil
+}
+
+// Update replaces a notification, conditional on its version unless zero
+func (s *InMemoryNotificationStore) Update(notification models.Notification) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Notifications[notification.ID]
+	if !exists {
+		return models.Notification{}, repositories.ErrNotFound
+	}
+	if notification.Version != 0 && notification.Version != current.Version {
+		return models.Notification{}, repositories.ErrVersionConflict
+	}
+	notification.Version = current.Version + 1
+	s.Notifications[notification.ID] = notification
+	return notification, nil
+}
+
+// Search filters notifications by status and customer_id, oldest first
+func (s *InMemoryNotificationStore) Search(query models.SearchCriteria) ([]models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Notification{}
+	for _, notification := range s.Notifications {
+		if status, exists := query.Filters["status"]; exists && notification.Status != status {
+			continue
+		}
+		if customerID, exists := query.Filters["customer_id"]; exists && notification.CustomerID != customerID {
+			continue
+		}
+		results = append(results, notification)
+	}
+	slices.SortFunc(results, func(a, b models.Notification) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryNotificationStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := maps.Clone(s.Notifications)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Notifications = notifications
+		s.nextID = nextID
+	}
+}
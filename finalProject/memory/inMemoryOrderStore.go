@@ -1,10 +1,15 @@
 package memory
 
 import (
+	"cmp"
 	"errors"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 
 	"bookstore.com/models"
+	"bookstore.com/repositories"
 )
 
 type InMemoryOrderStore struct {
@@ -36,6 +41,8 @@ func (s *InMemoryOrderStore) Create(Order models.Order) (models.Order, error) {
 	defer s.mu.Unlock()
 
 	Order.ID = s.nextID
+	Order.Version = 1
+	Order.DeletedAt = nil
 	s.Orders[s.nextID] = Order
 
 	s.nextID++
@@ -50,7 +57,7 @@ func (s *InMemoryOrderStore) Get(id int) (models.Order, error) {
 	defer s.mu.Unlock()
 
 	Order, exists := s.Orders[id]
-	if !exists {
+	if !exists || Order.DeletedAt != nil {
 		return models.Order{}, errors.New("Order not found")
 	}
 	return Order, nil
@@ -61,35 +68,148 @@ func (s *InMemoryOrderStore) Update(Order models.Order) (models.Order, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Orders[Order.ID]
-	if !exists {
+	current, exists := s.Orders[Order.ID]
+	if !exists || current.DeletedAt != nil {
 		return models.Order{}, errors.New("Order not found")
 	}
+	if Order.Version != 0 && Order.Version != current.Version {
+		return models.Order{}, repositories.ErrVersionConflict
+	}
+	Order.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore, and the
+	// creation date is set once
+	Order.DeletedAt, Order.CreatedAt = current.DeletedAt, current.CreatedAt
 	s.Orders[Order.ID] = Order
 	return Order, nil
 }
 
-// Delete removes an order by ID
-func (s *InMemoryOrderStore) Delete(id int) error {
+// Delete marks an order as deleted; it is kept until purged. A non-zero
+// version must match the stored one.
+func (s *InMemoryOrderStore) Delete(id int, version int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.Orders[id]
-	if !exists {
+	Order, exists := s.Orders[id]
+	if !exists || Order.DeletedAt != nil {
 		return errors.New("Order not found")
 	}
-	delete(s.Orders, id)
+	if version != 0 && Order.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	Order.DeletedAt = &now
+	Order.Version++
+	s.Orders[id] = Order
 	return nil
 }
 
-// Search filters orders based on the search criteria
+// Restore brings back a deleted order; restoring an order that is not
+// deleted returns it unchanged
+func (s *InMemoryOrderStore) Restore(id int) (models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	Order, exists := s.Orders[id]
+	if !exists {
+		return models.Order{}, errors.New("Order not found")
+	}
+	if Order.DeletedAt != nil {
+		Order.DeletedAt = nil
+		Order.Version++
+		s.Orders[id] = Order
+	}
+	return Order, nil
+}
+
+// Reassign gives every order of a customer, deleted ones included, to
+// another customer and returns them as they were, ordered by ID
+func (s *InMemoryOrderStore) Reassign(fromCustomerID int, to models.Customer) ([]models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	moved := []models.Order{}
+	for id, Order := range s.Orders {
+		if Order.Customer.ID != fromCustomerID {
+			continue
+		}
+		moved = append(moved, Order)
+		Order.Customer = to
+		Order.Version++
+		s.Orders[id] = Order
+	}
+	slices.SortFunc(moved, func(a, b models.Order) int { return a.ID - b.ID })
+	return moved, nil
+}
+
+// Purge removes for good the orders deleted before the given time
+func (s *InMemoryOrderStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, Order := range s.Orders {
+		if Order.DeletedAt != nil && Order.DeletedAt.Before(before) {
+			delete(s.Orders, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Search filters orders by customer_id, status, creation time (from
+// inclusive, to exclusive), total price (min_total and max_total inclusive)
+// and book_id (the orders with an item of the book), ordered by ID
 func (s *InMemoryOrderStore) Search(query models.SearchCriteria) ([]models.Order, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var results []models.Order
+	results := []models.Order{}
 	for _, Order := range s.Orders {
+		if Order.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if customerID, exists := query.Filters["customer_id"]; exists && Order.Customer.ID != customerID {
+			continue
+		}
+		if status, exists := query.Filters["status"]; exists && Order.Status != status {
+			continue
+		}
+		if from, exists := query.Filters["from"].(time.Time); exists && Order.CreatedAt.Before(from) {
+			continue
+		}
+		if to, exists := query.Filters["to"].(time.Time); exists && !Order.CreatedAt.Before(to) {
+			continue
+		}
+		if minTotal, exists := query.Filters["min_total"].(float64); exists && Order.TotalPrice < minTotal {
+			continue
+		}
+		if maxTotal, exists := query.Filters["max_total"].(float64); exists && Order.TotalPrice > maxTotal {
+			continue
+		}
+		if bookID, exists := query.Filters["book_id"]; exists && !slices.ContainsFunc(Order.Items, func(item models.OrderItem) bool {
+			return item.Book.ID == bookID
+		}) {
+			continue
+		}
 		results = append(results, Order)
 	}
+	slices.SortFunc(results, func(a, b models.Order) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
 	return results, nil
 }
+
+func (s *InMemoryOrderStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := maps.Clone(s.Orders)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Orders = orders
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryPaymentStore struct {
+	mu       sync.Mutex
+	Payments map[int]models.Payment
+	nextID   int
+}
+
+var (
+	paymentStoreInstance *InMemoryPaymentStore
+	paymentStoreOnce     sync.Once
+)
+
+// NewInMemoryPaymentStore returns the singleton instance of InMemoryPaymentStore
+func NewInMemoryPaymentStore() *InMemoryPaymentStore {
+	paymentStoreOnce.Do(func() {
+		paymentStoreInstance = &InMemoryPaymentStore{
+			Payments: make(map[int]models.Payment),
+			nextID:   1,
+		}
+	})
+	return paymentStoreInstance
+}
+
+// Create adds a new payment to the store
+func (s *InMemoryPaymentStore) Create(payment models.Payment) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment.ID = s.nextID
+	payment.Version = 1
+	s.Payments[s.nextID] = payment
+	s.nextID++
+	return payment, nil
+}
+
+// Get retrieves a payment by ID
+func (s *InMemoryPaymentStore) Get(id int) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, exists := s.Payments[id]
+	if !exists {
+		return models.Payment{}, repositories.ErrNotFound
+	}
+	return payment, nil
+}
+
+// Update modifies an existing payment in the store
+func (s *InMemoryPaymentStore) Update(payment models.Payment) (models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Payments[payment.ID]
+	if !exists {
+		return models.Payment{}, repositories.ErrNotFound
+	}
+	if payment.Version != 0 && payment.Version != current.Version {
+		return models.Payment{}, repositories.ErrVersionConflict
+	}
+	payment.Version = current.Version + 1
+	s.Payments[payment.ID] = payment
+	return payment, nil
+}
+
+// FindByOrder returns the payments of an order, oldest first
+func (s *InMemoryPaymentStore) FindByOrder(orderID int) ([]models.Payment, error) {
+	return s.Search(models.SearchCriteria{Filters: map[string]interface{}{"order_id": orderID}})
+}
+
+// Search filters payments by order_id and status, ordered by ID
+func (s *InMemoryPaymentStore) Search(query models.SearchCriteria) ([]models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Payment{}
+	for _, payment := range s.Payments {
+		if orderID, exists := query.Filters["order_id"]; exists && payment.OrderID != orderID {
+			continue
+		}
+		if status, exists := query.Filters["status"]; exists && payment.Status != status {
+			continue
+		}
+		results = append(results, payment)
+	}
+	slices.SortFunc(results, func(a, b models.Payment) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryPaymentStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payments := maps.Clone(s.Payments)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Payments = payments
+		s.nextID = nextID
+	}
+}
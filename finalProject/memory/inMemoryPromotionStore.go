@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryPromotionStore struct {
+	mu               sync.Mutex
+	Promotions       map[int]models.Promotion
+	Redemptions      map[int]models.PromotionRedemption
+	nextID           int
+	nextRedemptionID int
+}
+
+var (
+	promotionStoreInstance *InMemoryPromotionStore
+	promotionStoreOnce     sync.Once
+)
+
+// NewInMemoryPromotionStore returns the singleton instance of InMemoryPromotionStore
+func NewInMemoryPromotionStore() *InMemoryPromotionStore {
+	promotionStoreOnce.Do(func() {
+		promotionStoreInstance = &InMemoryPromotionStore{
+			Promotions:       make(map[int]models.Promotion),
+			Redemptions:      make(map[int]models.PromotionRedemption),
+			nextID:           1,
+			nextRedemptionID: 1,
+		}
+	})
+	return promotionStoreInstance
+}
+
+// Create adds a new promotion to the store; codes are unique regardless of case
+func (s *InMemoryPromotionStore) Create(promotion models.Promotion) (models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.codeTaken(promotion.Code, 0) {
+		return models.Promotion{}, repositories.ErrCodeTaken
+	}
+
+	promotion.ID = s.nextID
+	promotion.Version = 1
+	promotion.DeletedAt = nil
+	s.Promotions[s.nextID] = promotion
+	s.nextID++
+	return promotion, nil
+}
+
+// Get retrieves a promotion by ID
+func (s *InMemoryPromotionStore) Get(id int) (models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promotion, exists := s.Promotions[id]
+	if !exists || promotion.DeletedAt != nil {
+		return models.Promotion{}, repositories.ErrNotFound
+	}
+	return promotion, nil
+}
+
+// FindByCode retrieves a promotion by its code, ignoring case
+func (s *InMemoryPromotionStore) FindByCode(code string) (models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, promotion := range s.Promotions {
+		if promotion.DeletedAt == nil && promotion.Code != "" && strings.EqualFold(promotion.Code, strings.TrimSpace(code)) {
+			return promotion, nil
+		}
+	}
+	return models.Promotion{}, repositories.ErrNotFound
+}
+
+// Update modifies an existing promotion in the store
+func (s *InMemoryPromotionStore) Update(promotion models.Promotion) (models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Promotions[promotion.ID]
+	if !exists || current.DeletedAt != nil {
+		return models.Promotion{}, repositories.ErrNotFound
+	}
+	if promotion.Version != 0 && promotion.Version != current.Version {
+		return models.Promotion{}, repositories.ErrVersionConflict
+	}
+	if s.codeTaken(promotion.Code, promotion.ID) {
+		return models.Promotion{}, repositories.ErrCodeTaken
+	}
+	promotion.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore
+	promotion.DeletedAt = current.DeletedAt
+	s.Promotions[promotion.ID] = promotion
+	return promotion, nil
+}
+
+// Delete marks a promotion as deleted; it is kept until purged, but its
+// code can be used again meanwhile. A non-zero version must match the
+// stored one.
+func (s *InMemoryPromotionStore) Delete(id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promotion, exists := s.Promotions[id]
+	if !exists || promotion.DeletedAt != nil {
+		return repositories.ErrNotFound
+	}
+	if version != 0 && promotion.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	promotion.DeletedAt = &now
+	promotion.Version++
+	s.Promotions[id] = promotion
+	return nil
+}
+
+// Restore brings back a deleted promotion, unless its code was taken since.
+// Restoring a promotion that is not deleted returns it unchanged.
+func (s *InMemoryPromotionStore) Restore(id int) (models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promotion, exists := s.Promotions[id]
+	if !exists {
+		return models.Promotion{}, repositories.ErrNotFound
+	}
+	if promotion.DeletedAt != nil {
+		if s.codeTaken(promotion.Code, id) {
+			return models.Promotion{}, repositories.ErrCodeTaken
+		}
+		promotion.DeletedAt = nil
+		promotion.Version++
+		s.Promotions[id] = promotion
+	}
+	return promotion, nil
+}
+
+// Purge removes for good the promotions deleted before the given time; their
+// redemptions are kept
+func (s *InMemoryPromotionStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, promotion := range s.Promotions {
+		if promotion.DeletedAt != nil && promotion.DeletedAt.Before(before) {
+			delete(s.Promotions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Search filters promotions by type and code, ordered by ID
+func (s *InMemoryPromotionStore) Search(query models.SearchCriteria) ([]models.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Promotion{}
+	for _, promotion := range s.Promotions {
+		if promotion.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if promotionType, exists := query.Filters["type"]; exists && promotion.Type != promotionType {
+			continue
+		}
+		if code, exists := query.Filters["code"].(string); exists && !strings.EqualFold(promotion.Code, code) {
+			continue
+		}
+		results = append(results, promotion)
+	}
+	slices.SortFunc(results, func(a, b models.Promotion) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+// Redeem records the redemptions if every promotion is still within its
+// global and per customer limits; none is recorded otherwise
+func (s *InMemoryPromotionStore) Redeem(redemptions []models.PromotionRedemption) ([]models.PromotionRedemption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, redemption := range redemptions {
+		promotion, exists := s.Promotions[redemption.PromotionID]
+		if !exists || promotion.DeletedAt != nil {
+			return nil, repositories.ErrNotFound
+		}
+		total, byCustomer := s.countRedemptions(redemption.PromotionID, redemption.CustomerID)
+		if promotion.UsageLimit > 0 && total >= promotion.UsageLimit {
+			return nil, repositories.ErrUsageLimitReached
+		}
+		if promotion.PerCustomerLimit > 0 && byCustomer >= promotion.PerCustomerLimit {
+			return nil, repositories.ErrUsageLimitReached
+		}
+	}
+
+	saved := make([]models.PromotionRedemption, 0, len(redemptions))
+	for _, redemption := range redemptions {
+		redemption.ID = s.nextRedemptionID
+		s.Redemptions[redemption.ID] = redemption
+		s.nextRedemptionID++
+		saved = append(saved, redemption)
+	}
+	return saved, nil
+}
+
+// CountRedemptions returns how often a promotion was used in total and by a customer
+func (s *InMemoryPromotionStore) CountRedemptions(promotionID int, customerID int) (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.countRedemptions(promotionID, customerID)
+}
+
+// AttachOrder links redemptions to the order they were made for
+func (s *InMemoryPromotionStore) AttachOrder(redemptionIDs []int, orderID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range redemptionIDs {
+		redemption, exists := s.Redemptions[id]
+		if !exists {
+			return repositories.ErrNotFound
+		}
+		redemption.OrderID = orderID
+		s.Redemptions[id] = redemption
+	}
+	return nil
+}
+
+// OrderRedemptions returns the IDs of the redemptions made for an order
+func (s *InMemoryPromotionStore) OrderRedemptions(orderID int) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []int
+	for id, redemption := range s.Redemptions {
+		if redemption.OrderID == orderID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CancelRedemptions removes redemptions of an order that was not placed or
+// whose payment fell through
+func (s *InMemoryPromotionStore) CancelRedemptions(redemptionIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range redemptionIDs {
+		delete(s.Redemptions, id)
+	}
+	return nil
+}
+
+func (s *InMemoryPromotionStore) countRedemptions(promotionID int, customerID int) (total int, byCustomer int) {
+	for _, redemption := range s.Redemptions {
+		if redemption.PromotionID != promotionID {
+			continue
+		}
+		total++
+		if redemption.CustomerID == customerID {
+			byCustomer++
+		}
+	}
+	return total, byCustomer
+}
+
+// codeTaken reports whether another promotion uses the code; the caller holds the lock
+func (s *InMemoryPromotionStore) codeTaken(code string, exceptID int) bool {
+	if code == "" {
+		return false
+	}
+	for id, promotion := range s.Promotions {
+		if id != exceptID && promotion.DeletedAt == nil && strings.EqualFold(promotion.Code, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *InMemoryPromotionStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promotions := maps.Clone(s.Promotions)
+	redemptions := maps.Clone(s.Redemptions)
+	nextID, nextRedemptionID := s.nextID, s.nextRedemptionID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Promotions = promotions
+		s.Redemptions = redemptions
+		s.nextID, s.nextRedemptionID = nextID, nextRedemptionID
+	}
+}
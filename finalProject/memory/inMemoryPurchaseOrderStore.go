@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryPurchaseOrderStore struct {
+	mu             sync.Mutex
+	PurchaseOrders map[int]models.PurchaseOrder
+	nextID         int
+}
+
+var (
+	purchaseOrderStoreInstance *InMemoryPurchaseOrderStore
+	purchaseOrderStoreOnce     sync.Once
+)
+
+// NewInMemoryPurchaseOrderStore returns the singleton instance of InMemoryPurchaseOrderStore
+func NewInMemoryPurchaseOrderStore() *InMemoryPurchaseOrderStore {
+	purchaseOrderStoreOnce.Do(func() {
+		purchaseOrderStoreInstance = &InMemoryPurchaseOrderStore{
+			PurchaseOrders: make(map[int]models.PurchaseOrder),
+			nextID:         1,
+		}
+	})
+	return purchaseOrderStoreInstance
+}
+
+func (s *InMemoryPurchaseOrderStore) Create(order models.PurchaseOrder) (models.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order.ID = s.nextID
+	order.Version = 1
+	s.PurchaseOrders[s.nextID] = order
+	s.nextID++
+	return order, nil
+}
+
+func (s *InMemoryPurchaseOrderStore) Get(id int) (models.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.PurchaseOrders[id]
+	if !exists {
+		return models.PurchaseOrder{}, repositories.ErrNotFound
+	}
+	return order, nil
+}
+
+// Update replaces a purchase order, conditional on its version unless zero
+func (s *InMemoryPurchaseOrderStore) Update(order models.PurchaseOrder) (models.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.PurchaseOrders[order.ID]
+	if !exists {
+		return models.PurchaseOrder{}, repositories.ErrNotFound
+	}
+	if order.Version != 0 && order.Version != current.Version {
+		return models.PurchaseOrder{}, repositories.ErrVersionConflict
+	}
+	order.Version = current.Version + 1
+	s.PurchaseOrders[order.ID] = order
+	return order, nil
+}
+
+// Search filters purchase orders by supplier_id, status and book_id (the
+// orders with a line for the book), ordered by ID
+func (s *InMemoryPurchaseOrderStore) Search(query models.SearchCriteria) ([]models.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.PurchaseOrder{}
+	for _, order := range s.PurchaseOrders {
+		if supplierID, exists := query.Filters["supplier_id"]; exists && order.SupplierID != supplierID {
+			continue
+		}
+		if status, exists := query.Filters["status"]; exists && order.Status != status {
+			continue
+		}
+		if bookID, exists := query.Filters["book_id"]; exists && !slices.ContainsFunc(order.Lines, func(line models.PurchaseOrderLine) bool {
+			return line.BookID == bookID
+		}) {
+			continue
+		}
+		results = append(results, order)
+	}
+	slices.SortFunc(results, func(a, b models.PurchaseOrder) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryPurchaseOrderStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := maps.Clone(s.PurchaseOrders)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.PurchaseOrders = orders
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryReturnStore struct {
+	mu      sync.Mutex
+	Returns map[int]models.Return
+	nextID  int
+}
+
+var (
+	returnStoreInstance *InMemoryReturnStore
+	returnStoreOnce     sync.Once
+)
+
+// NewInMemoryReturnStore returns the singleton instance of InMemoryReturnStore
+func NewInMemoryReturnStore() *InMemoryReturnStore {
+	returnStoreOnce.Do(func() {
+		returnStoreInstance = &InMemoryReturnStore{
+			Returns: make(map[int]models.Return),
+			nextID:  1,
+		}
+	})
+	return returnStoreInstance
+}
+
+// Create adds a new return to the store
+func (s *InMemoryReturnStore) Create(ret models.Return) (models.Return, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret.ID = s.nextID
+	ret.Version = 1
+	s.Returns[s.nextID] = ret
+	s.nextID++
+	return ret, nil
+}
+
+// Get retrieves a return by ID
+func (s *InMemoryReturnStore) Get(id int) (models.Return, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret, exists := s.Returns[id]
+	if !exists {
+		return models.Return{}, repositories.ErrNotFound
+	}
+	return ret, nil
+}
+
+// Update modifies an existing return in the store
+func (s *InMemoryReturnStore) Update(ret models.Return) (models.Return, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Returns[ret.ID]
+	if !exists {
+		return models.Return{}, repositories.ErrNotFound
+	}
+	if ret.Version != 0 && ret.Version != current.Version {
+		return models.Return{}, repositories.ErrVersionConflict
+	}
+	ret.Version = current.Version + 1
+	s.Returns[ret.ID] = ret
+	return ret, nil
+}
+
+// FindByOrder returns the returns of an order, oldest first
+func (s *InMemoryReturnStore) FindByOrder(orderID int) ([]models.Return, error) {
+	return s.Search(models.SearchCriteria{Filters: map[string]interface{}{"order_id": orderID}})
+}
+
+// Search filters returns by order_id and status, ordered by ID
+func (s *InMemoryReturnStore) Search(query models.SearchCriteria) ([]models.Return, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Return{}
+	for _, ret := range s.Returns {
+		if orderID, exists := query.Filters["order_id"]; exists && ret.OrderID != orderID {
+			continue
+		}
+		if status, exists := query.Filters["status"]; exists && ret.Status != status {
+			continue
+		}
+		results = append(results, ret)
+	}
+	slices.SortFunc(results, func(a, b models.Return) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryReturnStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	returns := maps.Clone(s.Returns)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Returns = returns
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryReviewStore struct {
+	mu      sync.Mutex
+	Reviews map[int]models.Review
+	nextID  int
+}
+
+var (
+	reviewStoreInstance *InMemoryReviewStore
+	reviewStoreOnce     sync.Once
+)
+
+// NewInMemoryReviewStore returns the singleton instance of InMemoryReviewStore
+func NewInMemoryReviewStore() *InMemoryReviewStore {
+	reviewStoreOnce.Do(func() {
+		reviewStoreInstance = &InMemoryReviewStore{
+			Reviews: make(map[int]models.Review),
+			nextID:  1,
+		}
+	})
+	return reviewStoreInstance
+}
+
+// Create adds a review unless the customer already reviewed the book
+func (s *InMemoryReviewStore) Create(review models.Review) (models.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.Reviews {
+		if existing.BookID == review.BookID && existing.CustomerID == review.CustomerID {
+			return models.Review{}, repositories.ErrAlreadyReviewed
+		}
+	}
+	review.ID = s.nextID
+	review.Version = 1
+	s.Reviews[s.nextID] = review
+	s.nextID++
+	return review, nil
+}
+
+func (s *InMemoryReviewStore) Get(id int) (models.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	review, exists := s.Reviews[id]
+	if !exists {
+		return models.Review{}, repositories.ErrNotFound
+	}
+	return review, nil
+}
+
+// Update replaces a review, conditional on its version unless zero; the book
+// and the customer of a review cannot change
+func (s *InMemoryReviewStore) Update(review models.Review) (models.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Reviews[review.ID]
+	if !exists {
+		return models.Review{}, repositories.ErrNotFound
+	}
+	if review.Version != 0 && review.Version != current.Version {
+		return models.Review{}, repositories.ErrVersionConflict
+	}
+	review.BookID, review.CustomerID = current.BookID, current.CustomerID
+	review.Version = current.Version + 1
+	s.Reviews[review.ID] = review
+	return review, nil
+}
+
+func (s *InMemoryReviewStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Reviews[id]; !exists {
+		return repositories.ErrNotFound
+	}
+	delete(s.Reviews, id)
+	return nil
+}
+
+// Reassign gives the reviews of a customer to another one, except for the
+// books the other customer reviewed already, and returns them as they were,
+// ordered by ID
+func (s *InMemoryReviewStore) Reassign(fromCustomerID, toCustomerID int) ([]models.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reviewed := make(map[int]bool)
+	for _, review := range s.Reviews {
+		if review.CustomerID == toCustomerID {
+			reviewed[review.BookID] = true
+		}
+	}
+	moved := []models.Review{}
+	for id, review := range s.Reviews {
+		if review.CustomerID != fromCustomerID || reviewed[review.BookID] {
+			continue
+		}
+		moved = append(moved, review)
+		review.CustomerID = toCustomerID
+		review.Version++
+		s.Reviews[id] = review
+	}
+	slices.SortFunc(moved, func(a, b models.Review) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return moved, nil
+}
+
+// Search filters reviews by book_id and customer_id, ordered by ID
+func (s *InMemoryReviewStore) Search(query models.SearchCriteria) ([]models.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Review{}
+	for _, review := range s.Reviews {
+		if bookID, exists := query.Filters["book_id"]; exists && review.BookID != bookID {
+			continue
+		}
+		if customerID, exists := query.Filters["customer_id"]; exists && review.CustomerID != customerID {
+			continue
+		}
+		results = append(results, review)
+	}
+	slices.SortFunc(results, func(a, b models.Review) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryReviewStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reviews := maps.Clone(s.Reviews)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Reviews = reviews
+		s.nextID = nextID
+	}
+}
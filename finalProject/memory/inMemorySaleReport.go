@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -65,3 +66,18 @@ func (s *InMemorySalesReportStore) Search(query models.SearchCriteria) ([]models
 
 	return results, nil
 }
+
+func (s *InMemorySalesReportStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	salesReports := slices.Clone(s.SalesReports)
+	ordersList := slices.Clone(s.ordersList)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.SalesReports = salesReports
+		s.ordersList = ordersList
+	}
+}
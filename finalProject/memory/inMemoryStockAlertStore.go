@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"maps"
+	"sort"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryStockAlertStore struct {
+	mu     sync.Mutex
+	Alerts map[int]models.StockAlert
+}
+
+var (
+	stockAlertStoreInstance *InMemoryStockAlertStore
+	stockAlertStoreOnce     sync.Once
+)
+
+// NewInMemoryStockAlertStore returns the singleton instance of InMemoryStockAlertStore
+func NewInMemoryStockAlertStore() *InMemoryStockAlertStore {
+	stockAlertStoreOnce.Do(func() {
+		stockAlertStoreInstance = &InMemoryStockAlertStore{
+			Alerts: make(map[int]models.StockAlert),
+		}
+	})
+	return stockAlertStoreInstance
+}
+
+func (s *InMemoryStockAlertStore) Get(bookID int) (models.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, exists := s.Alerts[bookID]
+	if !exists {
+		return models.StockAlert{}, repositories.ErrNotFound
+	}
+	return alert, nil
+}
+
+// Save creates or replaces the alert of a book
+func (s *InMemoryStockAlertStore) Save(alert models.StockAlert) (models.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Alerts[alert.BookID] = alert
+	return alert, nil
+}
+
+// Delete clears the alert of a book, if any
+func (s *InMemoryStockAlertStore) Delete(bookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Alerts, bookID)
+	return nil
+}
+
+// Search returns every alert, ordered by book ID
+func (s *InMemoryStockAlertStore) Search(query models.SearchCriteria) ([]models.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]models.StockAlert, 0, len(s.Alerts))
+	for _, alert := range s.Alerts {
+		results = append(results, alert)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].BookID < results[j].BookID })
+	return results, nil
+}
+
+func (s *InMemoryStockAlertStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := maps.Clone(s.Alerts)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Alerts = alerts
+	}
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryStockMovementStore struct {
+	mu        sync.Mutex
+	Movements []models.StockMovement
+}
+
+var (
+	stockMovementStoreInstance *InMemoryStockMovementStore
+	stockMovementStoreOnce     sync.Once
+)
+
+// NewInMemoryStockMovementStore returns the singleton instance of InMemoryStockMovementStore
+func NewInMemoryStockMovementStore() *InMemoryStockMovementStore {
+	stockMovementStoreOnce.Do(func() {
+		stockMovementStoreInstance = &InMemoryStockMovementStore{}
+	})
+	return stockMovementStoreInstance
+}
+
+// Append adds a movement at the end of the ledger, numbered after the last one
+func (s *InMemoryStockMovementStore) Append(movement models.StockMovement) (models.StockMovement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movement.ID = 1
+	if len(s.Movements) > 0 {
+		movement.ID = s.Movements[len(s.Movements)-1].ID + 1
+	}
+	s.Movements = append(s.Movements, movement)
+	return movement, nil
+}
+
+// Search filters movements by book_id, order_id and type, oldest first
+func (s *InMemoryStockMovementStore) Search(query models.SearchCriteria) ([]models.StockMovement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.StockMovement{}
+	for _, movement := range s.Movements {
+		if bookID, exists := query.Filters["book_id"]; exists && movement.BookID != bookID {
+			continue
+		}
+		if orderID, exists := query.Filters["order_id"]; exists && movement.OrderID != orderID {
+			continue
+		}
+		if movementType, exists := query.Filters["type"]; exists && movement.Type != movementType {
+			continue
+		}
+		results = append(results, movement)
+	}
+	return results, nil
+}
+
+// AttachOrder links sales to the order they were made for
+func (s *InMemoryStockMovementStore) AttachOrder(movementIDs []int, orderID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range movementIDs {
+		i := slices.IndexFunc(s.Movements, func(movement models.StockMovement) bool { return movement.ID == id })
+		if i < 0 {
+			return repositories.ErrNotFound
+		}
+		s.Movements[i].OrderID = orderID
+	}
+	return nil
+}
+
+func (s *InMemoryStockMovementStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movements := slices.Clone(s.Movements)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Movements = movements
+	}
+}
@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemorySupplierStore struct {
+	mu        sync.Mutex
+	Suppliers map[int]models.Supplier
+	nextID    int
+}
+
+var (
+	supplierStoreInstance *InMemorySupplierStore
+	supplierStoreOnce     sync.Once
+)
+
+// NewInMemorySupplierStore returns the singleton instance of InMemorySupplierStore
+func NewInMemorySupplierStore() *InMemorySupplierStore {
+	supplierStoreOnce.Do(func() {
+		supplierStoreInstance = &InMemorySupplierStore{
+			Suppliers: make(map[int]models.Supplier),
+			nextID:    1,
+		}
+	})
+	return supplierStoreInstance
+}
+
+func (s *InMemorySupplierStore) Create(supplier models.Supplier) (models.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	supplier.ID = s.nextID
+	supplier.Version = 1
+	supplier.DeletedAt = nil
+	s.Suppliers[s.nextID] = supplier
+	s.nextID++
+	return supplier, nil
+}
+
+func (s *InMemorySupplierStore) Get(id int) (models.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	supplier, exists := s.Suppliers[id]
+	if !exists || supplier.DeletedAt != nil {
+		return models.Supplier{}, repositories.ErrNotFound
+	}
+	return supplier, nil
+}
+
+func (s *InMemorySupplierStore) Update(supplier models.Supplier) (models.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Suppliers[supplier.ID]
+	if !exists || current.DeletedAt != nil {
+		return models.Supplier{}, repositories.ErrNotFound
+	}
+	if supplier.Version != 0 && supplier.Version != current.Version {
+		return models.Supplier{}, repositories.ErrVersionConflict
+	}
+	supplier.Version = current.Version + 1
+	// Deleting and restoring go through Delete and Restore
+	supplier.DeletedAt = current.DeletedAt
+	s.Suppliers[supplier.ID] = supplier
+	return supplier, nil
+}
+
+// Delete marks a supplier as deleted; it is kept until purged. A non-zero
+// version must match the stored one.
+func (s *InMemorySupplierStore) Delete(id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	supplier, exists := s.Suppliers[id]
+	if !exists || supplier.DeletedAt != nil {
+		return repositories.ErrNotFound
+	}
+	if version != 0 && supplier.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	now := time.Now()
+	supplier.DeletedAt = &now
+	supplier.Version++
+	s.Suppliers[id] = supplier
+	return nil
+}
+
+// Restore brings back a deleted supplier. Restoring a supplier that is not
+// deleted returns it unchanged.
+func (s *InMemorySupplierStore) Restore(id int) (models.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	supplier, exists := s.Suppliers[id]
+	if !exists {
+		return models.Supplier{}, repositories.ErrNotFound
+	}
+	if supplier.DeletedAt != nil {
+		supplier.DeletedAt = nil
+		supplier.Version++
+		s.Suppliers[id] = supplier
+	}
+	return supplier, nil
+}
+
+// Purge removes for good the suppliers deleted before the given time
+func (s *InMemorySupplierStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, supplier := range s.Suppliers {
+		if supplier.DeletedAt != nil && supplier.DeletedAt.Before(before) {
+			delete(s.Suppliers, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Search filters suppliers by a part of their name, ordered by ID
+func (s *InMemorySupplierStore) Search(query models.SearchCriteria) ([]models.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Supplier{}
+	for _, supplier := range s.Suppliers {
+		if supplier.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if name, exists := query.Filters["name"].(string); exists && !strings.Contains(strings.ToLower(supplier.Name), strings.ToLower(name)) {
+			continue
+		}
+		results = append(results, supplier)
+	}
+	slices.SortFunc(results, func(a, b models.Supplier) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return results, nil
+}
+
+func (s *InMemorySupplierStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suppliers := maps.Clone(s.Suppliers)
+	nextID := s.nextID
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Suppliers = suppliers
+		s.nextID = nextID
+	}
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type InMemoryWishlistStore struct {
+	mu        sync.Mutex
+	Wishlists map[int]models.Wishlist
+}
+
+var (
+	wishlistStoreInstance *InMemoryWishlistStore
+	wishlistStoreOnce     sync.Once
+)
+
+// NewInMemoryWishlistStore returns the singleton instance of InMemoryWishlistStore
+func NewInMemoryWishlistStore() *InMemoryWishlistStore {
+	wishlistStoreOnce.Do(func() {
+		wishlistStoreInstance = &InMemoryWishlistStore{
+			Wishlists: make(map[int]models.Wishlist),
+		}
+	})
+	return wishlistStoreInstance
+}
+
+// Get retrieves the wishlist of a customer
+func (s *InMemoryWishlistStore) Get(customerID int) (models.Wishlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wishlist, exists := s.Wishlists[customerID]
+	if !exists {
+		return models.Wishlist{}, repositories.ErrNotFound
+	}
+	return wishlist, nil
+}
+
+// Save creates or replaces the wishlist of a customer. A non-zero version
+// must match the stored wishlist; a new wishlist is saved with version 0.
+func (s *InMemoryWishlistStore) Save(wishlist models.Wishlist) (models.Wishlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.Wishlists[wishlist.CustomerID]
+	if wishlist.Version != 0 && (!exists || wishlist.Version != current.Version) {
+		return models.Wishlist{}, repositories.ErrVersionConflict
+	}
+	if wishlist.Version == 0 && exists {
+		return models.Wishlist{}, repositories.ErrVersionConflict
+	}
+	wishlist.Version = current.Version + 1
+	s.Wishlists[wishlist.CustomerID] = wishlist
+	return wishlist, nil
+}
+
+// Delete removes the wishlist of a customer
+func (s *InMemoryWishlistStore) Delete(customerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Wishlists[customerID]; !exists {
+		return repositories.ErrNotFound
+	}
+	delete(s.Wishlists, customerID)
+	return nil
+}
+
+// Search returns the wishlists holding the book_id filter, or all of them,
+// ordered by customer
+func (s *InMemoryWishlistStore) Search(query models.SearchCriteria) ([]models.Wishlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []models.Wishlist{}
+	for _, wishlist := range s.Wishlists {
+		if bookID, exists := query.Filters["book_id"]; exists && !slices.ContainsFunc(wishlist.Items, func(item models.WishlistItem) bool {
+			return item.BookID == bookID
+		}) {
+			continue
+		}
+		results = append(results, wishlist)
+	}
+	slices.SortFunc(results, func(a, b models.Wishlist) int {
+		return cmp.Compare(a.CustomerID, b.CustomerID)
+	})
+	return results, nil
+}
+
+func (s *InMemoryWishlistStore) snapshot() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wishlists := maps.Clone(s.Wishlists)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Wishlists = wishlists
+	}
+}
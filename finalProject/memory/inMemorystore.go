@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"bytes"
 	"encoding/json"
 	"log"
 	"os"
@@ -9,11 +10,27 @@ import (
 )
 
 type InMemoryStore struct {
-	BookStore     InMemoryBookStore
-	AuthorStore   InMemoryAuthorStore
-	CustomerStore InMemoryCustomerStore
-	OrderStore    InMemoryOrderStore
-	SalesReport   InMemorySalesReportStore
+	BookStore      *InMemoryBookStore
+	AuthorStore    *InMemoryAuthorStore
+	CustomerStore  *InMemoryCustomerStore
+	OrderStore     *InMemoryOrderStore
+	SalesReport    *InMemorySalesReportStore
+	AuthStore      *InMemoryAuthStore
+	CartStore      *InMemoryCartStore
+	PromotionStore *InMemoryPromotionStore
+	PaymentStore   *InMemoryPaymentStore
+	ReturnStore    *InMemoryReturnStore
+	AuditStore     *InMemoryAuditStore
+	StockStore     *InMemoryStockMovementStore
+	AlertStore     *InMemoryStockAlertStore
+	SupplierStore  *InMemorySupplierStore
+	PurchaseStore  *InMemoryPurchaseOrderStore
+	ReviewStore    *InMemoryReviewStore
+	WishlistStore  *InMemoryWishlistStore
+	// Notifications queues the events the notifier has yet to deliver
+	Notifications *InMemoryNotificationStore
+	// CoPurchases is rebuilt from the orders rather than saved
+	CoPurchases *InMemoryCoPurchaseStore `json:"-"`
 }
 
 var (
@@ -39,40 +56,164 @@ func NewInMemoryStore() (*InMemoryStore, error) {
 	return instance, nil
 }
 
+// newStore wires the aggregate store to the singleton instances so that the
+// handlers and the services reaching for memory.NewInMemory*Store() share
+// the same maps, counters and locks.
+func newStore() *InMemoryStore {
+	return &InMemoryStore{
+		BookStore:      NewInMemoryBookStore(),
+		AuthorStore:    NewInMemoryAuthorStore(),
+		CustomerStore:  NewInMemoryCustomerStore(),
+		OrderStore:     NewInMemoryOrderStore(),
+		SalesReport:    NewInMemorySalesReportStore(),
+		AuthStore:      NewInMemoryAuthStore(),
+		CartStore:      NewInMemoryCartStore(),
+		PromotionStore: NewInMemoryPromotionStore(),
+		PaymentStore:   NewInMemoryPaymentStore(),
+		ReturnStore:    NewInMemoryReturnStore(),
+		AuditStore:     NewInMemoryAuditStore(),
+		StockStore:     NewInMemoryStockMovementStore(),
+		AlertStore:     NewInMemoryStockAlertStore(),
+		SupplierStore:  NewInMemorySupplierStore(),
+		PurchaseStore:  NewInMemoryPurchaseOrderStore(),
+		ReviewStore:    NewInMemoryReviewStore(),
+		WishlistStore:  NewInMemoryWishlistStore(),
+		Notifications:  NewInMemoryNotificationStore(),
+		CoPurchases:    NewInMemoryCoPurchaseStore(),
+	}
+}
+
 func initializeStores(store *InMemoryStore) {
-	// Initialize BookStore if it is not initialized
-	if store.BookStore.Books == nil {
-		store.BookStore = *NewInMemoryBookStore()
+	// Move the ID counters past the records loaded from disk, and start the
+	// records saved before they had a version at 1, which If-Match can name
+	for id, book := range store.BookStore.Books {
+		if id >= store.BookStore.nextID {
+			store.BookStore.nextID = id + 1
+		}
+		if book.Version == 0 {
+			book.Version = 1
+			store.BookStore.Books[id] = book
+		}
+	}
+
+	for id, author := range store.AuthorStore.Authors {
+		if id >= store.AuthorStore.nextID {
+			store.AuthorStore.nextID = id + 1
+		}
+		if author.Version == 0 {
+			author.Version = 1
+			store.AuthorStore.Authors[id] = author
+		}
+	}
+
+	for id, customer := range store.CustomerStore.Customers {
+		if id >= store.CustomerStore.nextID {
+			store.CustomerStore.nextID = id + 1
+		}
+		if customer.Version == 0 {
+			customer.Version = 1
+			store.CustomerStore.Customers[id] = customer
+		}
+	}
+
+	for id, order := range store.OrderStore.Orders {
+		if id >= store.OrderStore.nextID {
+			store.OrderStore.nextID = id + 1
+		}
+		if order.Version == 0 {
+			order.Version = 1
+			store.OrderStore.Orders[id] = order
+		}
+	}
+
+	for id, promotion := range store.PromotionStore.Promotions {
+		if id >= store.PromotionStore.nextID {
+			store.PromotionStore.nextID = id + 1
+		}
+		if promotion.Version == 0 {
+			promotion.Version = 1
+			store.PromotionStore.Promotions[id] = promotion
+		}
+	}
+	for id := range store.PromotionStore.Redemptions {
+		if id >= store.PromotionStore.nextRedemptionID {
+			store.PromotionStore.nextRedemptionID = id + 1
+		}
 	}
 
-	// Initialize AuthorStore if it is not initialized
-	if store.AuthorStore.Authors == nil {
-		store.AuthorStore = *NewInMemoryAuthorStore()
+	for id := range store.PaymentStore.Payments {
+		if id >= store.PaymentStore.nextID {
+			store.PaymentStore.nextID = id + 1
+		}
+	}
+
+	for id := range store.ReturnStore.Returns {
+		if id >= store.ReturnStore.nextID {
+			store.ReturnStore.nextID = id + 1
+		}
+	}
+
+	for id, supplier := range store.SupplierStore.Suppliers {
+		if id >= store.SupplierStore.nextID {
+			store.SupplierStore.nextID = id + 1
+		}
+		if supplier.Version == 0 {
+			supplier.Version = 1
+			store.SupplierStore.Suppliers[id] = supplier
+		}
 	}
 
-	// Initialize CustomerStore if it is not initialized
-	if store.CustomerStore.Customers == nil {
-		store.CustomerStore = *NewInMemoryCustomerStore()
+	for id := range store.PurchaseStore.PurchaseOrders {
+		if id >= store.PurchaseStore.nextID {
+			store.PurchaseStore.nextID = id + 1
+		}
 	}
 
-	// Initialize OrderStore if it is not initialized
-	if store.OrderStore.Orders == nil {
-		store.OrderStore = *NewInMemoryOrderStore()
+	for id := range store.ReviewStore.Reviews {
+		if id >= store.ReviewStore.nextID {
+			store.ReviewStore.nextID = id + 1
+		}
 	}
 
+	for id := range store.Notifications.Notifications {
+		if id >= store.Notifications.nextID {
+			store.Notifications.nextID = id + 1
+		}
+	}
+
+	store.CoPurchases.rebuild(store.OrderStore.Orders)
+
 }
+
+// LoadData reads database.json into the singleton stores. The fields of the
+// aggregate store point to the singletons, so a store saved as null is left
+// out rather than decoded, which would set its field to nil.
 func LoadData() (*InMemoryStore, error) {
+	store := newStore()
+
 	data, err := os.ReadFile("database.json")
 	if err != nil {
 		// If file doesn't exist, return empty store
 		if os.IsNotExist(err) {
-			return &InMemoryStore{}, nil
+			return store, nil
 		}
 		return nil, err
 	}
 
-	store := &InMemoryStore{}
-	err = json.Unmarshal(data, &store)
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for name, raw := range saved {
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			delete(saved, name)
+		}
+	}
+	if data, err = json.Marshal(saved); err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, store)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,47 @@
+package memory
+
+// snapshotter is implemented by every store so that a group of changes can
+// be rolled back: snapshot copies the store and returns a function putting
+// the copy back in place.
+type snapshotter interface {
+	snapshot() func()
+}
+
+// Snapshot captures the content of every store and returns a function that
+// restores it. Writes made by other goroutines in between are lost on restore,
+// callers keep them out while a snapshot may be restored.
+func (s *InMemoryStore) Snapshot() (restore func()) {
+	stores := []snapshotter{
+		s.BookStore,
+		s.AuthorStore,
+		s.CustomerStore,
+		s.OrderStore,
+		s.SalesReport,
+		s.AuthStore,
+		s.CartStore,
+		s.PromotionStore,
+		s.PaymentStore,
+		s.ReturnStore,
+		s.AuditStore,
+		s.StockStore,
+		s.AlertStore,
+		s.SupplierStore,
+		s.PurchaseStore,
+		s.ReviewStore,
+		s.WishlistStore,
+		s.Notifications,
+		s.CoPurchases,
+		NewInMemoryOrderItemStore(),
+		NewInMemoryBookSaleStore(),
+	}
+
+	restores := make([]func(), 0, len(stores))
+	for _, store := range stores {
+		restores = append(restores, store.snapshot())
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit configures the token bucket of a route group: clients may send
+// Burst requests at once and regain Rate requests per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter keeps one token bucket per route group and client in memory.
+// Clients are identified by their X-API-Key header when it is one of the
+// keys given out, otherwise by IP address.
+type RateLimiter struct {
+	mu       sync.Mutex
+	limits   map[string]RateLimit
+	classify func(r *http.Request) string
+	buckets  map[string]*bucket
+	idleTTL  time.Duration
+	// apiKeys are the keys given out to clients; other keys are ignored so
+	// that a client cannot get a fresh bucket by sending a new key
+	apiKeys map[string]bool
+}
+
+// NewRateLimiter creates a limiter and starts a goroutine that forgets clients
+// idle for longer than idleTTL. classify maps a request to a key of limits;
+// requests of groups without a limit are not throttled.
+func NewRateLimiter(limits map[string]RateLimit, classify func(r *http.Request) string, idleTTL time.Duration, apiKeys []string) *RateLimiter {
+	l := &RateLimiter{
+		limits:   limits,
+		classify: classify,
+		buckets:  make(map[string]*bucket),
+		idleTTL:  idleTTL,
+		apiKeys:  make(map[string]bool, len(apiKeys)),
+	}
+	for _, key := range apiKeys {
+		l.apiKeys[key] = true
+	}
+	go l.cleanup()
+	return l
+}
+
+// Middleware rejects requests over the limit of their group with 429 and
+// reports the client's budget through X-RateLimit-* headers.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := l.classify(r)
+		limit, exists := l.limits[group]
+		if !exists {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, retryAfter, reset := l.take(group+"|"+l.ClientKey(r), limit)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(reset)))
+		if !allowed {
+			log.Printf("RateLimiter: %s over the %q limit, retry after %v", r.RemoteAddr, group, retryAfter)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take refills the bucket for the time elapsed since the last request and
+// consumes one token if available. It returns the tokens left, how long until
+// the next token, and how long until the bucket is full again.
+func (l *RateLimiter) take(key string, limit RateLimit) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.lastSeen).Seconds()*limit.Rate)
+	b.lastSeen = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	retryAfter := time.Duration(0)
+	if b.tokens < 1 {
+		retryAfter = refillTime(1-b.tokens, limit.Rate)
+	}
+	reset := refillTime(float64(limit.Burst)-b.tokens, limit.Rate)
+	return allowed, int(b.tokens), retryAfter, reset
+}
+
+func (l *RateLimiter) cleanup() {
+	ticker := time.NewTicker(l.idleTTL)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if time.Since(b.lastSeen) > l.idleTTL {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// ClientKey identifies the caller by API key if it is a known one, falling
+// back to the remote IP
+func (l *RateLimiter) ClientKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); l.apiKeys[key] {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func refillTime(tokens, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
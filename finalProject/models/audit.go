@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entity types
+const (
+	EntityBook          = "book"
+	EntityAuthor        = "author"
+	EntityCustomer      = "customer"
+	EntityOrder         = "order"
+	EntityPromotion     = "promotion"
+	EntitySupplier      = "supplier"
+	EntityPurchaseOrder = "purchase_order"
+	EntityReview        = "review"
+	EntityPayment       = "payment"
+	EntityReturn        = "return"
+	EntityCart          = "cart"
+	EntityWishlist      = "wishlist"
+	EntityCredential    = "credential"
+	EntityPasswordReset = "password_reset"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditEntry records a change made to an entity. Actor is "admin",
+// "customer:<id>", "anonymous" or "system" for changes made by the server
+// itself. Changes holds the fields that changed, by JSON name.
+type AuditEntry struct {
+	ID        int                    `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Actor     string                 `json:"actor"`
+	Entity    string                 `json:"entity"`
+	EntityID  int                    `json:"entity_id"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange holds the value of a field before and after the change; a
+// created field has no before, a deleted one no after
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Registration is the payload accepted when a customer signs up with a password
+type Registration struct {
+	Customer
+	Password string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type EmailChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewEmail        string `json:"new_email"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmation struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// Credential holds the salted password hash of a customer
+type Credential struct {
+	CustomerID   int       `json:"customer_id"`
+	PasswordHash string    `json:"password_hash"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Session is a logged-in customer; ID is the SHA-256 digest of the bearer token
+type Session struct {
+	ID         string    `json:"id"`
+	CustomerID int       `json:"customer_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// PasswordReset is a one-time reset token; ID is the SHA-256 digest of the token
+type PasswordReset struct {
+	ID         string    `json:"id"`
+	CustomerID int       `json:"customer_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LoginAttempt tracks failed logins for an email address
+type LoginAttempt struct {
+	Email        string    `json:"email"`
+	Failures     int       `json:"failures"`
+	FirstFailure time.Time `json:"first_failure"`
+	LockedUntil  time.Time `json:"locked_until"`
+}
+
+// AuthToken is returned to the client after a successful login
+type AuthToken struct {
+	Token      string    `json:"token"`
+	CustomerID int       `json:"customer_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "encoding/json"
+
+// BatchRequest lists sub-requests run in order against the API; with Atomic
+// set the batch stops at the first failure and every change is rolled back
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one sub-request. A JSON string Body is sent as is, which
+// allows non JSON payloads such as a CSV import
+type BatchOperation struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse holds one result per operation, in request order
+type BatchResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchResult is the response of one sub-request. Body is the decoded JSON
+// response, or the raw text when the response is not JSON
+type BatchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
@@ -9,5 +9,18 @@ type Book struct {
 	Genres      []string  `json:"genres"`
 	PublishedAt time.Time `json:"published_at"`
 	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
+	// CostPrice is what a copy cost on average, updated by the receipts
+	// of purchase orders; only the admin sees and sets it
+	CostPrice   float64 `json:"cost_price,omitempty"`
+	Stock       int     `json:"stock"`
+	WeightGrams int     `json:"weight_grams"`
+	// ReorderThreshold raises a low stock alert when the stock drops below
+	// it; zero disables the alert
+	ReorderThreshold int `json:"reorder_threshold"`
+	// AverageRating and RatingCount sum up the reviews of the book; they
+	// only change with its reviews
+	AverageRating float64    `json:"average_rating"`
+	RatingCount   int        `json:"rating_count"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+	Version       int        `json:"version"`
 }
@@ -1,6 +1,15 @@
 package models
 
+import "time"
+
+// BookSale records copies of a book sold; a return is recorded with a
+// negative quantity and the ID of the return, a cancelled order with a
+// negative quantity and the ID of the order
 type BookSale struct {
+	ID       int `json:"id,omitempty"`
 	Book     `json:"book"`
-	Quantity int `json:"quantity_sold"`
+	Quantity int       `json:"quantity_sold"`
+	OrderID  int       `json:"order_id,omitempty"`
+	ReturnID int       `json:"return_id,omitempty"`
+	SoldAt   time.Time `json:"sold_at"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Cart collects the books a customer intends to order. Prices, totals and
+// warnings are evaluated against the catalog every time the cart is read.
+type Cart struct {
+	CustomerID int           `json:"customer_id"`
+	Items      []CartItem    `json:"items"`
+	Subtotal   float64       `json:"subtotal"`
+	Warnings   []CartWarning `json:"warnings,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	Version    int           `json:"version"`
+}
+
+// CartItem is one book in a cart; AddedPrice is the price when the book was
+// put in the cart, UnitPrice the current catalog price
+type CartItem struct {
+	BookID     int     `json:"book_id"`
+	Title      string  `json:"title"`
+	Quantity   int     `json:"quantity"`
+	AddedPrice float64 `json:"added_price"`
+	UnitPrice  float64 `json:"unit_price"`
+	LineTotal  float64 `json:"line_total"`
+	Available  int     `json:"available"`
+}
+
+// CartWarning flags an item that changed since it was added or cannot be
+// ordered in the requested quantity
+type CartWarning struct {
+	BookID  int    `json:"book_id"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	CartWarningPriceChanged      = "price_changed"
+	CartWarningInsufficientStock = "insufficient_stock"
+	CartWarningOutOfStock        = "out_of_stock"
+	CartWarningUnavailable       = "unavailable"
+)
+
+// CartItemRequest is the payload to add a book to a cart or change its quantity
+type CartItemRequest struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
@@ -0,0 +1,30 @@
+package models
+
+const (
+	// DuplicateSameEmail flags customers whose emails only differ in case,
+	// a +tag or, for Gmail, dots
+	DuplicateSameEmail = "same_email"
+	// DuplicateSimilarName flags customers with nearly the same name living
+	// at the same postal code
+	DuplicateSimilarName = "similar_name_and_postal_code"
+)
+
+// CustomerDuplicate pairs a customer with a likely duplicate registered
+// after it, and tells why they look alike
+type CustomerDuplicate struct {
+	Customer  Customer `json:"customer"`
+	Duplicate Customer `json:"duplicate"`
+	Reasons   []string `json:"reasons"`
+}
+
+// CustomerMerge is the outcome of merging a duplicate into a customer: the
+// orders and reviews moved to the customer, the reviews of the duplicate
+// deleted because the customer reviewed the same books, and the deleted
+// duplicate
+type CustomerMerge struct {
+	Customer       Customer `json:"customer"`
+	DuplicateID    int      `json:"duplicate_id"`
+	MovedOrders    []int    `json:"moved_orders"`
+	MovedReviews   []int    `json:"moved_reviews"`
+	DeletedReviews []int    `json:"deleted_reviews"`
+}
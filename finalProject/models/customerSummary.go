@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CustomerSummary sums up the orders of a customer. Orders whose copies went
+// back to stock unpaid are left out and LifetimeSpend is net of refunds.
+type CustomerSummary struct {
+	CustomerID      int          `json:"customer_id"`
+	OrderCount      int          `json:"order_count"`
+	LifetimeSpend   float64      `json:"lifetime_spend"`
+	FirstOrderAt    *time.Time   `json:"first_order_at,omitempty"`
+	LastOrderAt     *time.Time   `json:"last_order_at,omitempty"`
+	FavouriteGenres []GenreCount `json:"favourite_genres"`
+}
+
+// GenreCount is the number of copies of books of a genre a customer kept
+type GenreCount struct {
+	Genre    string `json:"genre"`
+	Quantity int    `json:"quantity"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// ImportReport summarizes a catalog import, row by row
+type ImportReport struct {
+	DryRun         bool              `json:"dry_run"`
+	Rows           int               `json:"rows"`
+	Created        int               `json:"created"`
+	Updated        int               `json:"updated"`
+	Failed         int               `json:"failed"`
+	AuthorsCreated int               `json:"authors_created"`
+	Results        []ImportRowResult `json:"results"`
+}
+
+// ImportRowResult is the outcome of one CSV record or NDJSON line; Row is
+// the line number in the uploaded file
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	Action string `json:"action"`
+	BookID int    `json:"book_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Notification is an event for a customer, queued when it happens and
+// delivered by the notifier afterwards
+type Notification struct {
+	ID         int        `json:"id"`
+	Type       string     `json:"type"`
+	CustomerID int        `json:"customer_id"`
+	BookID     int        `json:"book_id"`
+	Title      string     `json:"title"`
+	Stock      int        `json:"stock"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	Version    int        `json:"version"`
+	// Token is the password reset token. Reset notifications are handed
+	// to the notifier as they are issued and never stored.
+	Token string `json:"token,omitempty"`
+}
+
+// NotificationBackInStock tells a customer that a book of their wishlist can
+// be ordered again
+const NotificationBackInStock = "back_in_stock"
+
+// NotificationPasswordReset hands a customer the token to reset their
+// password with
+const NotificationPasswordReset = "password_reset"
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	// NotificationFailed is given up on after too many failed deliveries
+	NotificationFailed = "failed"
+)
@@ -2,11 +2,44 @@ package models
 
 import "time"
 
+// Order is priced by the server when it is created: Subtotal is the sum of
+// the items at their current price and TotalPrice the subtotal less
+// DiscountTotal, plus Shipping and TaxTotal. PromotionCodes are the codes
+// entered by the customer.
 type Order struct {
-	ID         int         `json:"id"`
-	Customer   Customer    `json:"customer"`
-	Items      []OrderItem `json:"items"`
-	TotalPrice float64     `json:"total_price"`
-	CreatedAt  time.Time   `json:"created_at"`
-	Status     string      `json:"status"`
+	ID             int               `json:"id"`
+	Customer       Customer          `json:"customer"`
+	Items          []OrderItem       `json:"items"`
+	PromotionCodes []string          `json:"promotion_codes,omitempty"`
+	Subtotal       float64           `json:"subtotal"`
+	Discounts      []AppliedDiscount `json:"discounts,omitempty"`
+	DiscountTotal  float64           `json:"discount_total"`
+	Shipping       ShippingLine      `json:"shipping"`
+	TaxLines       []TaxLine         `json:"tax_lines,omitempty"`
+	TaxTotal       float64           `json:"tax_total"`
+	TotalPrice     float64           `json:"total_price"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Status         string            `json:"status"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+	Version        int               `json:"version"`
+}
+
+// Order statuses; the payment of an order moves it from pending onwards
+const (
+	OrderStatusPending           = "pending"
+	OrderStatusAuthorized        = "authorized"
+	OrderStatusPaid              = "paid"
+	OrderStatusPaymentFailed     = "payment_failed"
+	OrderStatusPaymentExpired    = "payment_expired"
+	OrderStatusCancelled         = "cancelled"
+	OrderStatusPartiallyRefunded = "partially_refunded"
+	OrderStatusRefunded          = "refunded"
+)
+
+// ReleasedOrderStatuses are the order statuses whose copies went back to
+// stock; such orders no longer count as sold
+var ReleasedOrderStatuses = []string{
+	OrderStatusPaymentFailed,
+	OrderStatusPaymentExpired,
+	OrderStatusCancelled,
 }
@@ -0,0 +1,17 @@
+package models
+
+// TaxLine is one tax levied on an order, such as a state sales tax; Rate is
+// a fraction of the taxed amount
+type TaxLine struct {
+	Name         string  `json:"name"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Rate         float64 `json:"rate"`
+	Amount       float64 `json:"amount"`
+}
+
+// ShippingLine is the shipping charge of an order and what it was based on
+type ShippingLine struct {
+	Zone        string  `json:"zone"`
+	WeightGrams int     `json:"weight_grams"`
+	Amount      float64 `json:"amount"`
+}
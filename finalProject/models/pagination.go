@@ -0,0 +1,21 @@
+package models
+
+// Pagination tells which page of a list is returned, pages counting from 1,
+// and how many items the whole list has
+type Pagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+// OrderPage is a page of orders, newest first
+type OrderPage struct {
+	Orders []Order `json:"orders"`
+	Pagination
+}
+
+// ReviewPage is a page of the reviews of a book, newest first
+type ReviewPage struct {
+	Reviews []Review `json:"reviews"`
+	Pagination
+}
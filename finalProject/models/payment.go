@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Payment statuses
+const (
+	PaymentAuthorized        = "authorized"
+	PaymentCaptured          = "captured"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+	PaymentVoided            = "voided"
+	PaymentFailed            = "failed"
+	PaymentExpired           = "expired"
+)
+
+// Payment is the payment of an order with a PaymentProvider; Reference is
+// the authorization ID given by the provider
+type Payment struct {
+	ID             int        `json:"id"`
+	OrderID        int        `json:"order_id"`
+	Provider       string     `json:"provider"`
+	Reference      string     `json:"reference,omitempty"`
+	Status         string     `json:"status"`
+	Amount         float64    `json:"amount"`
+	CapturedAmount float64    `json:"captured_amount"`
+	RefundedAmount float64    `json:"refunded_amount"`
+	FailureReason  string     `json:"failure_reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CapturedAt     *time.Time `json:"captured_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Version        int        `json:"version"`
+}
+
+// PaymentRequest starts the payment of an order
+type PaymentRequest struct {
+	PaymentMethod string `json:"payment_method"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+const (
+	PromotionPercentage  = "percentage"
+	PromotionFixedAmount = "fixed_amount"
+	PromotionBuyXGetY    = "buy_x_get_y"
+	PromotionAuthorSale  = "author_sale"
+)
+
+// Promotion is a pricing rule applied when an order is priced. Promotions
+// with a Code apply only when the customer enters it, the others apply to
+// every order they qualify for.
+//
+//   - percentage: Value percent off the order subtotal
+//   - fixed_amount: Value off the order subtotal
+//   - buy_x_get_y: for every BuyQuantity copies of Genre, the FreeQuantity
+//     cheapest following ones are free
+//   - author_sale: Value percent off the books of AuthorID
+//
+// Only promotions marked Stackable combine; otherwise the order gets the
+// single best discount. Zero times and limits mean no restriction.
+type Promotion struct {
+	ID               int        `json:"id"`
+	Code             string     `json:"code,omitempty"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Value            float64    `json:"value"`
+	Genre            string     `json:"genre,omitempty"`
+	AuthorID         int        `json:"author_id,omitempty"`
+	BuyQuantity      int        `json:"buy_quantity,omitempty"`
+	FreeQuantity     int        `json:"free_quantity,omitempty"`
+	StartsAt         time.Time  `json:"starts_at"`
+	EndsAt           time.Time  `json:"ends_at"`
+	UsageLimit       int        `json:"usage_limit"`
+	PerCustomerLimit int        `json:"per_customer_limit"`
+	Stackable        bool       `json:"stackable"`
+	Disabled         bool       `json:"disabled"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	Version          int        `json:"version"`
+}
+
+// PromotionRedemption records one use of a promotion by an order
+type PromotionRedemption struct {
+	ID          int       `json:"id"`
+	PromotionID int       `json:"promotion_id"`
+	CustomerID  int       `json:"customer_id"`
+	OrderID     int       `json:"order_id"`
+	Amount      float64   `json:"amount"`
+	RedeemedAt  time.Time `json:"redeemed_at"`
+}
+
+// AppliedDiscount is a promotion applied to an order and the amount it took off
+type AppliedDiscount struct {
+	PromotionID int     `json:"promotion_id"`
+	Code        string  `json:"code,omitempty"`
+	Name        string  `json:"name"`
+	Amount      float64 `json:"amount"`
+}
+
+// PromotionCodes is the optional payload of a cart checkout
+type PromotionCodes struct {
+	Codes []string `json:"promotion_codes"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Purchase order statuses
+const (
+	PurchaseOrderOpen              = "open"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+// PurchaseOrder orders books from a supplier. Its lines are received, in
+// one or several deliveries, into the stock of the books.
+type PurchaseOrder struct {
+	ID         int                 `json:"id"`
+	SupplierID int                 `json:"supplier_id"`
+	Lines      []PurchaseOrderLine `json:"lines"`
+	Status     string              `json:"status"`
+	// TotalCost is the cost of the ordered quantities
+	TotalCost float64   `json:"total_cost"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// PurchaseOrderLine is a quantity of a book bought at a unit cost
+type PurchaseOrderLine struct {
+	BookID           int     `json:"book_id"`
+	Title            string  `json:"title"`
+	Quantity         int     `json:"quantity"`
+	ReceivedQuantity int     `json:"received_quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+}
+
+// PurchaseOrderReceipt is a delivery of some of the ordered books
+type PurchaseOrderReceipt struct {
+	Lines []ReceivedLine `json:"lines"`
+}
+
+type ReceivedLine struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
@@ -0,0 +1,20 @@
+package models
+
+const (
+	RecommendedBoughtTogether = "bought_together"
+	RecommendedSameAuthor     = "same_author"
+	RecommendedSameGenre      = "same_genre"
+	RecommendedSimilarPrice   = "similar_price"
+	RecommendedPopular        = "popular"
+)
+
+// Recommendation is a book suggested to a reader, with the reason it was
+// picked. BoughtTogether is the number of orders holding both books when
+// the reason is bought_together; Score, from 0 to 1, tells how well a book
+// matches the purchase history of a customer.
+type Recommendation struct {
+	Book           Book    `json:"book"`
+	Reason         string  `json:"reason"`
+	BoughtTogether int     `json:"bought_together,omitempty"`
+	Score          float64 `json:"score,omitempty"`
+}
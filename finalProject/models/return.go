@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Return statuses; an approved return has been refunded and restocked
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+)
+
+// Return sends back some of the items of a paid order. RefundAmount is what
+// the customer paid for them: their price less their share of the order
+// discounts, plus the tax charged on it. Shipping is not refunded.
+type Return struct {
+	ID              int          `json:"id"`
+	OrderID         int          `json:"order_id"`
+	Items           []ReturnItem `json:"items"`
+	Reason          string       `json:"reason,omitempty"`
+	Status          string       `json:"status"`
+	Subtotal        float64      `json:"subtotal"`
+	DiscountTotal   float64      `json:"discount_total"`
+	TaxTotal        float64      `json:"tax_total"`
+	RefundAmount    float64      `json:"refund_amount"`
+	RejectionReason string       `json:"rejection_reason,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	DecidedAt       *time.Time   `json:"decided_at,omitempty"`
+	Version         int          `json:"version"`
+}
+
+// ReturnItem is a quantity of an OrderItem being returned
+type ReturnItem struct {
+	OrderItemID int     `json:"order_item_id"`
+	BookID      int     `json:"book_id"`
+	Title       string  `json:"title"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// ReturnRequest asks for the return of order items
+type ReturnRequest struct {
+	Items  []ReturnItemRequest `json:"items"`
+	Reason string              `json:"reason"`
+}
+
+type ReturnItemRequest struct {
+	OrderItemID int `json:"order_item_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ReturnDecision is the reason given when a return is rejected
+type ReturnDecision struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Review is the opinion of a customer on a book, one per customer and book.
+// VerifiedPurchase is set by the server when the customer ordered the book.
+type Review struct {
+	ID               int       `json:"id"`
+	BookID           int       `json:"book_id"`
+	CustomerID       int       `json:"customer_id"`
+	Rating           int       `json:"rating"`
+	Text             string    `json:"text"`
+	VerifiedPurchase bool      `json:"verified_purchase"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Version          int       `json:"version"`
+}
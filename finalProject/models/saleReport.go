@@ -2,9 +2,73 @@ package models
 
 import "time"
 
+// SalesReport sums up the orders placed between From and To, less the
+// returns approved in that period; a nil bound is open. Orders whose copies
+// went back to stock unpaid are left out. AverageOrderValue is what the
+// orders charged on average, before returns.
 type SalesReport struct {
-	Timestamp       time.Time  `json:"timestamp"`
-	TotalRevenue    float64    `json:"total_revenue"`
-	TotalOrders     int        `json:"total_orders"`
-	TopSellingBooks []BookSale `json:"top_selling_books"`
+	Timestamp         time.Time        `json:"timestamp"`
+	From              *time.Time       `json:"from,omitempty"`
+	To                *time.Time       `json:"to,omitempty"`
+	TotalRevenue      float64          `json:"total_revenue"`
+	TotalOrders       int              `json:"total_orders"`
+	TotalReturns      int              `json:"total_returns"`
+	AverageOrderValue float64          `json:"average_order_value"`
+	Revenue           RevenueBreakdown `json:"revenue"`
+	Margin            MarginBreakdown  `json:"margin"`
+	RevenueByGenre    []GenreRevenue   `json:"revenue_by_genre"`
+	RevenueByAuthor   []AuthorRevenue  `json:"revenue_by_author"`
+	TopSellingBooks   []BookSale       `json:"top_selling_books"`
+	TopCustomers      []CustomerSpend  `json:"top_customers"`
+}
+
+// RevenueBreakdown splits the total revenue into the items sold, less
+// discounts and returns, and the shipping and taxes charged on top. Returns
+// and TaxRefunded are what the returns gave back, net of their discounts.
+type RevenueBreakdown struct {
+	Subtotal          float64            `json:"subtotal"`
+	Discounts         float64            `json:"discounts"`
+	Returns           float64            `json:"returns"`
+	NetSales          float64            `json:"net_sales"`
+	Shipping          float64            `json:"shipping"`
+	Tax               float64            `json:"tax"`
+	TaxRefunded       float64            `json:"tax_refunded"`
+	ShippingByZone    map[string]float64 `json:"shipping_by_zone,omitempty"`
+	TaxByJurisdiction map[string]float64 `json:"tax_by_jurisdiction,omitempty"`
+}
+
+// MarginBreakdown compares the net sales with the cost of the copies sold,
+// at the cost price the books had when they were ordered. Copies of books
+// without a cost price count as free and are reported in UncostedQuantity.
+type MarginBreakdown struct {
+	NetSales           float64 `json:"net_sales"`
+	CostOfGoods        float64 `json:"cost_of_goods"`
+	GrossMargin        float64 `json:"gross_margin"`
+	GrossMarginPercent float64 `json:"gross_margin_percent"`
+	UncostedQuantity   int     `json:"uncosted_quantity"`
+}
+
+// GenreRevenue is the net sales of the books of a genre; a book with several
+// genres counts in each of them
+type GenreRevenue struct {
+	Genre    string  `json:"genre"`
+	Quantity int     `json:"quantity"`
+	NetSales float64 `json:"net_sales"`
+}
+
+// AuthorRevenue is the net sales of the books of an author
+type AuthorRevenue struct {
+	AuthorID int     `json:"author_id"`
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	NetSales float64 `json:"net_sales"`
+}
+
+// CustomerSpend is what a customer paid for the orders of the period, less
+// the refunds of the period
+type CustomerSpend struct {
+	CustomerID int     `json:"customer_id"`
+	Name       string  `json:"name"`
+	Orders     int     `json:"orders"`
+	TotalSpent float64 `json:"total_spent"`
 }
@@ -1,5 +1,8 @@
 package models
 
+// SearchCriteria filters the records of a store. Soft deleted records are
+// left out unless IncludeDeleted is set.
 type SearchCriteria struct {
-	Filters map[string]interface{}
+	Filters        map[string]interface{}
+	IncludeDeleted bool
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StockAlert flags a book whose stock dropped below its reorder threshold,
+// with the quantity to reorder to cover the recent sales
+type StockAlert struct {
+	BookID           int       `json:"book_id"`
+	Title            string    `json:"title"`
+	Stock            int       `json:"stock"`
+	ReorderThreshold int       `json:"reorder_threshold"`
+	RaisedAt         time.Time `json:"raised_at"`
+	// DailySales is the average number of copies sold a day recently
+	DailySales        float64 `json:"daily_sales"`
+	SuggestedQuantity int     `json:"suggested_quantity"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Types of stock movements
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementReturn     = "return"
+	MovementAdjustment = "adjustment"
+	MovementDamage     = "damage"
+	// MovementCancellation gives back the copies of an order that was not
+	// placed or whose payment failed, expired or was voided
+	MovementCancellation = "cancellation"
+)
+
+// StockMovement is an entry of the inventory ledger; the stock of a book is
+// the sum of the quantities of its movements
+type StockMovement struct {
+	ID     int    `json:"id"`
+	BookID int    `json:"book_id"`
+	Type   string `json:"type"`
+	// Quantity is the change of the stock, negative for copies leaving it
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason,omitempty"`
+	// UnitCost is what the received copies cost each, if known
+	UnitCost        float64   `json:"unit_cost,omitempty"`
+	OrderID         int       `json:"order_id,omitempty"`
+	ReturnID        int       `json:"return_id,omitempty"`
+	PurchaseOrderID int       `json:"purchase_order_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// StockMovementRequest records a receipt, an adjustment or damaged copies;
+// sales and returns are recorded by orders and returns
+type StockMovementRequest struct {
+	Type     string  `json:"type"`
+	Quantity int     `json:"quantity"`
+	Reason   string  `json:"reason"`
+	UnitCost float64 `json:"unit_cost"`
+}
+
+// StockHistory lists the movements of a book with the stock after each of
+// them, and reconciles the ledger with the book and its orders
+type StockHistory struct {
+	BookID int `json:"book_id"`
+	// Stock is the stock of the book, LedgerStock the sum of its movements
+	Stock         int                 `json:"stock"`
+	LedgerStock   int                 `json:"ledger_stock"`
+	Reconciled    bool                `json:"reconciled"`
+	Movements     []StockHistoryEntry `json:"movements"`
+	Discrepancies []StockDiscrepancy  `json:"discrepancies"`
+}
+
+type StockHistoryEntry struct {
+	StockMovement
+	Balance int `json:"balance"`
+}
+
+// StockDiscrepancy is an order whose movements do not add up to the copies
+// it holds: the ordered quantity less the returned one, or none once the
+// order is cancelled
+type StockDiscrepancy struct {
+	OrderID  int    `json:"order_id"`
+	Status   string `json:"status"`
+	Expected int    `json:"expected"`
+	Recorded int    `json:"recorded"`
+}
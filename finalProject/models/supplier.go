@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Supplier delivers books through purchase orders
+type Supplier struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Email   string  `json:"email"`
+	Phone   string  `json:"phone,omitempty"`
+	Address Address `json:"address"`
+	// LeadTimeDays is how long the supplier usually takes to deliver
+	LeadTimeDays int        `json:"lead_time_days"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Version      int        `json:"version"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Wishlist holds the books a customer wants to buy later, typically books
+// out of stock; the customer is notified when one is back in stock. Titles
+// and stock are read from the catalog every time the wishlist is read.
+type Wishlist struct {
+	CustomerID int            `json:"customer_id"`
+	Items      []WishlistItem `json:"items"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Version    int            `json:"version"`
+}
+
+// WishlistItem is one book in a wishlist
+type WishlistItem struct {
+	BookID    int       `json:"book_id"`
+	Title     string    `json:"title"`
+	Available int       `json:"available"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// WishlistItemRequest is the payload to add a book to a wishlist
+type WishlistItemRequest struct {
+	BookID int `json:"book_id"`
+}
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+// AuditStore is append-only: entries are never updated or deleted
+type AuditStore interface {
+	Append(entry models.AuditEntry) (models.AuditEntry, error)
+	Search(query models.SearchCriteria) ([]models.AuditEntry, error)
+}
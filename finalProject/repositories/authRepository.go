@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore.com/models"
+)
+
+type AuthStore interface {
+	SaveCredential(credential models.Credential) error
+	GetCredential(customerID int) (models.Credential, error)
+
+	CreateSession(session models.Session) (models.Session, error)
+	GetSession(id string) (models.Session, error)
+	DeleteSession(id string) error
+	DeleteCustomerSessions(customerID int) error
+
+	CreatePasswordReset(reset models.PasswordReset) error
+	ConsumePasswordReset(id string) (models.PasswordReset, error)
+
+	GetLoginAttempt(email string) models.LoginAttempt
+	SaveLoginAttempt(attempt models.LoginAttempt) error
+	ClearLoginAttempt(email string) error
+	// PurgeLoginAttempts drops the counters whose failures started and whose
+	// lockout ended before the given time
+	PurgeLoginAttempts(before time.Time) int
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"bookstore.com/models"
 )
 
@@ -8,6 +10,9 @@ type AuthorStore interface {
 	Create(Author models.Author) (models.Author, error)
 	Get(idx int) (models.Author, error)
 	Update(item models.Author) (models.Author, error)
-	Delete(idx int) error
+	Delete(id int, version int) error
+	Restore(idx int) (models.Author, error)
+	// Purge removes for good the authors deleted before the given time
+	Purge(before time.Time) (int, error)
 	Search(query models.SearchCriteria) ([]models.Author, error)
 }
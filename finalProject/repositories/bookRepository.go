@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"bookstore.com/models"
 )
 
@@ -11,7 +13,12 @@ type BookStore interface {
 
 	Update(item models.Book) (models.Book, error)
 
-	Delete(idx int) error
+	Delete(id int, version int) error
+
+	Restore(idx int) (models.Book, error)
+
+	// Purge removes for good the books deleted before the given time
+	Purge(before time.Time) (int, error)
 
 	Search(query models.SearchCriteria) ([]models.Book, error)
 }
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type CartStore interface {
+	Get(customerID int) (models.Cart, error)
+	Save(cart models.Cart) (models.Cart, error)
+	Delete(customerID int, version int) error
+}
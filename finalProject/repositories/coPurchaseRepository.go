@@ -0,0 +1,12 @@
+package repositories
+
+// CoPurchaseStore counts how often books are ordered together
+type CoPurchaseStore interface {
+	// Record counts an order holding the given books
+	Record(bookIDs []int) error
+	// Remove uncounts an order holding the given books
+	Remove(bookIDs []int) error
+	// Related returns the books ordered along with a book and in how many
+	// orders
+	Related(bookID int) (map[int]int, error)
+}
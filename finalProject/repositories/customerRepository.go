@@ -1,13 +1,19 @@
 package repositories
 
 import (
+	"time"
+
 	"bookstore.com/models"
 )
 
 type CustomerStore interface {
 	Create(Customer models.Customer) (models.Customer, error)
 	Get(idx int) (models.Customer, error)
+	FindByEmail(email string) (models.Customer, error)
 	Update(item models.Customer) (models.Customer, error)
-	Delete(idx int) error
+	Delete(id int, version int) error
+	Restore(idx int) (models.Customer, error)
+	// Purge removes for good the customers deleted before the given time
+	Purge(before time.Time) (int, error)
 	Search(query models.SearchCriteria) ([]models.Customer, error)
 }
@@ -0,0 +1,20 @@
+package repositories
+
+import "errors"
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrEmailTaken = errors.New("email already registered")
+	ErrCodeTaken  = errors.New("promotion code already in use")
+
+	// ErrAlreadyReviewed is returned when a customer reviews a book twice
+	ErrAlreadyReviewed = errors.New("book already reviewed by this customer")
+
+	// ErrUsageLimitReached is returned by Redeem when a promotion has been
+	// used as often as its limits allow
+	ErrUsageLimitReached = errors.New("promotion usage limit reached")
+
+	// ErrVersionConflict is returned by Update when the version of the item
+	// does not match the stored one; a zero version skips the check
+	ErrVersionConflict = errors.New("version conflict")
+)
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type NotificationStore interface {
+	Create(notification models.Notification) (models.Notification, error)
+	Update(notification models.Notification) (models.Notification, error)
+	Search(query models.SearchCriteria) ([]models.Notification, error)
+}
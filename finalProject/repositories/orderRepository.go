@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"bookstore.com/models"
 )
 
@@ -8,6 +10,12 @@ type OrderStore interface {
 	Create(Order models.Order) (models.Order, error)
 	Get(idx int) (models.Order, error)
 	Update(item models.Order) (models.Order, error)
-	Delete(idx int) error
+	Delete(id int, version int) error
+	Restore(idx int) (models.Order, error)
+	// Reassign gives every order of a customer, deleted ones included, to
+	// another customer and returns them as they were
+	Reassign(fromCustomerID int, to models.Customer) ([]models.Order, error)
+	// Purge removes for good the orders deleted before the given time
+	Purge(before time.Time) (int, error)
 	Search(query models.SearchCriteria) ([]models.Order, error)
 }
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type PaymentStore interface {
+	Create(payment models.Payment) (models.Payment, error)
+	Get(id int) (models.Payment, error)
+	Update(payment models.Payment) (models.Payment, error)
+	FindByOrder(orderID int) ([]models.Payment, error)
+	Search(query models.SearchCriteria) ([]models.Payment, error)
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore.com/models"
+)
+
+type PromotionStore interface {
+	Create(promotion models.Promotion) (models.Promotion, error)
+	Get(id int) (models.Promotion, error)
+	FindByCode(code string) (models.Promotion, error)
+	Update(promotion models.Promotion) (models.Promotion, error)
+	Delete(id int, version int) error
+	Restore(id int) (models.Promotion, error)
+	// Purge removes for good the promotions deleted before the given time
+	Purge(before time.Time) (int, error)
+	Search(query models.SearchCriteria) ([]models.Promotion, error)
+
+	// Redeem records the redemptions if none of them exceeds the usage
+	// limits of its promotion, and returns them with their IDs
+	Redeem(redemptions []models.PromotionRedemption) ([]models.PromotionRedemption, error)
+	// CountRedemptions returns how often a promotion was used in total and by a customer
+	CountRedemptions(promotionID int, customerID int) (total int, byCustomer int)
+	AttachOrder(redemptionIDs []int, orderID int) error
+	// OrderRedemptions returns the IDs of the redemptions made for an order
+	OrderRedemptions(orderID int) []int
+	CancelRedemptions(redemptionIDs []int) error
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type PurchaseOrderStore interface {
+	Create(order models.PurchaseOrder) (models.PurchaseOrder, error)
+	Get(id int) (models.PurchaseOrder, error)
+	Update(order models.PurchaseOrder) (models.PurchaseOrder, error)
+	Search(query models.SearchCriteria) ([]models.PurchaseOrder, error)
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type ReturnStore interface {
+	Create(ret models.Return) (models.Return, error)
+	Get(id int) (models.Return, error)
+	Update(ret models.Return) (models.Return, error)
+	FindByOrder(orderID int) ([]models.Return, error)
+	Search(query models.SearchCriteria) ([]models.Return, error)
+}
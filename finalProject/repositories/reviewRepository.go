@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type ReviewStore interface {
+	// Create fails with ErrAlreadyReviewed when the customer already
+	// reviewed the book
+	Create(review models.Review) (models.Review, error)
+	Get(id int) (models.Review, error)
+	Update(review models.Review) (models.Review, error)
+	Delete(id int) error
+	// Reassign gives the reviews of a customer to another one, except for
+	// the books the other customer reviewed already, and returns them as
+	// they were
+	Reassign(fromCustomerID, toCustomerID int) ([]models.Review, error)
+	Search(query models.SearchCriteria) ([]models.Review, error)
+}
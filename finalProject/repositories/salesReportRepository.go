@@ -0,0 +1,10 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type SalesReportStore interface {
+	Create(salesReport models.SalesReport) (models.SalesReport, error)
+	Search(query models.SearchCriteria) ([]models.SalesReport, error)
+}
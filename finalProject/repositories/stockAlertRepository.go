@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+// StockAlertStore holds the open low stock alerts, one per book
+type StockAlertStore interface {
+	Get(bookID int) (models.StockAlert, error)
+	Save(alert models.StockAlert) (models.StockAlert, error)
+	Delete(bookID int) error
+	Search(query models.SearchCriteria) ([]models.StockAlert, error)
+}
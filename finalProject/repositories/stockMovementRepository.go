@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+// StockMovementStore is the inventory ledger. Movements are never updated,
+// except to link the sales to the order they were made for.
+type StockMovementStore interface {
+	Append(movement models.StockMovement) (models.StockMovement, error)
+	Search(query models.SearchCriteria) ([]models.StockMovement, error)
+	AttachOrder(movementIDs []int, orderID int) error
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore.com/models"
+)
+
+type SupplierStore interface {
+	Create(supplier models.Supplier) (models.Supplier, error)
+	Get(id int) (models.Supplier, error)
+	Update(supplier models.Supplier) (models.Supplier, error)
+	Delete(id int, version int) error
+	Restore(id int) (models.Supplier, error)
+	// Purge removes for good the suppliers deleted before the given time
+	Purge(before time.Time) (int, error)
+	Search(query models.SearchCriteria) ([]models.Supplier, error)
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"bookstore.com/models"
+)
+
+type WishlistStore interface {
+	Get(customerID int) (models.Wishlist, error)
+	Save(wishlist models.Wishlist) (models.Wishlist, error)
+	Delete(customerID int) error
+	Search(query models.SearchCriteria) ([]models.Wishlist, error)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// SystemActor is recorded for the changes the server makes on its own, like
+// expiring payments, and for services used without an actor
+const SystemActor = "system"
+
+// AdminActor is recorded for the changes made with the admin key
+const AdminActor = "admin"
+
+// CustomerActor is recorded for the changes made by a signed in customer
+func CustomerActor(customerID int) string {
+	return fmt.Sprintf("customer:%d", customerID)
+}
+
+type AuditService struct {
+	auditRepo repositories.AuditStore
+}
+
+func NewAuditService(repo repositories.AuditStore) *AuditService {
+	return &AuditService{auditRepo: repo}
+}
+
+// Record appends an entry for a change of an entity, holding the fields that
+// differ between before and after; pass nil before a creation and nil after a
+// deletion. The version field is left out. A failure to record is logged
+// rather than failing the change, which is already made.
+func (s *AuditService) Record(actor string, entity string, entityID int, action string, before, after interface{}) {
+	if actor == "" {
+		actor = SystemActor
+	}
+	changes, err := diffFields(before, after)
+	if err != nil {
+		log.Printf("AuditService.Record: %s %d: %v", entity, entityID, err)
+		return
+	}
+	if action == models.AuditUpdate && len(changes) == 0 {
+		return
+	}
+	entry := models.AuditEntry{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Changes:   changes,
+	}
+	if _, err := s.auditRepo.Append(entry); err != nil {
+		log.Printf("AuditService.Record: %s %d: %v", entity, entityID, err)
+	}
+}
+
+func (s *AuditService) SearchEntries(query models.SearchCriteria) ([]models.AuditEntry, error) {
+	return s.auditRepo.Search(query)
+}
+
+// ExportEntries writes the matching entries as NDJSON, one entry per line
+func (s *AuditService) ExportEntries(w io.Writer, query models.SearchCriteria) error {
+	entries, err := s.auditRepo.Search(query)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffFields compares the JSON fields of two versions of an entity
+func diffFields(before, after interface{}) (map[string]models.FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for name, value := range beforeFields {
+		if other, exists := afterFields[name]; !exists || !bytes.Equal(value, other) {
+			changes[name] = models.FieldChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, exists := beforeFields[name]; !exists {
+			changes[name] = models.FieldChange{After: value}
+		}
+	}
+	delete(changes, "version")
+	return changes, nil
+}
+
+func jsonFields(entity interface{}) (map[string]json.RawMessage, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
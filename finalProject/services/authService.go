@@ -0,0 +1,365 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+const (
+	sessionLifetime       = 24 * time.Hour
+	passwordResetLifetime = 30 * time.Minute
+	maxLoginFailures      = 5
+	loginFailureWindow    = 15 * time.Minute
+	loginLockout          = 15 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts, try again later")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+)
+
+type AuthService struct {
+	authRepo     repositories.AuthStore
+	customerRepo repositories.CustomerStore
+	// notifier delivers the password reset tokens
+	notifier Notifier
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+// NewAuthService hands the password reset tokens to a LogNotifier, which
+// does not pass them on, until a notifier is set with WithNotifier
+func NewAuthService(authRepo repositories.AuthStore, customerRepo repositories.CustomerStore) *AuthService {
+	return &AuthService{
+		authRepo:     authRepo,
+		customerRepo: customerRepo,
+		notifier:     NewLogNotifier(),
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// WithNotifier sets the notifier the password reset tokens are delivered by
+func (s *AuthService) WithNotifier(notifier Notifier) *AuthService {
+	s.notifier = notifier
+	return s
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *AuthService) As(actor string) *AuthService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+// Register creates the customer and stores the hash of their password
+func (s *AuthService) Register(registration models.Registration) (models.Customer, error) {
+	if err := validatePassword(registration.Password); err != nil {
+		return models.Customer{}, err
+	}
+	hash, err := hashPassword(registration.Password)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	customer := registration.Customer
+	customer.Email = strings.TrimSpace(customer.Email)
+	if err := validateCustomer(customer); err != nil {
+		return models.Customer{}, err
+	}
+	customer.CreatedAt = time.Now()
+	customer, err = s.customerRepo.Create(customer)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	credential := models.Credential{
+		CustomerID:   customer.ID,
+		PasswordHash: hash,
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.authRepo.SaveCredential(credential); err != nil {
+		return models.Customer{}, err
+	}
+	s.auditLog.Record(CustomerActor(customer.ID), models.EntityCustomer, customer.ID, models.AuditCreate, nil, customer)
+	s.auditLog.Record(CustomerActor(customer.ID), models.EntityCredential, customer.ID, models.AuditCreate, nil, credentialEntry(credential))
+	return customer, nil
+}
+
+// Login checks the password and opens a new session, locking the email out
+// for a while after too many consecutive failures
+func (s *AuthService) Login(request models.LoginRequest) (models.AuthToken, error) {
+	now := time.Now()
+	attempt := s.authRepo.GetLoginAttempt(request.Email)
+	if now.Before(attempt.LockedUntil) {
+		return models.AuthToken{}, ErrTooManyAttempts
+	}
+
+	customer, err := s.authenticate(request.Email, request.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.recordFailure(attempt, now)
+		}
+		return models.AuthToken{}, err
+	}
+	s.authRepo.ClearLoginAttempt(request.Email)
+
+	token, digest, err := newToken()
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+	session, err := s.authRepo.CreateSession(models.Session{
+		ID:         digest,
+		CustomerID: customer.ID,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(sessionLifetime),
+	})
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+	return models.AuthToken{Token: token, CustomerID: customer.ID, ExpiresAt: session.ExpiresAt}, nil
+}
+
+// Logout ends the session identified by the bearer token
+func (s *AuthService) Logout(token string) error {
+	if err := s.authRepo.DeleteSession(tokenDigest(token)); err != nil {
+		return ErrInvalidSession
+	}
+	return nil
+}
+
+// Authenticate resolves a bearer token to its session
+func (s *AuthService) Authenticate(token string) (models.Session, error) {
+	session, err := s.authRepo.GetSession(tokenDigest(token))
+	if err != nil {
+		return models.Session{}, ErrInvalidSession
+	}
+	return session, nil
+}
+
+// ChangePassword replaces the password of the logged-in customer and closes
+// their other sessions
+func (s *AuthService) ChangePassword(token string, change models.PasswordChange) error {
+	session, err := s.Authenticate(token)
+	if err != nil {
+		return err
+	}
+	credential, err := s.authRepo.GetCredential(session.CustomerID)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+	ok, err := verifyPassword(change.CurrentPassword, credential.PasswordHash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	if err := s.setPassword(session.CustomerID, change.NewPassword); err != nil {
+		return err
+	}
+
+	s.authRepo.DeleteCustomerSessions(session.CustomerID)
+	_, err = s.authRepo.CreateSession(session)
+	return err
+}
+
+// ChangeEmail replaces the email the logged-in customer signs in with and
+// receives their password resets at, once their password is checked
+func (s *AuthService) ChangeEmail(token string, change models.EmailChange) (models.Customer, error) {
+	session, err := s.Authenticate(token)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	credential, err := s.authRepo.GetCredential(session.CustomerID)
+	if err != nil {
+		return models.Customer{}, ErrInvalidCredentials
+	}
+	ok, err := verifyPassword(change.CurrentPassword, credential.PasswordHash)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if !ok {
+		return models.Customer{}, ErrInvalidCredentials
+	}
+
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.customerRepo.Get(session.CustomerID)
+		if err != nil {
+			return models.Customer{}, err
+		}
+		customer := current
+		customer.Email = strings.TrimSpace(change.NewEmail)
+		if err := validateCustomer(customer); err != nil {
+			return models.Customer{}, err
+		}
+		updated, err := s.customerRepo.Update(customer)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Customer{}, err
+		}
+		s.auditLog.Record(s.actor, models.EntityCustomer, updated.ID, models.AuditUpdate, current, updated)
+		return updated, nil
+	}
+	return models.Customer{}, repositories.ErrVersionConflict
+}
+
+// RequestPasswordReset issues a one-time reset token and hands it to the
+// notifier. Unknown emails are not reported so the endpoint cannot be used
+// to probe for accounts, and neither are failed deliveries.
+func (s *AuthService) RequestPasswordReset(request models.PasswordResetRequest) error {
+	customer, err := s.customerRepo.FindByEmail(request.Email)
+	if err != nil {
+		return nil
+	}
+	if _, err := s.authRepo.GetCredential(customer.ID); err != nil {
+		return nil
+	}
+
+	token, digest, err := newToken()
+	if err != nil {
+		return err
+	}
+	reset := models.PasswordReset{
+		ID:         digest,
+		CustomerID: customer.ID,
+		ExpiresAt:  time.Now().Add(passwordResetLifetime),
+	}
+	if err := s.authRepo.CreatePasswordReset(reset); err != nil {
+		return err
+	}
+	s.auditLog.Record(s.actor, models.EntityPasswordReset, customer.ID, models.AuditCreate, nil, resetEntry(reset))
+
+	err = s.notifier.Notify(models.Notification{
+		Type:       models.NotificationPasswordReset,
+		CustomerID: customer.ID,
+		Token:      token,
+		Status:     models.NotificationSent,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("AuthService.RequestPasswordReset: customer %d through %s: %v", customer.ID, s.notifier.Name(), err)
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and closes
+// every session of the customer
+func (s *AuthService) ResetPassword(confirmation models.PasswordResetConfirmation) error {
+	if err := validatePassword(confirmation.NewPassword); err != nil {
+		return err
+	}
+	reset, err := s.authRepo.ConsumePasswordReset(tokenDigest(confirmation.Token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	s.auditLog.Record(s.actor, models.EntityPasswordReset, reset.CustomerID, models.AuditDelete, resetEntry(reset), nil)
+	if err := s.setPassword(reset.CustomerID, confirmation.NewPassword); err != nil {
+		return err
+	}
+	s.authRepo.ClearLoginAttempt(s.customerEmail(reset.CustomerID))
+	return s.authRepo.DeleteCustomerSessions(reset.CustomerID)
+}
+
+// authenticate checks the password of the customer with the email. Unknown
+// emails and customers without a password are checked against a dummy hash,
+// so the time taken does not tell them from wrong passwords.
+func (s *AuthService) authenticate(email, password string) (models.Customer, error) {
+	customer, err := s.customerRepo.FindByEmail(email)
+	if err != nil {
+		verifyPassword(password, dummyPasswordHash)
+		return models.Customer{}, ErrInvalidCredentials
+	}
+	credential, err := s.authRepo.GetCredential(customer.ID)
+	if err != nil {
+		verifyPassword(password, dummyPasswordHash)
+		return models.Customer{}, ErrInvalidCredentials
+	}
+	ok, err := verifyPassword(password, credential.PasswordHash)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if !ok {
+		return models.Customer{}, ErrInvalidCredentials
+	}
+	return customer, nil
+}
+
+func (s *AuthService) setPassword(customerID int, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	before, _ := s.authRepo.GetCredential(customerID)
+	credential := models.Credential{
+		CustomerID:   customerID,
+		PasswordHash: hash,
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.authRepo.SaveCredential(credential); err != nil {
+		return err
+	}
+	s.auditLog.Record(s.actor, models.EntityCredential, customerID, models.AuditUpdate, credentialEntry(before), credentialEntry(credential))
+	return nil
+}
+
+// auditedCredential is what the audit log keeps of a credential: when it
+// changed, never the password hash
+type auditedCredential struct {
+	CustomerID int       `json:"customer_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func credentialEntry(credential models.Credential) auditedCredential {
+	return auditedCredential{CustomerID: credential.CustomerID, UpdatedAt: credential.UpdatedAt}
+}
+
+// auditedReset is what the audit log keeps of a password reset, without the
+// digest of its token
+type auditedReset struct {
+	CustomerID int       `json:"customer_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func resetEntry(reset models.PasswordReset) auditedReset {
+	return auditedReset{CustomerID: reset.CustomerID, ExpiresAt: reset.ExpiresAt}
+}
+
+// recordFailure counts a failed login, unknown emails included so a lockout
+// does not tell which exist. The counters that ran out are dropped first so
+// that failures for made-up emails do not pile up.
+func (s *AuthService) recordFailure(attempt models.LoginAttempt, now time.Time) {
+	s.authRepo.PurgeLoginAttempts(now.Add(-loginFailureWindow))
+	if now.Sub(attempt.FirstFailure) > loginFailureWindow {
+		attempt.Failures = 0
+		attempt.FirstFailure = now
+	}
+	attempt.Failures++
+	if attempt.Failures >= maxLoginFailures {
+		attempt.LockedUntil = now.Add(loginLockout)
+		attempt.Failures = 0
+	}
+	s.authRepo.SaveLoginAttempt(attempt)
+}
+
+func (s *AuthService) customerEmail(customerID int) string {
+	customer, err := s.customerRepo.Get(customerID)
+	if err != nil {
+		return ""
+	}
+	return customer.Email
+}
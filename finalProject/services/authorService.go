@@ -1,20 +1,41 @@
 package services
 
 import (
+	"errors"
+
+	"bookstore.com/memory"
 	"bookstore.com/models"
 	"bookstore.com/repositories"
 )
 
 type AuthorService struct {
 	authorRepo repositories.AuthorStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
 }
 
 func NewAuthorService(repo repositories.AuthorStore) *AuthorService {
-	return &AuthorService{authorRepo: repo}
+	return &AuthorService{authorRepo: repo, auditLog: NewAuditService(memory.NewInMemoryAuditStore())}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *AuthorService) As(actor string) *AuthorService {
+	copied := *s
+	copied.actor = actor
+	return &copied
 }
 
 func (s *AuthorService) CreateAuthor(author models.Author) (models.Author, error) {
-	return s.authorRepo.Create(author)
+	if err := validateAuthor(author); err != nil {
+		return models.Author{}, err
+	}
+	created, err := s.authorRepo.Create(author)
+	if err == nil {
+		s.record(models.AuditCreate, created.ID, nil, created)
+	}
+	return created, err
 }
 
 func (s *AuthorService) GetAuthor(id int) (models.Author, error) {
@@ -22,13 +43,75 @@ func (s *AuthorService) GetAuthor(id int) (models.Author, error) {
 }
 
 func (s *AuthorService) UpdateAuthor(author models.Author) (models.Author, error) {
-	return s.authorRepo.Update(author)
+	if err := validateAuthor(author); err != nil {
+		return models.Author{}, err
+	}
+	before, _ := s.authorRepo.Get(author.ID)
+	updated, err := s.authorRepo.Update(author)
+	if err == nil {
+		s.record(models.AuditUpdate, updated.ID, before, updated)
+	}
+	return updated, err
+}
+
+// PatchAuthor applies a merge patch or JSON patch to the stored author. A
+// non-zero version must match the stored one; otherwise the patch is
+// reapplied if the author changes while it is being patched.
+func (s *AuthorService) PatchAuthor(id int, version int, patch []byte, contentType string) (models.Author, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.authorRepo.Get(id)
+		if err != nil {
+			return models.Author{}, err
+		}
+		if version != 0 && current.Version != version {
+			return models.Author{}, repositories.ErrVersionConflict
+		}
+		author, err := applyPatch(current, patch, contentType)
+		if err != nil {
+			return models.Author{}, err
+		}
+		author.ID, author.Version = current.ID, current.Version
+		if err := validateAuthor(author); err != nil {
+			return models.Author{}, err
+		}
+		updated, err := s.authorRepo.Update(author)
+		if errors.Is(err, repositories.ErrVersionConflict) && version == 0 {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+		}
+		return updated, err
+	}
+	return models.Author{}, repositories.ErrVersionConflict
 }
 
-func (s *AuthorService) DeleteAuthor(id int) error {
-	return s.authorRepo.Delete(id)
+// DeleteAuthor soft deletes an author. A non-zero version must match the
+// stored one, which the store checks as it deletes.
+func (s *AuthorService) DeleteAuthor(id int, version int) error {
+	before, err := s.authorRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	return nil
+}
+
+func (s *AuthorService) RestoreAuthor(id int) (models.Author, error) {
+	restored, err := s.authorRepo.Restore(id)
+	if err == nil {
+		s.record(models.AuditRestore, id, nil, restored)
+	}
+	return restored, err
 }
 
 func (s *AuthorService) SearchAuthors(query models.SearchCriteria) ([]models.Author, error) {
 	return s.authorRepo.Search(query)
 }
+
+func (s *AuthorService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityAuthor, id, action, before, after)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"time"
+
 	"bookstore.com/models"
 	"bookstore.com/repositories"
 )
@@ -14,6 +16,9 @@ func NewBookSaleService(repo repositories.BookSaleStore) *BookSaleService {
 }
 
 func (s *BookSaleService) CreateBookSale(BookSale models.BookSale) (models.BookSale, error) {
+	if BookSale.SoldAt.IsZero() {
+		BookSale.SoldAt = time.Now()
+	}
 	return s.BookSaleRepo.Create(BookSale)
 }
 
@@ -3,31 +3,98 @@ package services
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"bookstore.com/memory"
 	"bookstore.com/models"
 	"bookstore.com/repositories"
 )
 
+// Book sort keys of SearchBooks, the best rated or most reviewed books first
+const (
+	SortByRating      = "rating"
+	SortByRatingCount = "rating_count"
+)
+
+// bookOrders compares books for every sort key, the empty key ordering them
+// by ID
+var bookOrders = map[string]func(a, b models.Book) bool{
+	"": func(a, b models.Book) bool { return a.ID < b.ID },
+	SortByRating: func(a, b models.Book) bool {
+		if a.AverageRating != b.AverageRating {
+			return a.AverageRating > b.AverageRating
+		}
+		if a.RatingCount != b.RatingCount {
+			return a.RatingCount > b.RatingCount
+		}
+		return a.ID < b.ID
+	},
+	SortByRatingCount: func(a, b models.Book) bool {
+		if a.RatingCount != b.RatingCount {
+			return a.RatingCount > b.RatingCount
+		}
+		if a.AverageRating != b.AverageRating {
+			return a.AverageRating > b.AverageRating
+		}
+		return a.ID < b.ID
+	},
+}
+
 type BookService struct {
-	bookRepo repositories.BookStore
+	bookRepo  repositories.BookStore
+	inventory *InventoryService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
 }
 
 func NewBookService(bookRepo repositories.BookStore) *BookService {
 	return &BookService{
-		bookRepo: bookRepo,
+		bookRepo:  bookRepo,
+		inventory: NewInventoryService(memory.NewInMemoryStockMovementStore()),
+		auditLog:  NewAuditService(memory.NewInMemoryAuditStore()),
 	}
 }
 
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *BookService) As(actor string) *BookService {
+	copied := *s
+	copied.actor = actor
+	copied.inventory = s.inventory.As(actor)
+	return &copied
+}
+
 // CreateBook adds a new book to the store with validation and context propagation
 func (s *BookService) CreateBook(book models.Book) (models.Book, error) {
+	if err := validateBook(book); err != nil {
+		return models.Book{}, err
+	}
 
 	_, authorExists := NewAuthorService(memory.NewInMemoryAuthorStore()).GetAuthor(book.Author.ID)
 	fmt.Println(book.Author.ID)
 	if authorExists != nil {
 		return models.Book{}, errors.New("Author not found")
 	}
-	return s.bookRepo.Create(book)
+	return s.createBook(book)
+}
+
+// createBook stores a new book and records its stock as received
+func (s *BookService) createBook(book models.Book) (models.Book, error) {
+	book.AverageRating, book.RatingCount = 0, 0
+	if !s.byAdmin() {
+		book.CostPrice = 0
+	}
+	created, err := s.bookRepo.Create(book)
+	if err != nil {
+		return models.Book{}, err
+	}
+	s.record(models.AuditCreate, created.ID, nil, created)
+	if err := s.inventory.recordInitialStock(created); err != nil {
+		return models.Book{}, err
+	}
+	s.inventory.checkStockAlert(created.ID)
+	return created, nil
 }
 
 // GetBookByID retrieves a book by its ID, passing context to the repository
@@ -35,16 +102,125 @@ func (s *BookService) GetBookByID(id int) (models.Book, error) {
 	return s.bookRepo.Get(id)
 }
 
-// UpdateBook updates an existing book in the store
+// UpdateBook replaces a book, except for its stock which only changes with
+// stock movements and its rating which only changes with its reviews. A
+// non-zero version must match the stored one; otherwise the update is
+// retried if the stock changes in between.
 func (s *BookService) UpdateBook(book models.Book) (models.Book, error) {
+	if err := validateBook(book); err != nil {
+		return models.Book{}, err
+	}
+	version := book.Version
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.bookRepo.Get(book.ID)
+		if err != nil {
+			return models.Book{}, err
+		}
+		if version != 0 && current.Version != version {
+			return models.Book{}, repositories.ErrVersionConflict
+		}
+		book.Stock, book.Version = current.Stock, current.Version
+		book.AverageRating, book.RatingCount = current.AverageRating, current.RatingCount
+		if !s.byAdmin() {
+			book.CostPrice = current.CostPrice
+		}
+		updated, err := s.bookRepo.Update(book)
+		if errors.Is(err, repositories.ErrVersionConflict) && version == 0 {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, updated.ID, current, updated)
+			s.inventory.checkStockAlert(updated.ID)
+		}
+		return updated, err
+	}
+	return models.Book{}, repositories.ErrVersionConflict
+}
+
+// PatchBook applies a merge patch or JSON patch to the stored book, keeping
+// its stock and rating. A non-zero version must match the stored one;
+// otherwise the patch is reapplied if the book changes while it is being
+// patched.
+func (s *BookService) PatchBook(id int, version int, patch []byte, contentType string) (models.Book, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.bookRepo.Get(id)
+		if err != nil {
+			return models.Book{}, err
+		}
+		if version != 0 && current.Version != version {
+			return models.Book{}, repositories.ErrVersionConflict
+		}
+		book, err := applyPatch(current, patch, contentType)
+		if err != nil {
+			return models.Book{}, err
+		}
+		book.ID, book.Version, book.Stock = current.ID, current.Version, current.Stock
+		book.AverageRating, book.RatingCount = current.AverageRating, current.RatingCount
+		if !s.byAdmin() {
+			book.CostPrice = current.CostPrice
+		}
+		if err := validateBook(book); err != nil {
+			return models.Book{}, err
+		}
+		updated, err := s.bookRepo.Update(book)
+		if errors.Is(err, repositories.ErrVersionConflict) && version == 0 {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+			s.inventory.checkStockAlert(id)
+		}
+		return updated, err
+	}
+	return models.Book{}, repositories.ErrVersionConflict
+}
 
-	return s.bookRepo.Update(book)
+// DeleteBook soft deletes a book. A non-zero version must match the stored
+// one, which the store checks as it deletes.
+func (s *BookService) DeleteBook(id int, version int) error {
+	before, err := s.bookRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.bookRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	s.inventory.checkStockAlert(id)
+	return nil
+}
+
+func (s *BookService) RestoreBook(id int) (models.Book, error) {
+	restored, err := s.bookRepo.Restore(id)
+	if err == nil {
+		s.record(models.AuditRestore, id, nil, restored)
+		s.inventory.checkStockAlert(id)
+	}
+	return restored, err
+}
+
+// SearchBooks returns the books matching the query, ordered by ID or by one
+// of the book sort keys
+func (s *BookService) SearchBooks(query models.SearchCriteria, sortBy string) ([]models.Book, error) {
+	less, known := bookOrders[sortBy]
+	if !known {
+		return nil, invalid("unknown sort %q", sortBy)
+	}
+	books, err := s.bookRepo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(books, func(i, j int) bool { return less(books[i], books[j]) })
+	return books, nil
 }
 
-func (s *BookService) DeleteBook(id int) error {
-	return s.bookRepo.Delete(id)
+// byAdmin tells whether the actor is the admin, who alone sees and sets the
+// cost prices and counts stock by import; the others keep the stored cost
+// prices when writing books
+func (s *BookService) byAdmin() bool {
+	return s.actor == AdminActor
 }
 
-func (s *BookService) SearchBooks(query models.SearchCriteria) ([]models.Book, error) {
-	return s.bookRepo.Search(query)
+func (s *BookService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityBook, id, action, before, after)
 }
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// bookCSVColumns is the header written on export and understood on import.
+// Genres are separated by "|", published_at is RFC 3339 or YYYY-MM-DD.
+var bookCSVColumns = []string{"id", "title", "author_id", "author_first_name", "author_last_name", "genres", "published_at", "price", "cost_price", "stock", "weight_grams", "reorder_threshold"}
+
+// ImportBooks creates or updates a book for every CSV record or NDJSON line.
+// Rows with an id update that book, the others are created. The stock of an
+// updated book is only changed when the admin gives one in the row, by an
+// adjustment movement to the stock counted. Authors are matched by id, or by first and
+// last name and created when missing. In dry run mode every row is checked
+// but nothing is saved.
+func (s *BookService) ImportBooks(r io.Reader, format string, dryRun bool) (models.ImportReport, error) {
+	report := models.ImportReport{DryRun: dryRun, Results: []models.ImportRowResult{}}
+	authors, err := newAuthorResolver(memory.NewInMemoryAuthorStore(), dryRun, func(author models.Author) {
+		s.auditLog.Record(s.actor, models.EntityAuthor, author.ID, models.AuditCreate, nil, author)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	importRow := func(row int, book models.Book, stockGiven bool, err error) {
+		result := models.ImportRowResult{Row: row}
+		if err == nil {
+			result.Action, result.BookID, err = s.importBook(book, stockGiven, authors, dryRun)
+		}
+		if err != nil {
+			result.Action = "failed"
+			result.Error = err.Error()
+			report.Failed++
+		} else if result.Action == "created" {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		report.Rows++
+		report.Results = append(report.Results, result)
+	}
+
+	switch format {
+	case FormatCSV:
+		err = readBooksCSV(r, importRow)
+	case FormatNDJSON:
+		err = readBooksNDJSON(r, importRow)
+	default:
+		return report, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	report.AuthorsCreated = authors.created
+	return report, err
+}
+
+// ExportBooks writes the whole catalog ordered by id; the cost prices are
+// left empty unless the admin exports it
+func (s *BookService) ExportBooks(w io.Writer, format string) error {
+	if format != FormatCSV && format != FormatNDJSON {
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	books, err := s.bookRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return err
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	if !s.byAdmin() {
+		for i := range books {
+			books[i].CostPrice = 0
+		}
+	}
+
+	if format == FormatNDJSON {
+		encoder := json.NewEncoder(w)
+		for _, book := range books {
+			if err := encoder.Encode(book); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(bookCSVColumns); err != nil {
+		return err
+	}
+	for _, book := range books {
+		publishedAt := ""
+		if !book.PublishedAt.IsZero() {
+			publishedAt = book.PublishedAt.Format(time.RFC3339)
+		}
+		costPrice := ""
+		if book.CostPrice != 0 {
+			costPrice = strconv.FormatFloat(book.CostPrice, 'f', -1, 64)
+		}
+		err := writer.Write(spreadsheetSafe([]string{
+			strconv.Itoa(book.ID),
+			book.Title,
+			strconv.Itoa(book.Author.ID),
+			book.Author.FirstName,
+			book.Author.LastName,
+			strings.Join(book.Genres, "|"),
+			publishedAt,
+			strconv.FormatFloat(book.Price, 'f', -1, 64),
+			costPrice,
+			strconv.Itoa(book.Stock),
+			strconv.Itoa(book.WeightGrams),
+			strconv.Itoa(book.ReorderThreshold),
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// importBook creates or updates the book of a row; stockGiven tells whether
+// the row has a stock, which an update records as an adjustment
+func (s *BookService) importBook(book models.Book, stockGiven bool, authors *authorResolver, dryRun bool) (string, int, error) {
+	author, pending, err := authors.resolve(book.Author)
+	if err != nil {
+		return "", 0, err
+	}
+	book.Author = author
+	if pending {
+		err = validateBookDetails(book)
+	} else {
+		err = validateBook(book)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	action := "created"
+	if book.ID != 0 {
+		current, err := s.bookRepo.Get(book.ID)
+		if err != nil {
+			return "", 0, fmt.Errorf("book %d: %w", book.ID, err)
+		}
+		if stockGiven && book.Stock != current.Stock && !s.byAdmin() {
+			return "", 0, invalid("changing the stock of book %d from %d takes the admin key", book.ID, current.Stock)
+		}
+		action = "updated"
+	}
+	if dryRun {
+		return action, book.ID, nil
+	}
+
+	book.Version = 0
+	if action == "created" {
+		book, err = s.createBook(book)
+		return action, book.ID, err
+	}
+	counted := book.Stock
+	if book, err = s.UpdateBook(book); err != nil || !stockGiven || counted == book.Stock {
+		return action, book.ID, err
+	}
+	_, _, err = s.inventory.move(models.StockMovement{
+		BookID:   book.ID,
+		Type:     models.MovementAdjustment,
+		Quantity: counted - book.Stock,
+		Reason:   "import",
+	})
+	return action, book.ID, err
+}
+
+// authorResolver finds the authors referenced by imported rows and creates
+// the missing ones, remembering them for the following rows
+type authorResolver struct {
+	repo    repositories.AuthorStore
+	dryRun  bool
+	byID    map[int]models.Author
+	byName  map[string]models.Author
+	created int
+	// onCreate is called with every author created
+	onCreate func(models.Author)
+}
+
+func newAuthorResolver(repo repositories.AuthorStore, dryRun bool, onCreate func(models.Author)) (*authorResolver, error) {
+	authors, err := repo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	resolver := &authorResolver{
+		repo:     repo,
+		dryRun:   dryRun,
+		byID:     make(map[int]models.Author),
+		byName:   make(map[string]models.Author),
+		onCreate: onCreate,
+	}
+	for _, author := range authors {
+		resolver.byID[author.ID] = author
+		resolver.byName[authorKey(author)] = author
+	}
+	return resolver, nil
+}
+
+// resolve returns the stored author; pending is true in dry run mode for an
+// author that would have been created
+func (a *authorResolver) resolve(author models.Author) (models.Author, bool, error) {
+	if author.ID != 0 {
+		existing, exists := a.byID[author.ID]
+		if !exists {
+			return models.Author{}, false, fmt.Errorf("author %d not found", author.ID)
+		}
+		return existing, false, nil
+	}
+
+	if err := validateAuthor(author); err != nil {
+		return models.Author{}, false, err
+	}
+	if existing, exists := a.byName[authorKey(author)]; exists {
+		return existing, existing.ID == 0, nil
+	}
+
+	a.created++
+	if a.dryRun {
+		a.byName[authorKey(author)] = author
+		return author, true, nil
+	}
+	created, err := a.repo.Create(author)
+	if err != nil {
+		return models.Author{}, false, err
+	}
+	a.byID[created.ID] = created
+	a.byName[authorKey(created)] = created
+	a.onCreate(created)
+	return created, false, nil
+}
+
+func authorKey(author models.Author) string {
+	return strings.ToLower(strings.TrimSpace(author.FirstName)) + "\x00" + strings.ToLower(strings.TrimSpace(author.LastName))
+}
+
+// readBooksCSV calls fn for every record after the header row, telling
+// whether the record has a stock. Columns are matched by name, unknown
+// columns are ignored.
+func readBooksCSV(r io.Reader, fn func(row int, book models.Book, stockGiven bool, err error)) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading csv header: %v", ErrValidation, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"title", "price"} {
+		if _, exists := columns[required]; !exists {
+			return fmt.Errorf("%w: csv header lacks the %q column", ErrValidation, required)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			fn(parseErr.StartLine, models.Book{}, false, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		book, err := parseBookRecord(record, columns)
+		stockIndex, stockGiven := columns["stock"]
+		stockGiven = stockGiven && stockIndex < len(record) && strings.TrimSpace(record[stockIndex]) != ""
+		fn(line, book, stockGiven, err)
+	}
+}
+
+func parseBookRecord(record []string, columns map[string]int) (models.Book, error) {
+	field := func(name string) string {
+		if i, exists := columns[name]; exists && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var book models.Book
+	var err error
+	book.Title = spreadsheetText(field("title"))
+	book.Author.FirstName = spreadsheetText(field("author_first_name"))
+	book.Author.LastName = spreadsheetText(field("author_last_name"))
+	if value := spreadsheetText(field("genres")); value != "" {
+		for _, genre := range strings.Split(value, "|") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				book.Genres = append(book.Genres, genre)
+			}
+		}
+	}
+	if value := field("id"); value != "" {
+		if book.ID, err = strconv.Atoi(value); err != nil {
+			return book, fmt.Errorf("invalid id %q", value)
+		}
+	}
+	if value := field("author_id"); value != "" {
+		if book.Author.ID, err = strconv.Atoi(value); err != nil {
+			return book, fmt.Errorf("invalid author_id %q", value)
+		}
+	}
+	if value := field("published_at"); value != "" {
+		if book.PublishedAt, err = time.Parse(time.RFC3339, value); err != nil {
+			if book.PublishedAt, err = time.Parse(time.DateOnly, value); err != nil {
+				return book, fmt.Errorf("invalid published_at %q", value)
+			}
+		}
+	}
+	if book.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+		return book, fmt.Errorf("invalid price %q", field("price"))
+	}
+	if value := field("cost_price"); value != "" {
+		if book.CostPrice, err = strconv.ParseFloat(value, 64); err != nil {
+			return book, fmt.Errorf("invalid cost_price %q", value)
+		}
+	}
+	if value := field("stock"); value != "" {
+		if book.Stock, err = strconv.Atoi(value); err != nil {
+			return book, fmt.Errorf("invalid stock %q", value)
+		}
+	}
+	if value := field("weight_grams"); value != "" {
+		if book.WeightGrams, err = strconv.Atoi(value); err != nil {
+			return book, fmt.Errorf("invalid weight_grams %q", value)
+		}
+	}
+	if value := field("reorder_threshold"); value != "" {
+		if book.ReorderThreshold, err = strconv.Atoi(value); err != nil {
+			return book, fmt.Errorf("invalid reorder_threshold %q", value)
+		}
+	}
+	return book, nil
+}
+
+// readBooksNDJSON calls fn for every non-empty line holding a JSON book,
+// telling whether the line has a stock
+func readBooksNDJSON(r io.Reader, fn func(row int, book models.Book, stockGiven bool, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var book models.Book
+		var stock struct {
+			Stock *int `json:"stock"`
+		}
+		err := json.Unmarshal([]byte(text), &book)
+		if err == nil {
+			err = json.Unmarshal([]byte(text), &stock)
+		}
+		fn(line, book, stock.Stock != nil, err)
+	}
+	return scanner.Err()
+}
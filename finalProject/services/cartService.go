@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// cartLifetime is how long a cart is kept after its last change
+const cartLifetime = 7 * 24 * time.Hour
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrCartItemNotFound = errors.New("book is not in the cart")
+)
+
+type CartService struct {
+	cartRepo     repositories.CartStore
+	bookRepo     repositories.BookStore
+	customerRepo repositories.CustomerStore
+	orderService *OrderService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewCartService(repo repositories.CartStore, orderService *OrderService) *CartService {
+	return &CartService{
+		cartRepo:     repo,
+		bookRepo:     memory.NewInMemoryBookStore(),
+		customerRepo: memory.NewInMemoryCustomerStore(),
+		orderService: orderService,
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes, and the orders
+// checked out, in the audit log on behalf of actor
+func (s *CartService) As(actor string) *CartService {
+	copied := *s
+	copied.actor = actor
+	if s.orderService != nil {
+		copied.orderService = s.orderService.As(actor)
+	}
+	return &copied
+}
+
+// GetCart returns the cart of a customer priced against the current catalog;
+// a customer without a cart gets an empty one
+func (s *CartService) GetCart(customerID int) (models.Cart, error) {
+	cart, err := s.loadCart(customerID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+	return s.evaluate(cart), nil
+}
+
+// AddItem puts a book in the cart, adding to the quantity already there
+func (s *CartService) AddItem(customerID int, request models.CartItemRequest) (models.Cart, error) {
+	if request.Quantity <= 0 {
+		return models.Cart{}, invalid("invalid quantity %d", request.Quantity)
+	}
+	book, err := s.bookRepo.Get(request.BookID)
+	if err != nil {
+		return models.Cart{}, invalid("book %d not found", request.BookID)
+	}
+
+	return s.updateCart(customerID, func(cart *models.Cart) error {
+		for i := range cart.Items {
+			if cart.Items[i].BookID == book.ID {
+				cart.Items[i].Quantity += request.Quantity
+				return nil
+			}
+		}
+		cart.Items = append(cart.Items, models.CartItem{
+			BookID:     book.ID,
+			Quantity:   request.Quantity,
+			AddedPrice: book.Price,
+		})
+		return nil
+	})
+}
+
+// UpdateItem sets the quantity of a book in the cart; zero removes it
+func (s *CartService) UpdateItem(customerID int, bookID int, quantity int) (models.Cart, error) {
+	if quantity < 0 {
+		return models.Cart{}, invalid("invalid quantity %d", quantity)
+	}
+	if quantity == 0 {
+		return s.RemoveItem(customerID, bookID)
+	}
+	return s.updateCart(customerID, func(cart *models.Cart) error {
+		for i := range cart.Items {
+			if cart.Items[i].BookID == bookID {
+				cart.Items[i].Quantity = quantity
+				return nil
+			}
+		}
+		return ErrCartItemNotFound
+	})
+}
+
+func (s *CartService) RemoveItem(customerID int, bookID int) (models.Cart, error) {
+	return s.updateCart(customerID, func(cart *models.Cart) error {
+		for i := range cart.Items {
+			if cart.Items[i].BookID == bookID {
+				cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCartItemNotFound
+	})
+}
+
+// ClearCart empties the cart of a customer
+func (s *CartService) ClearCart(customerID int) error {
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return ErrCustomerNotFound
+	}
+	cart, err := s.cartRepo.Get(customerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.cartRepo.Delete(customerID, 0)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err == nil {
+		s.record(models.AuditDelete, customerID, cart, nil)
+	}
+	return err
+}
+
+// Checkout turns the cart into an order at the current prices, with the
+// promotion codes entered by the customer, and empties it. The cart is
+// claimed first by deleting the version read, so concurrent checkouts of the
+// same cart place a single order, and put back if the order fails. Stock is
+// only taken when the order is created, so a cart holding more copies than
+// available fails with ErrInsufficientStock.
+func (s *CartService) Checkout(customerID int, promotionCodes []string) (models.Order, error) {
+	cart, err := s.GetCart(customerID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if len(cart.Items) == 0 {
+		return models.Order{}, invalid("the cart is empty")
+	}
+	for _, warning := range cart.Warnings {
+		if warning.Code == models.CartWarningUnavailable {
+			return models.Order{}, invalid("book %d is no longer available", warning.BookID)
+		}
+	}
+
+	customer, err := s.customerRepo.Get(customerID)
+	if err != nil {
+		return models.Order{}, ErrCustomerNotFound
+	}
+	order := models.Order{
+		Customer:       customer,
+		Items:          make([]models.OrderItem, 0, len(cart.Items)),
+		PromotionCodes: promotionCodes,
+		CreatedAt:      time.Now(),
+		Status:         models.OrderStatusPending,
+	}
+	for _, item := range cart.Items {
+		order.Items = append(order.Items, models.OrderItem{
+			Book:     models.Book{ID: item.BookID},
+			Quantity: item.Quantity,
+		})
+	}
+
+	err = s.cartRepo.Delete(customerID, cart.Version)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Order{}, fmt.Errorf("%w: the cart was checked out or emptied meanwhile", repositories.ErrVersionConflict)
+	}
+	if err != nil {
+		return models.Order{}, err
+	}
+	s.record(models.AuditDelete, customerID, cart, nil)
+
+	order, err = s.orderService.CreateOrder(order)
+	if err != nil {
+		s.restoreCart(cart)
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+// restoreCart puts back a cart claimed by a checkout that failed, unless the
+// customer started a new one in the meantime
+func (s *CartService) restoreCart(cart models.Cart) {
+	cart.Version = 0
+	restored, err := s.cartRepo.Save(cart)
+	if err != nil {
+		log.Printf("CartService.restoreCart: customer %d: %v", cart.CustomerID, err)
+		return
+	}
+	s.record(models.AuditCreate, cart.CustomerID, nil, restored)
+}
+
+// mergeCart adds the items of the cart of a customer to the cart of another
+// one, and deletes the first cart
+func (s *CartService) mergeCart(fromID, toID int) error {
+	from, err := s.cartRepo.Get(fromID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.updateCart(toID, func(cart *models.Cart) error {
+		for _, item := range from.Items {
+			i := slices.IndexFunc(cart.Items, func(existing models.CartItem) bool { return existing.BookID == item.BookID })
+			if i < 0 {
+				cart.Items = append(cart.Items, models.CartItem{BookID: item.BookID, Quantity: item.Quantity, AddedPrice: item.AddedPrice})
+			} else {
+				cart.Items[i].Quantity += item.Quantity
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.cartRepo.Delete(fromID, from.Version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, fromID, from, nil)
+	return nil
+}
+
+// loadCart returns a copy of the stored cart, or a new empty one, that can
+// be changed without touching the store
+func (s *CartService) loadCart(customerID int) (models.Cart, error) {
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return models.Cart{}, ErrCustomerNotFound
+	}
+	cart, err := s.cartRepo.Get(customerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Cart{CustomerID: customerID, Items: []models.CartItem{}}, nil
+	}
+	if err != nil {
+		return models.Cart{}, err
+	}
+	cart.Items = slices.Clone(cart.Items)
+	return cart, nil
+}
+
+// updateCart applies change to the stored cart and saves it, starting over
+// if another request changed the cart in between
+func (s *CartService) updateCart(customerID int, change func(cart *models.Cart) error) (models.Cart, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		cart, err := s.loadCart(customerID)
+		if err != nil {
+			return models.Cart{}, err
+		}
+		before := cart
+		before.Items = slices.Clone(cart.Items)
+		if err := change(&cart); err != nil {
+			return models.Cart{}, err
+		}
+
+		now := time.Now()
+		if cart.CreatedAt.IsZero() {
+			cart.CreatedAt = now
+		}
+		cart.UpdatedAt = now
+		cart.ExpiresAt = now.Add(cartLifetime)
+		saved, err := s.cartRepo.Save(s.evaluate(cart))
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Cart{}, err
+		}
+		if before.Version == 0 {
+			s.record(models.AuditCreate, customerID, nil, saved)
+		} else {
+			s.record(models.AuditUpdate, customerID, before, saved)
+		}
+		return saved, nil
+	}
+	return models.Cart{}, repositories.ErrVersionConflict
+}
+
+// record logs a change of the cart of a customer, which carts are identified by
+func (s *CartService) record(action string, customerID int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityCart, customerID, action, before, after)
+}
+
+// evaluate prices the cart against the catalog and lists what changed since
+// the books were added or cannot be ordered
+func (s *CartService) evaluate(cart models.Cart) models.Cart {
+	cart.Subtotal = 0
+	cart.Warnings = nil
+	for i, item := range cart.Items {
+		book, err := s.bookRepo.Get(item.BookID)
+		if err != nil {
+			item.UnitPrice, item.LineTotal, item.Available = 0, 0, 0
+			cart.Items[i] = item
+			cart.Warnings = append(cart.Warnings, models.CartWarning{
+				BookID:  item.BookID,
+				Code:    models.CartWarningUnavailable,
+				Message: fmt.Sprintf("book %d is no longer available", item.BookID),
+			})
+			continue
+		}
+
+		item.Title = book.Title
+		item.UnitPrice = book.Price
+		item.LineTotal = roundPrice(book.Price * float64(item.Quantity))
+		item.Available = book.Stock
+		cart.Items[i] = item
+		cart.Subtotal += item.LineTotal
+
+		if book.Price != item.AddedPrice {
+			cart.Warnings = append(cart.Warnings, models.CartWarning{
+				BookID:  item.BookID,
+				Code:    models.CartWarningPriceChanged,
+				Message: fmt.Sprintf("price of %q changed from %.2f to %.2f", book.Title, item.AddedPrice, book.Price),
+			})
+		}
+		switch {
+		case book.Stock <= 0:
+			cart.Warnings = append(cart.Warnings, models.CartWarning{
+				BookID:  item.BookID,
+				Code:    models.CartWarningOutOfStock,
+				Message: fmt.Sprintf("%q is out of stock", book.Title),
+			})
+		case book.Stock < item.Quantity:
+			cart.Warnings = append(cart.Warnings, models.CartWarning{
+				BookID:  item.BookID,
+				Code:    models.CartWarningInsufficientStock,
+				Message: fmt.Sprintf("only %d copies of %q left", book.Stock, book.Title),
+			})
+		}
+	}
+	cart.Subtotal = roundPrice(cart.Subtotal)
+	return cart
+}
+
+// roundPrice rounds an amount to cents
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
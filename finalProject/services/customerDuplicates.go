@@ -0,0 +1,218 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+)
+
+// FindDuplicateCustomers lists the pairs of customers that are likely the
+// same person: their emails are the same once normalized, or their names
+// are nearly the same and they share a postal code. The customer of a pair
+// is the one registered first.
+func (s *CustomerService) FindDuplicateCustomers() ([]models.CustomerDuplicate, error) {
+	customers, err := s.customerRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+
+	duplicates := []models.CustomerDuplicate{}
+	for i, customer := range customers {
+		for _, other := range customers[i+1:] {
+			if reasons := duplicateReasons(customer, other); len(reasons) > 0 {
+				duplicates = append(duplicates, models.CustomerDuplicate{Customer: customer, Duplicate: other, Reasons: reasons})
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+// MergeCustomers moves the orders of a duplicate to the customer that
+// survives it, then deletes the duplicate and closes its sessions. Orders
+// placed for the duplicate while it is being merged are moved as well, and
+// so are its reviews, its wishlist and its cart, once it cannot change them
+// anymore. The survivor keeps its own review of a book both reviewed, the
+// other one is deleted.
+func (s *CustomerService) MergeCustomers(survivorID, duplicateID int) (models.CustomerMerge, error) {
+	if survivorID == duplicateID {
+		return models.CustomerMerge{}, invalid("a customer cannot be merged into itself")
+	}
+	survivor, err := s.customerRepo.Get(survivorID)
+	if err != nil {
+		return models.CustomerMerge{}, ErrCustomerNotFound
+	}
+	duplicate, err := s.customerRepo.Get(duplicateID)
+	if err != nil {
+		return models.CustomerMerge{}, invalid("duplicate customer %d not found", duplicateID)
+	}
+
+	merge := models.CustomerMerge{Customer: survivor, DuplicateID: duplicateID}
+	if merge.MovedOrders, err = s.moveOrders(duplicateID, survivor); err != nil {
+		return merge, err
+	}
+	if err := s.customerRepo.Delete(duplicateID, 0); err != nil {
+		return merge, err
+	}
+	s.record(models.AuditDelete, duplicateID, duplicate, nil)
+	if err := s.authRepo.DeleteCustomerSessions(duplicateID); err != nil {
+		return merge, fmt.Errorf("customer %d is merged but its sessions are not closed: %w", duplicateID, err)
+	}
+
+	late, err := s.moveOrders(duplicateID, survivor)
+	merge.MovedOrders = append(merge.MovedOrders, late...)
+	if err != nil {
+		return merge, fmt.Errorf("customer %d is merged but some of its orders are not moved: %w", duplicateID, err)
+	}
+	if merge.MovedReviews, merge.DeletedReviews, err = s.moveReviews(duplicateID, survivorID); err != nil {
+		return merge, fmt.Errorf("customer %d is merged but some of its reviews are not moved: %w", duplicateID, err)
+	}
+	if err := NewWishlistService(memory.NewInMemoryWishlistStore()).As(s.actor).mergeWishlist(duplicateID, survivorID); err != nil {
+		return merge, fmt.Errorf("customer %d is merged but its wishlist is not moved: %w", duplicateID, err)
+	}
+	// Merging carts places no order, so the cart service needs no order service
+	if err := NewCartService(memory.NewInMemoryCartStore(), nil).As(s.actor).mergeCart(duplicateID, survivorID); err != nil {
+		return merge, fmt.Errorf("customer %d is merged but its cart is not moved: %w", duplicateID, err)
+	}
+	return merge, nil
+}
+
+// moveOrders gives the orders of a customer, deleted ones included, to
+// another one and returns their IDs
+func (s *CustomerService) moveOrders(fromID int, to models.Customer) ([]int, error) {
+	orders, err := s.orderRepo.Reassign(fromID, to)
+	if err != nil {
+		return nil, err
+	}
+	moved := []int{}
+	for _, before := range orders {
+		after := before
+		after.Customer = to
+		after.Version++
+		s.auditLog.Record(s.actor, models.EntityOrder, before.ID, models.AuditUpdate, before, after)
+		moved = append(moved, before.ID)
+	}
+	return moved, nil
+}
+
+// moveReviews gives the reviews of a customer to another one and deletes
+// the ones of books the other customer reviewed too, returning the IDs of
+// both
+func (s *CustomerService) moveReviews(fromID, toID int) (moved []int, deleted []int, err error) {
+	reviews, err := s.reviewRepo.Reassign(fromID, toID)
+	if err != nil {
+		return nil, nil, err
+	}
+	moved = []int{}
+	for _, before := range reviews {
+		after := before
+		after.CustomerID = toID
+		after.Version++
+		s.auditLog.Record(s.actor, models.EntityReview, before.ID, models.AuditUpdate, before, after)
+		moved = append(moved, before.ID)
+	}
+
+	left, err := s.reviewRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"customer_id": fromID}})
+	if err != nil {
+		return moved, nil, err
+	}
+	deleted = []int{}
+	reviewService := NewReviewService(s.reviewRepo).As(s.actor)
+	for _, review := range left {
+		if err := reviewService.DeleteReview(review.BookID, review.ID); err != nil {
+			return moved, deleted, fmt.Errorf("review %d: %w", review.ID, err)
+		}
+		deleted = append(deleted, review.ID)
+	}
+	return moved, deleted, nil
+}
+
+// duplicateReasons tells why two customers look like the same person, if
+// they do
+func duplicateReasons(a, b models.Customer) []string {
+	var reasons []string
+	if email := canonicalEmail(a.Email); email != "" && email == canonicalEmail(b.Email) {
+		reasons = append(reasons, models.DuplicateSameEmail)
+	}
+	if postalCode := canonicalPostalCode(a.Address.PostalCode); postalCode != "" &&
+		postalCode == canonicalPostalCode(b.Address.PostalCode) && similarNames(a.Name, b.Name) {
+		reasons = append(reasons, models.DuplicateSimilarName)
+	}
+	return reasons
+}
+
+// canonicalEmail lowers an email and drops the +tag of its local part; Gmail
+// ignoring dots, they are dropped too for its addresses
+func canonicalEmail(email string) string {
+	local, domain, found := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !found {
+		return local
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+func canonicalPostalCode(postalCode string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+		return r
+	}, postalCode))
+}
+
+// similarNames reports whether two names differ by at most a typo or two,
+// whatever the order of their words, their case and punctuation. Short names
+// are allowed a single typo.
+func similarNames(a, b string) bool {
+	a, b = canonicalName(a), canonicalName(b)
+	if a == "" || b == "" {
+		return false
+	}
+	allowed := 2
+	if min(len([]rune(a)), len([]rune(b))) < 10 {
+		allowed = 1
+	}
+	return editDistance(a, b) <= allowed
+}
+
+// canonicalName lowers the words of a name and sorts them
+func canonicalName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"slices"
+	"sort"
+
+	"bookstore.com/models"
+)
+
+const (
+	// maxPerPage caps the page size asked for with per_page
+	maxPerPage = 100
+	// favouriteGenresLimit is the number of genres listed in a customer summary
+	favouriteGenresLimit = 3
+)
+
+// GetCustomerOrders returns a page of the orders of a customer, newest
+// first, optionally only those in a status
+func (s *CustomerService) GetCustomerOrders(customerID int, status string, page, perPage int) (models.OrderPage, error) {
+	if page < 1 || perPage < 1 || perPage > maxPerPage {
+		return models.OrderPage{}, invalid("page must be at least 1 and per_page between 1 and %d", maxPerPage)
+	}
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return models.OrderPage{}, ErrCustomerNotFound
+	}
+
+	query := models.SearchCriteria{Filters: map[string]interface{}{"customer_id": customerID}}
+	if status != "" {
+		query.Filters["status"] = status
+	}
+	orders, err := s.orderRepo.Search(query)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+	sortNewestFirst(orders)
+
+	first, last := pageBounds(page, perPage, len(orders))
+	return models.OrderPage{
+		Orders:     append([]models.Order{}, orders[first:last]...),
+		Pagination: models.Pagination{Page: page, PerPage: perPage, Total: len(orders)},
+	}, nil
+}
+
+// pageBounds returns the indexes of the first item of a page and of the
+// item after its last one, in a list of total items. Pages past the end are
+// empty; page-1 is checked before multiplying so that huge pages cannot
+// overflow.
+func pageBounds(page, perPage, total int) (first, last int) {
+	if page-1 > total/perPage {
+		return total, total
+	}
+	first = min((page-1)*perPage, total)
+	return first, min(first+perPage, total)
+}
+
+// GetCustomerSummary sums up the orders of a customer: how many were placed,
+// what they cost less the refunds, when and in which genres. Returned copies
+// do not count towards the favourite genres.
+func (s *CustomerService) GetCustomerSummary(customerID int) (models.CustomerSummary, error) {
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return models.CustomerSummary{}, ErrCustomerNotFound
+	}
+	orders, err := s.orderRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"customer_id": customerID}})
+	if err != nil {
+		return models.CustomerSummary{}, err
+	}
+
+	summary := models.CustomerSummary{CustomerID: customerID, FavouriteGenres: []models.GenreCount{}}
+	genres := make(map[string]int)
+	for _, order := range orders {
+		if slices.Contains(models.ReleasedOrderStatuses, order.Status) {
+			continue
+		}
+		summary.OrderCount++
+		summary.LifetimeSpend += order.TotalPrice
+		if summary.FirstOrderAt == nil || order.CreatedAt.Before(*summary.FirstOrderAt) {
+			summary.FirstOrderAt = &order.CreatedAt
+		}
+		if summary.LastOrderAt == nil || order.CreatedAt.After(*summary.LastOrderAt) {
+			summary.LastOrderAt = &order.CreatedAt
+		}
+		for _, item := range order.Items {
+			for _, genre := range item.Book.Genres {
+				genres[genre] += item.Quantity
+			}
+		}
+
+		returns, err := s.returnRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{
+			"order_id": order.ID,
+			"status":   models.ReturnApproved,
+		}})
+		if err != nil {
+			return models.CustomerSummary{}, err
+		}
+		for _, ret := range returns {
+			summary.LifetimeSpend -= ret.RefundAmount
+			for _, returned := range ret.Items {
+				item, found := findOrderItem(order, returned.OrderItemID)
+				if !found {
+					continue
+				}
+				for _, genre := range item.Book.Genres {
+					genres[genre] -= returned.Quantity
+				}
+			}
+		}
+	}
+	summary.LifetimeSpend = roundPrice(summary.LifetimeSpend)
+
+	for genre, quantity := range genres {
+		if quantity > 0 {
+			summary.FavouriteGenres = append(summary.FavouriteGenres, models.GenreCount{Genre: genre, Quantity: quantity})
+		}
+	}
+	sort.Slice(summary.FavouriteGenres, func(i, j int) bool {
+		a, b := summary.FavouriteGenres[i], summary.FavouriteGenres[j]
+		if a.Quantity != b.Quantity {
+			return a.Quantity > b.Quantity
+		}
+		return a.Genre < b.Genre
+	})
+	summary.FavouriteGenres = summary.FavouriteGenres[:min(favouriteGenresLimit, len(summary.FavouriteGenres))]
+	return summary, nil
+}
+
+// sortNewestFirst orders orders by creation time, the latest first
+func sortNewestFirst(orders []models.Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	"bookstore.com/models"
+)
+
+// Weights of the parts of the score of a book for a customer; they add up
+// to 1 so that the score stays between 0 and 1
+const (
+	genreWeight      = 0.45
+	authorWeight     = 0.3
+	priceWeight      = 0.15
+	popularityWeight = 0.1
+)
+
+// RecommendForCustomer suggests up to limit books in stock that a customer
+// did not buy yet, scored against the books of their orders: how much they
+// bought of the genres and the author of a book, how close its price is to
+// what they usually pay, and how well it sells. Customers without orders get
+// the best selling books.
+//
+// diversity, from 0 to 1, trades score for variety: each book is then picked
+// for its score less its resemblance to the books picked before it, books of
+// the same author being the most alike, then books sharing genres.
+func (s *RecommendationService) RecommendForCustomer(customerID int, limit int, diversity float64) ([]models.Recommendation, error) {
+	if limit < 1 || limit > maxRecommendations {
+		return nil, invalid("limit must be between 1 and %d", maxRecommendations)
+	}
+	if diversity < 0 || diversity > 1 {
+		return nil, invalid("diversity must be between 0 and 1")
+	}
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	orders, err := s.orderRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.bookRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+
+	profile := newReaderProfile()
+	sold := make(map[int]int)
+	for _, order := range orders {
+		if slices.Contains(models.ReleasedOrderStatuses, order.Status) {
+			continue
+		}
+		for _, item := range order.Items {
+			sold[item.Book.ID] += item.Quantity
+			if order.Customer.ID == customerID {
+				profile.buy(item.Book, item.Quantity)
+			}
+		}
+	}
+	bestSales := 0
+	for _, quantity := range sold {
+		bestSales = max(bestSales, quantity)
+	}
+
+	sortNewestBooksFirst(catalog)
+	candidates := make([]models.Recommendation, 0, len(catalog))
+	for _, book := range catalog {
+		if book.Stock <= 0 || profile.bought[book.ID] {
+			continue
+		}
+		popularity := 0.0
+		if bestSales > 0 {
+			popularity = float64(max(sold[book.ID], 0)) / float64(bestSales)
+		}
+		candidates = append(candidates, profile.score(book, popularity))
+	}
+	slices.SortStableFunc(candidates, func(a, b models.Recommendation) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	recommendations := []models.Recommendation{}
+	for len(recommendations) < limit && len(candidates) > 0 {
+		best, bestValue := 0, math.Inf(-1)
+		for i, candidate := range candidates {
+			resemblance := 0.0
+			for _, picked := range recommendations {
+				resemblance = max(resemblance, bookResemblance(candidate.Book, picked.Book))
+			}
+			if value := (1-diversity)*candidate.Score - diversity*resemblance; value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		recommendations = append(recommendations, candidates[best])
+		candidates = slices.Delete(candidates, best, best+1)
+	}
+	for i := range recommendations {
+		recommendations[i].Score = math.Round(recommendations[i].Score*1000) / 1000
+	}
+	return recommendations, nil
+}
+
+// readerProfile is what a customer bought: the copies by genre and author,
+// and the prices paid
+type readerProfile struct {
+	bought  map[int]bool
+	genres  map[string]int
+	authors map[int]int
+	copies  int
+	// spent and spentSquares sum the prices of the copies and their squares
+	spent, spentSquares float64
+}
+
+func newReaderProfile() *readerProfile {
+	return &readerProfile{
+		bought:  make(map[int]bool),
+		genres:  make(map[string]int),
+		authors: make(map[int]int),
+	}
+}
+
+func (p *readerProfile) buy(book models.Book, quantity int) {
+	p.bought[book.ID] = true
+	for _, genre := range distinctGenres(book) {
+		p.genres[genre] += quantity
+	}
+	if book.Author.ID != 0 {
+		p.authors[book.Author.ID] += quantity
+	}
+	p.copies += quantity
+	p.spent += book.Price * float64(quantity)
+	p.spentSquares += book.Price * book.Price * float64(quantity)
+}
+
+// score rates a book for the reader and gives the reason weighing the most
+func (p *readerProfile) score(book models.Book, popularity float64) models.Recommendation {
+	recommendation := models.Recommendation{Book: book, Reason: models.RecommendedPopular, Score: popularity}
+	if p.copies == 0 {
+		return recommendation
+	}
+
+	genre := 0.0
+	for _, name := range distinctGenres(book) {
+		genre += float64(p.genres[name]) / float64(p.copies)
+	}
+	author := float64(p.authors[book.Author.ID]) / float64(p.copies)
+	if book.Author.ID == 0 {
+		author = 0
+	}
+	mean := p.spent / float64(p.copies)
+	deviation := math.Sqrt(max(p.spentSquares/float64(p.copies)-mean*mean, 0))
+	band := max(deviation, mean/4, 1)
+	price := math.Exp(-math.Pow((book.Price-mean)/band, 2) / 2)
+
+	parts := []struct {
+		reason string
+		value  float64
+	}{
+		{models.RecommendedSameAuthor, authorWeight * author},
+		{models.RecommendedSameGenre, genreWeight * min(genre, 1)},
+		{models.RecommendedSimilarPrice, priceWeight * price},
+		{models.RecommendedPopular, popularityWeight * popularity},
+	}
+	recommendation.Score = 0
+	strongest := 0.0
+	for _, part := range parts {
+		recommendation.Score += part.value
+		if part.value > strongest {
+			recommendation.Reason, strongest = part.reason, part.value
+		}
+	}
+	return recommendation
+}
+
+// bookResemblance is 1 for books of the same author, otherwise the share of
+// their genres they have in common
+func bookResemblance(a, b models.Book) float64 {
+	if a.Author.ID != 0 && a.Author.ID == b.Author.ID {
+		return 1
+	}
+	genresA, genresB := distinctGenres(a), distinctGenres(b)
+	shared := 0
+	for _, genre := range genresA {
+		if slices.Contains(genresB, genre) {
+			shared++
+		}
+	}
+	if all := len(genresA) + len(genresB) - shared; all > 0 {
+		return float64(shared) / float64(all)
+	}
+	return 0
+}
+
+// distinctGenres lists the genres of a book once each, in lower case
+func distinctGenres(book models.Book) []string {
+	genres := make([]string, 0, len(book.Genres))
+	for _, genre := range book.Genres {
+		if genre = strings.ToLower(strings.TrimSpace(genre)); genre != "" && !slices.Contains(genres, genre) {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
@@ -1,34 +1,140 @@
 package services
 
 import (
+	"errors"
+	"time"
+
+	"bookstore.com/memory"
 	"bookstore.com/models"
 	"bookstore.com/repositories"
 )
 
 type CustomerService struct {
 	customerRepo repositories.CustomerStore
+	orderRepo    repositories.OrderStore
+	returnRepo   repositories.ReturnStore
+	reviewRepo   repositories.ReviewStore
+	authRepo     repositories.AuthStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
 }
 
 func NewCustomerService(repo repositories.CustomerStore) *CustomerService {
-	return &CustomerService{customerRepo: repo}
+	return &CustomerService{
+		customerRepo: repo,
+		orderRepo:    memory.NewInMemoryOrderStore(),
+		returnRepo:   memory.NewInMemoryReturnStore(),
+		reviewRepo:   memory.NewInMemoryReviewStore(),
+		authRepo:     memory.NewInMemoryAuthStore(),
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *CustomerService) As(actor string) *CustomerService {
+	copied := *s
+	copied.actor = actor
+	return &copied
 }
 
 func (s *CustomerService) CreateCustomer(customer models.Customer) (models.Customer, error) {
-	return s.customerRepo.Create(customer)
+	if err := validateCustomer(customer); err != nil {
+		return models.Customer{}, err
+	}
+	customer.CreatedAt = time.Now()
+	created, err := s.customerRepo.Create(customer)
+	if err == nil {
+		s.record(models.AuditCreate, created.ID, nil, created)
+	}
+	return created, err
 }
 
 func (s *CustomerService) GetCustomer(id int) (models.Customer, error) {
 	return s.customerRepo.Get(id)
 }
 
+// UpdateCustomer replaces a customer, keeping their email which logs them in
+// and only changes through AuthService.ChangeEmail
 func (s *CustomerService) UpdateCustomer(customer models.Customer) (models.Customer, error) {
-	return s.customerRepo.Update(customer)
+	before, err := s.customerRepo.Get(customer.ID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	// the creation time is not the client's to replace
+	customer.CreatedAt = before.CreatedAt
+	customer.Email = before.Email
+	if err := validateCustomer(customer); err != nil {
+		return models.Customer{}, err
+	}
+	updated, err := s.customerRepo.Update(customer)
+	if err == nil {
+		s.record(models.AuditUpdate, updated.ID, before, updated)
+	}
+	return updated, err
+}
+
+// PatchCustomer applies a merge patch or JSON patch to the stored customer,
+// keeping their email. A non-zero version must match the stored one;
+// otherwise the patch is reapplied if the customer changes while it is being
+// patched.
+func (s *CustomerService) PatchCustomer(id int, version int, patch []byte, contentType string) (models.Customer, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.customerRepo.Get(id)
+		if err != nil {
+			return models.Customer{}, err
+		}
+		if version != 0 && current.Version != version {
+			return models.Customer{}, repositories.ErrVersionConflict
+		}
+		customer, err := applyPatch(current, patch, contentType)
+		if err != nil {
+			return models.Customer{}, err
+		}
+		customer.ID, customer.Version, customer.Email = current.ID, current.Version, current.Email
+		customer.CreatedAt = current.CreatedAt
+		if err := validateCustomer(customer); err != nil {
+			return models.Customer{}, err
+		}
+		updated, err := s.customerRepo.Update(customer)
+		if errors.Is(err, repositories.ErrVersionConflict) && version == 0 {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+		}
+		return updated, err
+	}
+	return models.Customer{}, repositories.ErrVersionConflict
 }
 
-func (s *CustomerService) DeleteCustomer(id int) error {
-	return s.customerRepo.Delete(id)
+// DeleteCustomer soft deletes a customer. A non-zero version must match
+// the stored one, which the store checks as it deletes.
+func (s *CustomerService) DeleteCustomer(id int, version int) error {
+	before, err := s.customerRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.customerRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	return nil
+}
+
+func (s *CustomerService) RestoreCustomer(id int) (models.Customer, error) {
+	restored, err := s.customerRepo.Restore(id)
+	if err == nil {
+		s.record(models.AuditRestore, id, nil, restored)
+	}
+	return restored, err
 }
 
 func (s *CustomerService) SearchCustomers(query models.SearchCriteria) ([]models.Customer, error) {
 	return s.customerRepo.Search(query)
 }
+
+func (s *CustomerService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityCustomer, id, action, before, after)
+}
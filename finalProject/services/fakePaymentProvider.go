@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// Payment methods understood by FakePaymentProvider; any other non-empty
+// method is approved
+const (
+	FakeCardDeclined          = "fake_card_declined"
+	FakeCardInsufficientFunds = "fake_card_insufficient_funds"
+	FakeCardExpiring          = "fake_card_expiring"
+)
+
+// FakePaymentProvider is an in-process PaymentProvider for development and
+// tests. Its outcome only depends on the payment method, and references are
+// numbered in order: fake_auth_1, fake_auth_2, ... Its state is kept in
+// memory; Resume picks it up again from the stored payments.
+type FakePaymentProvider struct {
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+	operations     map[string]error
+	next           int
+	lifetime       time.Duration
+}
+
+type fakeAuthorization struct {
+	amount    float64
+	captured  float64
+	refunded  float64
+	voided    bool
+	expiresAt time.Time
+}
+
+// NewFakePaymentProvider creates a provider whose authorizations are valid
+// for lifetime; those made with FakeCardExpiring expire at once
+func NewFakePaymentProvider(lifetime time.Duration) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		authorizations: make(map[string]*fakeAuthorization),
+		operations:     make(map[string]error),
+		lifetime:       lifetime,
+	}
+}
+
+// Resume takes back the authorizations of the payments in store, made before
+// a restart, so they can still be captured, refunded or voided and new
+// references carry on from the last one
+func (p *FakePaymentProvider) Resume(store repositories.PaymentStore) error {
+	payments, err := store.Search(models.SearchCriteria{})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, payment := range payments {
+		number, found := strings.CutPrefix(payment.Reference, "fake_auth_")
+		if payment.Provider != p.Name() || !found {
+			continue
+		}
+		if n, err := strconv.Atoi(number); err == nil && n > p.next {
+			p.next = n
+		}
+		if _, known := p.authorizations[payment.Reference]; known {
+			continue
+		}
+		p.authorizations[payment.Reference] = &fakeAuthorization{
+			amount:    payment.Amount,
+			captured:  payment.CapturedAmount,
+			refunded:  payment.RefundedAmount,
+			voided:    payment.Status == models.PaymentVoided,
+			expiresAt: payment.ExpiresAt,
+		}
+	}
+	return nil
+}
+
+func (p *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakePaymentProvider) Authorize(amount float64, paymentMethod string) (PaymentAuthorization, error) {
+	switch paymentMethod {
+	case "":
+		return PaymentAuthorization{}, fmt.Errorf("%w: no payment method", ErrPaymentDeclined)
+	case FakeCardDeclined:
+		return PaymentAuthorization{}, fmt.Errorf("%w: card declined", ErrPaymentDeclined)
+	case FakeCardInsufficientFunds:
+		return PaymentAuthorization{}, fmt.Errorf("%w: insufficient funds", ErrPaymentDeclined)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.next++
+	authorization := &fakeAuthorization{amount: amount, expiresAt: time.Now().Add(p.lifetime)}
+	if paymentMethod == FakeCardExpiring {
+		authorization.expiresAt = time.Now()
+	}
+	reference := fmt.Sprintf("fake_auth_%d", p.next)
+	p.authorizations[reference] = authorization
+	return PaymentAuthorization{Reference: reference, ExpiresAt: authorization.expiresAt}, nil
+}
+
+func (p *FakePaymentProvider) Capture(reference string, amount float64, idempotencyKey string) error {
+	return p.once(idempotencyKey, func() error {
+		authorization, err := p.authorization(reference)
+		if err != nil {
+			return err
+		}
+		if time.Now().After(authorization.expiresAt) {
+			return ErrAuthorizationExpired
+		}
+		if authorization.captured > 0 {
+			return fmt.Errorf("%w: %s was already captured", ErrPaymentDeclined, reference)
+		}
+		if amount > authorization.amount {
+			return fmt.Errorf("%w: capture exceeds the authorized amount", ErrPaymentDeclined)
+		}
+		authorization.captured = amount
+		return nil
+	})
+}
+
+func (p *FakePaymentProvider) Refund(reference string, amount float64, idempotencyKey string) error {
+	return p.once(idempotencyKey, func() error {
+		authorization, err := p.authorization(reference)
+		if err != nil {
+			return err
+		}
+		if roundPrice(authorization.refunded+amount) > authorization.captured {
+			return fmt.Errorf("%w: refund exceeds the captured amount", ErrPaymentDeclined)
+		}
+		authorization.refunded += amount
+		return nil
+	})
+}
+
+func (p *FakePaymentProvider) Void(reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	authorization, err := p.authorization(reference)
+	if err != nil {
+		return err
+	}
+	if authorization.captured > 0 {
+		return fmt.Errorf("%w: %s was already captured", ErrPaymentDeclined, reference)
+	}
+	authorization.voided = true
+	return nil
+}
+
+// once runs an operation under the lock, or returns the outcome it had the
+// first time it ran with the same idempotency key
+func (p *FakePaymentProvider) once(idempotencyKey string, operation func() error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err, done := p.operations[idempotencyKey]; done && idempotencyKey != "" {
+		return err
+	}
+	err := operation()
+	if idempotencyKey != "" {
+		p.operations[idempotencyKey] = err
+	}
+	return err
+}
+
+// authorization looks up a live authorization; the caller holds the lock
+func (p *FakePaymentProvider) authorization(reference string) (*fakeAuthorization, error) {
+	authorization, exists := p.authorizations[reference]
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown authorization %s", ErrPaymentDeclined, reference)
+	}
+	if authorization.voided {
+		return nil, fmt.Errorf("%w: %s was voided", ErrPaymentDeclined, reference)
+	}
+	return authorization, nil
+}
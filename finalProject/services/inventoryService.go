@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+var ErrBookNotFound = errors.New("book not found")
+
+// InventoryService keeps the inventory ledger. Every change of the stock of
+// a book goes through it and is recorded as a movement.
+type InventoryService struct {
+	movementRepo repositories.StockMovementStore
+	bookRepo     repositories.BookStore
+	orderRepo    repositories.OrderStore
+	returnRepo   repositories.ReturnStore
+	wishlistRepo repositories.WishlistStore
+	// notificationRepo queues the back in stock notifications
+	notificationRepo repositories.NotificationStore
+	// stockAlerts raises and clears the low stock alerts as the stock moves
+	stockAlerts *StockAlertService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewInventoryService(repo repositories.StockMovementStore) *InventoryService {
+	return &InventoryService{
+		movementRepo:     repo,
+		bookRepo:         memory.NewInMemoryBookStore(),
+		orderRepo:        memory.NewInMemoryOrderStore(),
+		returnRepo:       memory.NewInMemoryReturnStore(),
+		wishlistRepo:     memory.NewInMemoryWishlistStore(),
+		notificationRepo: memory.NewInMemoryNotificationStore(),
+		stockAlerts:      NewStockAlertService(memory.NewInMemoryStockAlertStore()),
+		auditLog:         NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *InventoryService) As(actor string) *InventoryService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+// RecordMovement records copies received, damaged or counted by the
+// warehouse. The quantity is the change of the stock: positive for a
+// receipt, negative for damage, either way for an adjustment.
+func (s *InventoryService) RecordMovement(bookID int, request models.StockMovementRequest) (models.StockMovement, error) {
+	if err := validateStockMovement(request); err != nil {
+		return models.StockMovement{}, err
+	}
+	_, movement, err := s.move(models.StockMovement{
+		BookID:   bookID,
+		Type:     request.Type,
+		Quantity: request.Quantity,
+		Reason:   request.Reason,
+		UnitCost: request.UnitCost,
+	})
+	return movement, err
+}
+
+// GetStockHistory lists the movements of a book with the running stock and
+// checks them against the stock of the book and the orders holding it
+func (s *InventoryService) GetStockHistory(bookID int) (models.StockHistory, error) {
+	book, err := s.bookRepo.Get(bookID)
+	if err != nil {
+		return models.StockHistory{}, ErrBookNotFound
+	}
+	movements, err := s.movementRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"book_id": bookID}})
+	if err != nil {
+		return models.StockHistory{}, err
+	}
+
+	history := models.StockHistory{
+		BookID:        bookID,
+		Stock:         book.Stock,
+		Movements:     make([]models.StockHistoryEntry, 0, len(movements)),
+		Discrepancies: []models.StockDiscrepancy{},
+	}
+	recorded := make(map[int]int)
+	for _, movement := range movements {
+		history.LedgerStock += movement.Quantity
+		history.Movements = append(history.Movements, models.StockHistoryEntry{StockMovement: movement, Balance: history.LedgerStock})
+		if movement.OrderID != 0 {
+			recorded[movement.OrderID] += movement.Quantity
+		}
+	}
+
+	expected, err := s.expectedByOrder(bookID)
+	if err != nil {
+		return models.StockHistory{}, err
+	}
+	for orderID, status := range expected {
+		if recorded[orderID] != status.Expected {
+			status.Recorded = recorded[orderID]
+			history.Discrepancies = append(history.Discrepancies, status)
+		}
+	}
+	for orderID, quantity := range recorded {
+		if _, exists := expected[orderID]; !exists {
+			history.Discrepancies = append(history.Discrepancies, models.StockDiscrepancy{OrderID: orderID, Recorded: quantity})
+		}
+	}
+	slices.SortFunc(history.Discrepancies, func(a, b models.StockDiscrepancy) int { return a.OrderID - b.OrderID })
+	history.Reconciled = history.Stock == history.LedgerStock && len(history.Discrepancies) == 0
+	return history, nil
+}
+
+// expectedByOrder returns the stock change each order of a book should have
+// recorded: the copies it holds less the ones returned, none once released.
+// Deleted orders are counted, deleting an order does not restock it.
+func (s *InventoryService) expectedByOrder(bookID int) (map[int]models.StockDiscrepancy, error) {
+	orders, err := s.orderRepo.Search(models.SearchCriteria{IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[int]models.StockDiscrepancy)
+	for _, order := range orders {
+		ordered := 0
+		for _, item := range order.Items {
+			if item.Book.ID == bookID {
+				ordered += item.Quantity
+			}
+		}
+		if ordered == 0 {
+			continue
+		}
+		status := models.StockDiscrepancy{OrderID: order.ID, Status: order.Status}
+		if !slices.Contains(models.ReleasedOrderStatuses, order.Status) {
+			status.Expected = -ordered
+		}
+		returns, err := s.returnRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{
+			"order_id": order.ID,
+			"status":   models.ReturnApproved,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		for _, ret := range returns {
+			for _, item := range ret.Items {
+				if item.BookID == bookID {
+					status.Expected += item.Quantity
+				}
+			}
+		}
+		expected[order.ID] = status
+	}
+	return expected, nil
+}
+
+// OpenLedger records the stock of the books without any movement, kept from
+// before the ledger existed, as an opening adjustment
+func (s *InventoryService) OpenLedger() error {
+	books, err := s.bookRepo.Search(models.SearchCriteria{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	movements, err := s.movementRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return err
+	}
+	recorded := make(map[int]bool)
+	for _, movement := range movements {
+		recorded[movement.BookID] = true
+	}
+	for _, book := range books {
+		if book.Stock == 0 || recorded[book.ID] {
+			continue
+		}
+		_, err := s.movementRepo.Append(models.StockMovement{
+			BookID:    book.ID,
+			Type:      models.MovementAdjustment,
+			Quantity:  book.Stock,
+			Reason:    "opening balance",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordInitialStock records the stock a book is created with as a receipt
+func (s *InventoryService) recordInitialStock(book models.Book) error {
+	if book.Stock == 0 {
+		return nil
+	}
+	_, err := s.movementRepo.Append(models.StockMovement{
+		BookID:    book.ID,
+		Type:      models.MovementReceipt,
+		Quantity:  book.Stock,
+		Reason:    "initial stock",
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+func (s *InventoryService) attachOrder(movementIDs []int, orderID int) error {
+	return s.movementRepo.AttachOrder(movementIDs, orderID)
+}
+
+// averageCost weighs the cost price of the copies in stock with the unit
+// cost of the copies received; a book without a cost price takes the latter
+func averageCost(book models.Book, receipt models.StockMovement) float64 {
+	if book.CostPrice == 0 || book.Stock <= 0 {
+		return receipt.UnitCost
+	}
+	total := book.CostPrice*float64(book.Stock) + receipt.UnitCost*float64(receipt.Quantity)
+	return roundPrice(total / float64(book.Stock+receipt.Quantity))
+}
+
+// move adds the quantity of the movement to the stock of its book, refusing
+// to go below zero, and appends the movement to the ledger. The update is
+// conditional on the version read, so concurrent orders cannot both sell
+// the same copies. It returns the book as read before the change.
+func (s *InventoryService) move(movement models.StockMovement) (models.Book, models.StockMovement, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		book, err := s.bookRepo.Get(movement.BookID)
+		if err != nil {
+			return models.Book{}, models.StockMovement{}, fmt.Errorf("book %d: %w", movement.BookID, ErrBookNotFound)
+		}
+		if book.Stock+movement.Quantity < 0 {
+			return models.Book{}, models.StockMovement{}, fmt.Errorf("%w for book %d: %d left", ErrInsufficientStock, movement.BookID, book.Stock)
+		}
+
+		updated := book
+		updated.Stock += movement.Quantity
+		if movement.UnitCost > 0 {
+			updated.CostPrice = averageCost(book, movement)
+		}
+		updated, err = s.bookRepo.Update(updated)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Book{}, models.StockMovement{}, err
+		}
+		s.auditLog.Record(s.actor, models.EntityBook, book.ID, models.AuditUpdate, book, updated)
+		if book.Stock <= 0 && updated.Stock > 0 {
+			s.queueBackInStock(updated)
+		}
+		s.checkStockAlert(updated.ID)
+
+		movement.CreatedAt = time.Now()
+		movement, err = s.movementRepo.Append(movement)
+		return book, movement, err
+	}
+	return models.Book{}, models.StockMovement{}, fmt.Errorf("book %d: %w", movement.BookID, repositories.ErrVersionConflict)
+}
+
+// checkStockAlert raises or clears the low stock alert of a book after its
+// stock or threshold changed. Failures are only logged, the book has
+// changed already.
+func (s *InventoryService) checkStockAlert(bookID int) {
+	if err := s.stockAlerts.CheckBook(bookID, time.Now()); err != nil {
+		log.Printf("InventoryService.checkStockAlert: book %d: %v", bookID, err)
+	}
+}
+
+// queueBackInStock queues a back in stock notification for every customer
+// with the book in their wishlist. Failures are only logged, the stock has
+// changed already.
+func (s *InventoryService) queueBackInStock(book models.Book) {
+	wishlists, err := s.wishlistRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"book_id": book.ID}})
+	if err != nil {
+		log.Printf("InventoryService.queueBackInStock: book %d: %v", book.ID, err)
+		return
+	}
+	for _, wishlist := range wishlists {
+		_, err := s.notificationRepo.Create(models.Notification{
+			Type:       models.NotificationBackInStock,
+			CustomerID: wishlist.CustomerID,
+			BookID:     book.ID,
+			Title:      book.Title,
+			Stock:      book.Stock,
+			Status:     models.NotificationPending,
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			log.Printf("InventoryService.queueBackInStock: book %d, customer %d: %v", book.ID, wishlist.CustomerID, err)
+		}
+	}
+}
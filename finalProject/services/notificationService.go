@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// maxNotificationAttempts is how many times the delivery of a notification
+// is tried before it is marked as failed
+const maxNotificationAttempts = 5
+
+// NotificationService delivers the queued notifications through a Notifier
+type NotificationService struct {
+	notificationRepo repositories.NotificationStore
+	notifier         Notifier
+}
+
+func NewNotificationService(repo repositories.NotificationStore, notifier Notifier) *NotificationService {
+	return &NotificationService{
+		notificationRepo: repo,
+		notifier:         notifier,
+	}
+}
+
+// DeliverPending hands the pending notifications to the notifier, oldest
+// first, and marks them as sent
+func (s *NotificationService) DeliverPending(now time.Time) {
+	pending, err := s.notificationRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"status": models.NotificationPending}})
+	if err != nil {
+		log.Printf("NotificationService.DeliverPending: %v", err)
+		return
+	}
+	for _, notification := range pending {
+		s.deliver(notification, now)
+	}
+}
+
+// StartDispatcher delivers the pending notifications every interval,
+// holding gate while it does
+func (s *NotificationService) StartDispatcher(interval time.Duration, gate sync.Locker) {
+	go func() {
+		for now := range time.Tick(interval) {
+			gate.Lock()
+			s.DeliverPending(now)
+			gate.Unlock()
+		}
+	}()
+}
+
+func (s *NotificationService) deliver(notification models.Notification, now time.Time) {
+	notification.Attempts++
+	if err := s.notifier.Notify(notification); err != nil {
+		log.Printf("NotificationService.deliver: notification %d through %s: %v", notification.ID, s.notifier.Name(), err)
+		notification.LastError = err.Error()
+		if notification.Attempts >= maxNotificationAttempts {
+			notification.Status = models.NotificationFailed
+		}
+	} else {
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = ""
+	}
+	if _, err := s.notificationRepo.Update(notification); err != nil && !errors.Is(err, repositories.ErrVersionConflict) {
+		log.Printf("NotificationService.deliver: notification %d: %v", notification.ID, err)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+
+	"bookstore.com/models"
+)
+
+// Notifier delivers notifications to customers, by email, push or whatever
+// the deployment uses. A failed delivery is tried again later.
+type Notifier interface {
+	Name() string
+	Notify(notification models.Notification) error
+}
+
+// LogNotifier writes notifications to the server log, for local runs
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Name() string { return "log" }
+
+// Notify logs the notification; the token of a password reset is left out
+// since anyone reading the log could use it
+func (n *LogNotifier) Notify(notification models.Notification) error {
+	if notification.Type == models.NotificationPasswordReset {
+		log.Printf("Notification: %s for customer %d, token withheld from the log", notification.Type, notification.CustomerID)
+		return nil
+	}
+	log.Printf("Notification %d: %s for customer %d: book %d %q, %d in stock", notification.ID, notification.Type,
+		notification.CustomerID, notification.BookID, notification.Title, notification.Stock)
+	return nil
+}
+
+// FileNotifier appends notifications to a file, one JSON object per line
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Name() string { return "file " + n.path }
+
+func (n *FileNotifier) Notify(notification models.Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
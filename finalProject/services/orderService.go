@@ -2,46 +2,418 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
 
 	"bookstore.com/memory"
 	"bookstore.com/models"
 	"bookstore.com/repositories"
 )
 
+// maxUpdateRetries bounds how often a conditional update is retried when
+// another request updated the entity in between
+const maxUpdateRetries = 5
+
+var (
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrOrderNotFound     = errors.New("order not found")
+	// ErrOrderStatus is returned when an order is not in a status that
+	// allows the requested operation
+	ErrOrderStatus = errors.New("operation not allowed in the current order status")
+)
+
+// orderNotPlaced gives back the stock reserved for an order that failed
+var orderNotPlaced = models.StockMovement{Type: models.MovementCancellation, Reason: "order not placed"}
+
 type OrderService struct {
-	orderRepo repositories.OrderStore
+	orderRepo          repositories.OrderStore
+	bookRepo           repositories.BookStore
+	bookSaleRepo       repositories.BookSaleStore
+	coPurchaseRepo     repositories.CoPurchaseStore
+	promotionService   *PromotionService
+	taxCalculator      TaxCalculator
+	shippingCalculator ShippingCalculator
+	inventory          *InventoryService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
 }
 
+// NewOrderService prices orders without tax and with free shipping until
+// calculators are set with WithCalculators
 func NewOrderService(repo repositories.OrderStore) *OrderService {
-	return &OrderService{orderRepo: repo}
+	return &OrderService{
+		orderRepo:          repo,
+		bookRepo:           memory.NewInMemoryBookStore(),
+		bookSaleRepo:       memory.NewInMemoryBookSaleStore(),
+		coPurchaseRepo:     memory.NewInMemoryCoPurchaseStore(),
+		promotionService:   NewPromotionService(memory.NewInMemoryPromotionStore()),
+		taxCalculator:      &TaxTable{},
+		shippingCalculator: &ShippingZones{},
+		inventory:          NewInventoryService(memory.NewInMemoryStockMovementStore()),
+		auditLog:           NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *OrderService) As(actor string) *OrderService {
+	copied := *s
+	copied.actor = actor
+	copied.inventory = s.inventory.As(actor)
+	return &copied
 }
 
+// WithCalculators sets the tax and shipping calculators used to price orders
+func (s *OrderService) WithCalculators(tax TaxCalculator, shipping ShippingCalculator) *OrderService {
+	s.taxCalculator = tax
+	s.shippingCalculator = shipping
+	return s
+}
+
+// CreateOrder checks the customer, takes the ordered quantities out of stock,
+// prices the order and stores it; the stock and the promotion redemptions
+// are given back if any step fails
 func (s *OrderService) CreateOrder(order models.Order) (models.Order, error) {
-	_, customerExists := NewCustomerService(memory.NewInMemoryCustomerStore()).GetCustomer(order.Customer.ID)
-	for _, item := range order.Items {
-		_, bookFound := NewOrderItemService(memory.NewInMemoryOrderItemStore()).CreateOrderItem(item)
+	if err := validateOrder(order); err != nil {
+		return models.Order{}, err
+	}
+	customer, customerExists := NewCustomerService(memory.NewInMemoryCustomerStore()).GetCustomer(order.Customer.ID)
+	if customerExists != nil {
+		return models.Order{}, errors.New("customer not found")
+	}
+	order.Customer = customer
+	order.Status = models.OrderStatusPending
+	// the creation time dates the order in promotion windows and reports,
+	// so it is never the client's to set
+	order.CreatedAt = time.Now()
+
+	items, sales, err := s.reserveStock(order.Items)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.Items = items
+	order, err = s.priceOrder(order)
+	if err != nil {
+		s.releaseStock(items, orderNotPlaced)
+		return models.Order{}, err
+	}
+	redemptions, err := s.promotionService.Redeem(order.Customer.ID, order.Discounts)
+	if err != nil {
+		s.releaseStock(items, orderNotPlaced)
+		return models.Order{}, err
+	}
+
+	for i, item := range items {
+		createdItem, bookFound := NewOrderItemService(memory.NewInMemoryOrderItemStore()).CreateOrderItem(item)
 		if bookFound != nil {
+			s.releaseStock(items, orderNotPlaced)
+			s.promotionService.CancelRedemptions(redemptions)
 			return models.Order{}, errors.New("Some Books does not exist")
 		}
+		items[i] = createdItem
 	}
-	if customerExists != nil {
-		return models.Order{}, errors.New("customer not found")
+	order.Items = items
+
+	createdOrder, err := s.orderRepo.Create(order)
+	if err != nil {
+		s.releaseStock(items, orderNotPlaced)
+		s.promotionService.CancelRedemptions(redemptions)
+		return models.Order{}, err
+	}
+	s.record(models.AuditCreate, createdOrder.ID, nil, createdOrder)
+	if err := s.inventory.attachOrder(sales, createdOrder.ID); err != nil {
+		return models.Order{}, err
+	}
+	if err := s.recordSales(createdOrder, 1, createdOrder.CreatedAt); err != nil {
+		return models.Order{}, err
+	}
+	if err := s.recordCoPurchases(createdOrder); err != nil {
+		return models.Order{}, err
 	}
-	return s.orderRepo.Create(order)
+	if err := s.promotionService.AttachOrder(redemptions, createdOrder.ID); err != nil {
+		return models.Order{}, err
+	}
+	return createdOrder, nil
+}
+
+// priceOrder totals the items at the price of the books they hold, applies
+// the promotions the order gets, then adds shipping and the taxes on the
+// discounted items, both based on the customer address
+func (s *OrderService) priceOrder(order models.Order) (models.Order, error) {
+	order.PromotionCodes = normalizeCodes(order.PromotionCodes)
+	order.Subtotal = 0
+	for _, item := range order.Items {
+		order.Subtotal += item.Book.Price * float64(item.Quantity)
+	}
+	order.Subtotal = roundPrice(order.Subtotal)
+
+	discounts, err := s.promotionService.Discounts(order, time.Now())
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.Discounts = discounts
+	order.DiscountTotal = 0
+	for _, discount := range discounts {
+		order.DiscountTotal += discount.Amount
+	}
+	order.DiscountTotal = roundPrice(order.DiscountTotal)
+
+	order.Shipping, err = s.shippingCalculator.Shipping(order)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.TaxLines, err = s.taxCalculator.Tax(order)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.TaxTotal = 0
+	for _, line := range order.TaxLines {
+		order.TaxTotal += line.Amount
+	}
+	order.TaxTotal = roundPrice(order.TaxTotal)
+
+	order.TotalPrice = roundPrice(order.Subtotal - order.DiscountTotal + order.Shipping.Amount + order.TaxTotal)
+	return order, nil
 }
 
 func (s *OrderService) GetOrder(id int) (models.Order, error) {
 	return s.orderRepo.Get(id)
 }
 
+// UpdateOrder replaces an order, except for what was set when it was placed,
+// see keepPlacedOrder
 func (s *OrderService) UpdateOrder(order models.Order) (models.Order, error) {
-	return s.orderRepo.Update(order)
+	if err := validateOrder(order); err != nil {
+		return models.Order{}, err
+	}
+	current, err := s.orderRepo.Get(order.ID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err := keepPlacedOrder(&order, current); err != nil {
+		return models.Order{}, err
+	}
+	updated, err := s.orderRepo.Update(order)
+	if err == nil {
+		s.record(models.AuditUpdate, updated.ID, current, updated)
+	}
+	return updated, err
 }
 
-func (s *OrderService) DeleteOrder(id int) error {
-	return s.orderRepo.Delete(id)
+// PatchOrder applies a merge patch or JSON patch to the stored order. A
+// non-zero version must match the stored one; otherwise the patch is
+// reapplied if the order changes while it is being patched.
+func (s *OrderService) PatchOrder(id int, version int, patch []byte, contentType string) (models.Order, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.orderRepo.Get(id)
+		if err != nil {
+			return models.Order{}, err
+		}
+		if version != 0 && current.Version != version {
+			return models.Order{}, repositories.ErrVersionConflict
+		}
+		order, err := applyPatch(current, patch, contentType)
+		if err != nil {
+			return models.Order{}, err
+		}
+		order.ID, order.Version = current.ID, current.Version
+		if err := validateOrder(order); err != nil {
+			return models.Order{}, err
+		}
+		if err := keepPlacedOrder(&order, current); err != nil {
+			return models.Order{}, err
+		}
+		updated, err := s.orderRepo.Update(order)
+		if errors.Is(err, repositories.ErrVersionConflict) && version == 0 {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+		}
+		return updated, err
+	}
+	return models.Order{}, repositories.ErrVersionConflict
+}
+
+// keepPlacedOrder puts back on an updated order what the server set when
+// the order was placed: the customer, the items with the books as ordered,
+// the prices, the status, which only changes with the payment, and the
+// creation date. Their stock is reserved and their total may be paid
+// already, so an update changing the items is refused.
+func keepPlacedOrder(order *models.Order, current models.Order) error {
+	if !slices.EqualFunc(order.Items, current.Items, func(a, b models.OrderItem) bool {
+		return a.Book.ID == b.Book.ID && a.Quantity == b.Quantity
+	}) {
+		return invalid("the items of order %d cannot be changed, place a new order instead", current.ID)
+	}
+	order.Customer = current.Customer
+	order.Items = current.Items
+	order.PromotionCodes = current.PromotionCodes
+	order.Subtotal = current.Subtotal
+	order.Discounts = current.Discounts
+	order.DiscountTotal = current.DiscountTotal
+	order.Shipping = current.Shipping
+	order.TaxLines = current.TaxLines
+	order.TaxTotal = current.TaxTotal
+	order.TotalPrice = current.TotalPrice
+	order.CreatedAt = current.CreatedAt
+	order.Status = current.Status
+	return nil
+}
+
+// DeleteOrder soft deletes an order. A non-zero version must match the
+// stored one, which the store checks as it deletes. A deleted order no
+// longer counts as a co-purchase.
+func (s *OrderService) DeleteOrder(id int, version int) error {
+	before, err := s.orderRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.orderRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	if !slices.Contains(models.ReleasedOrderStatuses, before.Status) {
+		if err := s.removeCoPurchases(before); err != nil {
+			log.Printf("OrderService.DeleteOrder: order %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// RestoreOrder undeletes an order, which counts as a co-purchase again
+// unless its copies went back to stock. Restoring an order that is not
+// deleted changes nothing.
+func (s *OrderService) RestoreOrder(id int) (models.Order, error) {
+	_, err := s.orderRepo.Get(id)
+	wasDeleted := err != nil
+	restored, err := s.orderRepo.Restore(id)
+	if err != nil {
+		return restored, err
+	}
+	s.record(models.AuditRestore, id, nil, restored)
+	if wasDeleted && !slices.Contains(models.ReleasedOrderStatuses, restored.Status) {
+		if err := s.recordCoPurchases(restored); err != nil {
+			log.Printf("OrderService.RestoreOrder: order %d: %v", id, err)
+		}
+	}
+	return restored, nil
 }
 
 func (s *OrderService) SearchOrders(query models.SearchCriteria) ([]models.Order, error) {
 	return s.orderRepo.Search(query)
 }
+
+// transitionStatus moves an order to a new status if it is in one of the
+// from statuses, retrying when the order changes concurrently
+func (s *OrderService) transitionStatus(orderID int, from []string, to string) (models.Order, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		order, err := s.orderRepo.Get(orderID)
+		if err != nil {
+			return models.Order{}, ErrOrderNotFound
+		}
+		if !slices.Contains(from, order.Status) {
+			return models.Order{}, fmt.Errorf("%w: order %d is %s", ErrOrderStatus, orderID, order.Status)
+		}
+		before := order
+		order.Status = to
+		updated, err := s.orderRepo.Update(order)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, orderID, before, updated)
+		}
+		return updated, err
+	}
+	return models.Order{}, repositories.ErrVersionConflict
+}
+
+// reserveStock records the sale of every ordered book and returns the items
+// with the book as it was when ordered, and the sales to attach to the order
+// once placed. Nothing stays reserved on error.
+func (s *OrderService) reserveStock(items []models.OrderItem) ([]models.OrderItem, []int, error) {
+	reserved := make([]models.OrderItem, 0, len(items))
+	sales := make([]int, 0, len(items))
+	for _, item := range items {
+		book, sale, err := s.inventory.move(models.StockMovement{
+			BookID:   item.Book.ID,
+			Type:     models.MovementSale,
+			Quantity: -item.Quantity,
+		})
+		if err != nil {
+			s.releaseStock(reserved, orderNotPlaced)
+			return nil, nil, err
+		}
+		item.Book = book
+		reserved = append(reserved, item)
+		sales = append(sales, sale.ID)
+	}
+	return reserved, sales, nil
+}
+
+// releaseStock puts the items back in stock, each with a movement like the
+// given one
+func (s *OrderService) releaseStock(items []models.OrderItem, movement models.StockMovement) {
+	for _, item := range items {
+		movement.BookID, movement.Quantity = item.Book.ID, item.Quantity
+		if _, _, err := s.inventory.move(movement); err != nil {
+			log.Printf("OrderService.releaseStock: book %d: %v", item.Book.ID, err)
+		}
+	}
+}
+
+// restockCancelled puts the items of an order whose payment failed, expired
+// or was voided back in stock, uncounts its co-purchases and frees the uses
+// of its promotions
+func (s *OrderService) restockCancelled(order models.Order, reason string) {
+	s.releaseStock(order.Items, models.StockMovement{Type: models.MovementCancellation, OrderID: order.ID, Reason: reason})
+	if err := s.recordSales(order, -1, time.Now()); err != nil {
+		log.Printf("OrderService.restockCancelled: order %d: %v", order.ID, err)
+	}
+	if err := s.removeCoPurchases(order); err != nil {
+		log.Printf("OrderService.restockCancelled: order %d: %v", order.ID, err)
+	}
+	if err := s.promotionService.CancelOrderRedemptions(order.ID); err != nil {
+		log.Printf("OrderService.restockCancelled: order %d: %v", order.ID, err)
+	}
+}
+
+// recordSales records the items of an order as book sales, or as negative
+// ones with a sign of -1 when the order is cancelled
+func (s *OrderService) recordSales(order models.Order, sign int, soldAt time.Time) error {
+	for _, item := range order.Items {
+		sale := models.BookSale{Book: item.Book, Quantity: sign * item.Quantity, OrderID: order.ID, SoldAt: soldAt}
+		if _, err := s.bookSaleRepo.Create(sale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordCoPurchases counts the books of an order as bought together, for
+// the recommendations
+func (s *OrderService) recordCoPurchases(order models.Order) error {
+	return s.coPurchaseRepo.Record(orderBookIDs(order))
+}
+
+// removeCoPurchases uncounts the books of an order that is released or
+// deleted
+func (s *OrderService) removeCoPurchases(order models.Order) error {
+	return s.coPurchaseRepo.Remove(orderBookIDs(order))
+}
+
+func orderBookIDs(order models.Order) []int {
+	bookIDs := make([]int, 0, len(order.Items))
+	for _, item := range order.Items {
+		bookIDs = append(bookIDs, item.Book.ID)
+	}
+	return bookIDs
+}
+
+func (s *OrderService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityOrder, id, action, before, after)
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+	ErrInvalidPatch     = errors.New("invalid patch")
+	ErrPatchTestFailed  = errors.New("patch test operation failed")
+)
+
+// applyPatch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// to the JSON form of current and decodes the result into a fresh T, so
+// fields removed by the patch come back as zero values. Plain
+// application/json bodies are treated as merge patches.
+func applyPatch[T any](current T, patch []byte, contentType string) (T, error) {
+	var patched T
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return patched, fmt.Errorf("%w: %s", ErrUnsupportedPatch, contentType)
+	}
+
+	var document interface{}
+	data, err := json.Marshal(current)
+	if err != nil {
+		return patched, err
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return patched, err
+	}
+
+	switch mediaType {
+	case MergePatchContentType, "application/json", "":
+		var mergePatch interface{}
+		if err := json.Unmarshal(patch, &mergePatch); err != nil {
+			return patched, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		document = applyMergePatch(document, mergePatch)
+	case JSONPatchContentType:
+		var operations []patchOperation
+		if err := json.Unmarshal(patch, &operations); err != nil {
+			return patched, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		document, err = applyJSONPatch(document, operations)
+		if err != nil {
+			return patched, err
+		}
+	default:
+		return patched, fmt.Errorf("%w: %s", ErrUnsupportedPatch, mediaType)
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return patched, err
+	}
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return patched, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return patched, nil
+}
+
+// applyMergePatch follows the MergePatch pseudo code of RFC 7396 section 2
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = applyMergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies the operations in order; the document is left
+// untouched by the caller if any of them fails
+func applyJSONPatch(document interface{}, operations []patchOperation) (interface{}, error) {
+	for i, operation := range operations {
+		path, err := parsePointer(operation.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d: %s needs a value", ErrInvalidPatch, i, operation.Op)
+			}
+			var value interface{}
+			if err := json.Unmarshal(*operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+			switch operation.Op {
+			case "add":
+				document, err = setPointer(document, path, value, true)
+			case "replace":
+				if _, err = getPointer(document, path); err == nil {
+					document, err = setPointer(document, path, value, false)
+				}
+			case "test":
+				var actual interface{}
+				actual, err = getPointer(document, path)
+				if err == nil && !reflect.DeepEqual(actual, value) {
+					err = fmt.Errorf("%w: %s", ErrPatchTestFailed, operation.Path)
+				}
+			}
+		case "remove":
+			document, err = removePointer(document, path)
+		case "move", "copy":
+			var from []string
+			from, err = parsePointer(operation.From)
+			if err != nil {
+				break
+			}
+			if operation.Op == "move" && len(path) > len(from) && strings.HasPrefix(operation.Path, operation.From+"/") {
+				err = fmt.Errorf("%w: cannot move %s into itself", ErrInvalidPatch, operation.From)
+				break
+			}
+			var value interface{}
+			value, err = getPointer(document, from)
+			if err != nil {
+				break
+			}
+			if operation.Op == "move" {
+				document, err = removePointer(document, from)
+			} else {
+				value, err = deepCopy(value)
+			}
+			if err == nil {
+				document, err = setPointer(document, path, value, true)
+			}
+		default:
+			err = fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, operation.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return document, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getPointer(document interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := document.(type) {
+		case map[string]interface{}:
+			value, exists := container[token]
+			if !exists {
+				return nil, fmt.Errorf("%w: no member %q", ErrInvalidPatch, token)
+			}
+			document = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			document = container[index]
+		default:
+			return nil, fmt.Errorf("%w: cannot index into a scalar with %q", ErrInvalidPatch, token)
+		}
+	}
+	return document, nil
+}
+
+// setPointer stores value at path and returns the updated document. insert
+// selects the add semantics for arrays: the value is inserted before the
+// index ("-" appends) instead of replacing the element.
+func setPointer(document interface{}, path []string, value interface{}, insert bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+
+	switch container := document.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			container[token] = value
+			return container, nil
+		}
+		child, exists := container[token]
+		if !exists {
+			return nil, fmt.Errorf("%w: no member %q", ErrInvalidPatch, token)
+		}
+		child, err := setPointer(child, rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+		return container, nil
+	case []interface{}:
+		if len(rest) == 0 && insert {
+			index, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := setPointer(container[index], rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+		return container, nil
+	default:
+		return nil, fmt.Errorf("%w: cannot index into a scalar with %q", ErrInvalidPatch, token)
+	}
+}
+
+func removePointer(document interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+	token, rest := path[0], path[1:]
+
+	switch container := document.(type) {
+	case map[string]interface{}:
+		child, exists := container[token]
+		if !exists {
+			return nil, fmt.Errorf("%w: no member %q", ErrInvalidPatch, token)
+		}
+		if len(rest) == 0 {
+			delete(container, token)
+			return container, nil
+		}
+		child, err := removePointer(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+		return container, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			return append(container[:index], container[index+1:]...), nil
+		}
+		child, err := removePointer(container[index], rest)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+		return container, nil
+	default:
+		return nil, fmt.Errorf("%w: cannot index into a scalar with %q", ErrInvalidPatch, token)
+	}
+}
+
+// arrayIndex parses an array reference token; "-" and the length itself are
+// only accepted when inserting
+func arrayIndex(token string, length int, insert bool) (int, error) {
+	if insert && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	if index > length || (!insert && index == length) {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrInvalidPatch, index)
+	}
+	return index, nil
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type patchAuthor struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type patchTarget struct {
+	Title  string            `json:"title"`
+	Price  float64           `json:"price"`
+	Genres []string          `json:"genres"`
+	Author patchAuthor       `json:"author"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func newPatchTarget() patchTarget {
+	return patchTarget{
+		Title:  "Go",
+		Price:  10,
+		Genres: []string{"tech", "go"},
+		Author: patchAuthor{FirstName: "Ann", LastName: "Lee"},
+		Labels: map[string]string{"a/b": "1", "m~n": "2"},
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       string
+		contentType string
+		want        func(*patchTarget)
+		wantErr     error
+	}{
+		{
+			name:        "replaces a member",
+			patch:       `{"title":"Rust"}`,
+			contentType: MergePatchContentType,
+			want:        func(p *patchTarget) { p.Title = "Rust" },
+		},
+		{
+			name:        "null removes a member",
+			patch:       `{"labels":null}`,
+			contentType: MergePatchContentType,
+			want:        func(p *patchTarget) { p.Labels = nil },
+		},
+		{
+			name:        "merges nested objects",
+			patch:       `{"author":{"last_name":"Kim"}}`,
+			contentType: MergePatchContentType,
+			want:        func(p *patchTarget) { p.Author.LastName = "Kim" },
+		},
+		{
+			name:        "null removes a nested member",
+			patch:       `{"labels":{"a/b":null}}`,
+			contentType: MergePatchContentType,
+			want:        func(p *patchTarget) { delete(p.Labels, "a/b") },
+		},
+		{
+			name:        "replaces arrays as a whole",
+			patch:       `{"genres":["fiction"]}`,
+			contentType: MergePatchContentType,
+			want:        func(p *patchTarget) { p.Genres = []string{"fiction"} },
+		},
+		{
+			name:        "plain JSON is a merge patch",
+			patch:       `{"price":12.5}`,
+			contentType: "application/json; charset=utf-8",
+			want:        func(p *patchTarget) { p.Price = 12.5 },
+		},
+		{
+			name:  "no content type is a merge patch",
+			patch: `{"price":12.5}`,
+			want:  func(p *patchTarget) { p.Price = 12.5 },
+		},
+		{
+			name:        "invalid JSON",
+			patch:       `{"title":`,
+			contentType: MergePatchContentType,
+			wantErr:     ErrInvalidPatch,
+		},
+		{
+			name:        "value of the wrong type",
+			patch:       `{"price":"free"}`,
+			contentType: MergePatchContentType,
+			wantErr:     ErrInvalidPatch,
+		},
+		{
+			name:        "unsupported media type",
+			patch:       `{"title":"Rust"}`,
+			contentType: "text/plain",
+			wantErr:     ErrUnsupportedPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkPatch(t, tt.patch, tt.contentType, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		want    func(*patchTarget)
+		wantErr error
+	}{
+		{
+			name:  "add appends with -",
+			patch: `[{"op":"add","path":"/genres/-","value":"web"}]`,
+			want:  func(p *patchTarget) { p.Genres = []string{"tech", "go", "web"} },
+		},
+		{
+			name:  "add inserts before an index",
+			patch: `[{"op":"add","path":"/genres/0","value":"web"}]`,
+			want:  func(p *patchTarget) { p.Genres = []string{"web", "tech", "go"} },
+		},
+		{
+			name:  "add sets an object member",
+			patch: `[{"op":"add","path":"/labels/new","value":"3"}]`,
+			want:  func(p *patchTarget) { p.Labels["new"] = "3" },
+		},
+		{
+			name:  "replace",
+			patch: `[{"op":"replace","path":"/author/first_name","value":"Bo"}]`,
+			want:  func(p *patchTarget) { p.Author.FirstName = "Bo" },
+		},
+		{
+			name:  "remove an array element",
+			patch: `[{"op":"remove","path":"/genres/0"}]`,
+			want:  func(p *patchTarget) { p.Genres = []string{"go"} },
+		},
+		{
+			name:  "pointer escapes",
+			patch: `[{"op":"remove","path":"/labels/a~1b"},{"op":"replace","path":"/labels/m~0n","value":"9"}]`,
+			want: func(p *patchTarget) {
+				delete(p.Labels, "a/b")
+				p.Labels["m~n"] = "9"
+			},
+		},
+		{
+			name:  "move",
+			patch: `[{"op":"move","from":"/author/first_name","path":"/title"}]`,
+			want: func(p *patchTarget) {
+				p.Title = "Ann"
+				p.Author.FirstName = ""
+			},
+		},
+		{
+			name:  "copy",
+			patch: `[{"op":"copy","from":"/genres/1","path":"/genres/0"}]`,
+			want:  func(p *patchTarget) { p.Genres = []string{"go", "tech", "go"} },
+		},
+		{
+			name:  "test then replace",
+			patch: `[{"op":"test","path":"/price","value":10},{"op":"replace","path":"/price","value":11}]`,
+			want:  func(p *patchTarget) { p.Price = 11 },
+		},
+		{
+			name:    "failed test",
+			patch:   `[{"op":"test","path":"/title","value":"Rust"},{"op":"replace","path":"/price","value":11}]`,
+			wantErr: ErrPatchTestFailed,
+		},
+		{
+			name:    "replace a missing member",
+			patch:   `[{"op":"replace","path":"/labels/none","value":"1"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "remove a missing member",
+			patch:   `[{"op":"remove","path":"/labels/none"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "index out of range",
+			patch:   `[{"op":"replace","path":"/genres/2","value":"web"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "index with a leading zero",
+			patch:   `[{"op":"remove","path":"/genres/01"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "move into itself",
+			patch:   `[{"op":"move","from":"/author","path":"/author/first_name"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "add without a value",
+			patch:   `[{"op":"add","path":"/title"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "pointer without a leading slash",
+			patch:   `[{"op":"remove","path":"title"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "unknown operation",
+			patch:   `[{"op":"increment","path":"/price","value":1}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "not an array of operations",
+			patch:   `{"op":"remove","path":"/title"}`,
+			wantErr: ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkPatch(t, tt.patch, JSONPatchContentType, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func checkPatch(t *testing.T, patch, contentType string, want func(*patchTarget), wantErr error) {
+	t.Helper()
+
+	current := newPatchTarget()
+	got, err := applyPatch(current, []byte(patch), contentType)
+	if wantErr != nil {
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("applyPatch() error = %v, want %v", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("applyPatch() error = %v", err)
+	}
+
+	expected := newPatchTarget()
+	want(&expected)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("applyPatch() = %+v, want %+v", got, expected)
+	}
+	if !reflect.DeepEqual(current, newPatchTarget()) {
+		t.Errorf("applyPatch() changed the current value to %+v", current)
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrPaymentDeclined is returned by a PaymentProvider refusing an operation
+	ErrPaymentDeclined = errors.New("payment declined")
+	// ErrAuthorizationExpired is returned when capturing an authorization
+	// that is no longer valid
+	ErrAuthorizationExpired = errors.New("payment authorization expired")
+)
+
+// PaymentAuthorization is a hold on the customer funds, valid until ExpiresAt
+type PaymentAuthorization struct {
+	Reference string
+	ExpiresAt time.Time
+}
+
+// PaymentProvider is the gateway through which orders are paid. Capture and
+// Refund take an idempotency key: calling them again with the same key has
+// no further effect.
+type PaymentProvider interface {
+	Name() string
+	Authorize(amount float64, paymentMethod string) (PaymentAuthorization, error)
+	Capture(reference string, amount float64, idempotencyKey string) error
+	Refund(reference string, amount float64, idempotencyKey string) error
+	Void(reference string) error
+}
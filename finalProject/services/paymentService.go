@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type PaymentService struct {
+	paymentRepo  repositories.PaymentStore
+	orderRepo    repositories.OrderStore
+	orderService *OrderService
+	provider     PaymentProvider
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewPaymentService(repo repositories.PaymentStore, orderService *OrderService, provider PaymentProvider) *PaymentService {
+	return &PaymentService{
+		paymentRepo:  repo,
+		orderRepo:    memory.NewInMemoryOrderStore(),
+		orderService: orderService,
+		provider:     provider,
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *PaymentService) As(actor string) *PaymentService {
+	copied := *s
+	copied.actor = actor
+	copied.orderService = s.orderService.As(actor)
+	return &copied
+}
+
+func (s *PaymentService) GetPayments(orderID int) ([]models.Payment, error) {
+	if _, err := s.orderRepo.Get(orderID); err != nil {
+		return nil, ErrOrderNotFound
+	}
+	return s.paymentRepo.FindByOrder(orderID)
+}
+
+// Authorize puts a hold on the order total with the provider. A declined
+// authorization is recorded as a failed payment and fails the order, giving
+// its stock back.
+func (s *PaymentService) Authorize(orderID int, request models.PaymentRequest) (models.Payment, error) {
+	order, err := s.orderRepo.Get(orderID)
+	if err != nil {
+		return models.Payment{}, ErrOrderNotFound
+	}
+	if order.Status != models.OrderStatusPending {
+		return models.Payment{}, fmt.Errorf("%w: order %d is %s", ErrOrderStatus, orderID, order.Status)
+	}
+
+	now := time.Now()
+	payment := models.Payment{
+		OrderID:   orderID,
+		Provider:  s.provider.Name(),
+		Amount:    order.TotalPrice,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	authorization, authErr := s.provider.Authorize(order.TotalPrice, request.PaymentMethod)
+	if authErr != nil {
+		payment.Status = models.PaymentFailed
+		payment.FailureReason = authErr.Error()
+		if _, err := s.orderService.transitionStatus(orderID, []string{models.OrderStatusPending}, models.OrderStatusPaymentFailed); err == nil {
+			s.orderService.restockCancelled(order, "payment failed")
+		}
+		if _, err := s.create(payment); err != nil {
+			return models.Payment{}, err
+		}
+		return models.Payment{}, authErr
+	}
+
+	if _, err := s.orderService.transitionStatus(orderID, []string{models.OrderStatusPending}, models.OrderStatusAuthorized); err != nil {
+		// Another payment got there first
+		s.provider.Void(authorization.Reference)
+		return models.Payment{}, err
+	}
+	payment.Status = models.PaymentAuthorized
+	payment.Reference = authorization.Reference
+	payment.ExpiresAt = authorization.ExpiresAt
+	return s.create(payment)
+}
+
+// Capture collects the authorized amount. Capturing a payment again returns
+// it unchanged; an expired authorization is released.
+func (s *PaymentService) Capture(orderID int) (models.Payment, error) {
+	payment, err := s.currentPayment(orderID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if payment.Status != models.PaymentAuthorized {
+		if payment.CapturedAt != nil {
+			return payment, nil
+		}
+		return models.Payment{}, fmt.Errorf("%w: payment %d is %s", ErrOrderStatus, payment.ID, payment.Status)
+	}
+	if time.Now().After(payment.ExpiresAt) {
+		s.expire(payment)
+		return models.Payment{}, ErrAuthorizationExpired
+	}
+
+	err = s.provider.Capture(payment.Reference, payment.Amount, fmt.Sprintf("capture-%d", payment.ID))
+	if errors.Is(err, ErrAuthorizationExpired) {
+		s.expire(payment)
+		return models.Payment{}, err
+	}
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	now := time.Now()
+	payment.Status = models.PaymentCaptured
+	payment.CapturedAmount = payment.Amount
+	payment.CapturedAt = &now
+	payment.UpdatedAt = now
+	captured, err := s.update(payment)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		// A concurrent capture recorded it first
+		return s.paymentRepo.Get(payment.ID)
+	}
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if _, err := s.orderService.transitionStatus(orderID, []string{models.OrderStatusAuthorized}, models.OrderStatusPaid); err != nil {
+		return models.Payment{}, err
+	}
+	return captured, nil
+}
+
+// Void cancels an authorized payment and the order, giving its stock back
+func (s *PaymentService) Void(orderID int) (models.Payment, error) {
+	payment, err := s.currentPayment(orderID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if payment.Status != models.PaymentAuthorized {
+		return models.Payment{}, fmt.Errorf("%w: payment %d is %s", ErrOrderStatus, payment.ID, payment.Status)
+	}
+	if err := s.provider.Void(payment.Reference); err != nil {
+		return models.Payment{}, err
+	}
+
+	payment.Status = models.PaymentVoided
+	payment.UpdatedAt = time.Now()
+	voided, err := s.update(payment)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	order, err := s.orderService.transitionStatus(orderID, []string{models.OrderStatusAuthorized}, models.OrderStatusCancelled)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	s.orderService.restockCancelled(order, "payment voided")
+	return voided, nil
+}
+
+// Refund gives back part or all of a captured payment. The idempotency key
+// identifies the refund, so retrying it does not refund twice.
+func (s *PaymentService) Refund(orderID int, amount float64, idempotencyKey string) (models.Payment, error) {
+	amount = roundPrice(amount)
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		payment, err := s.currentPayment(orderID)
+		if err != nil {
+			return models.Payment{}, err
+		}
+		if payment.Status != models.PaymentCaptured && payment.Status != models.PaymentPartiallyRefunded {
+			return models.Payment{}, fmt.Errorf("%w: payment %d is %s", ErrOrderStatus, payment.ID, payment.Status)
+		}
+		if amount <= 0 || roundPrice(payment.RefundedAmount+amount) > payment.CapturedAmount {
+			return models.Payment{}, invalid("refund of %.2f exceeds the %.2f left on payment %d",
+				amount, payment.CapturedAmount-payment.RefundedAmount, payment.ID)
+		}
+		if err := s.provider.Refund(payment.Reference, amount, idempotencyKey); err != nil {
+			return models.Payment{}, err
+		}
+
+		payment.RefundedAmount = roundPrice(payment.RefundedAmount + amount)
+		payment.Status = models.PaymentPartiallyRefunded
+		orderStatus := models.OrderStatusPartiallyRefunded
+		if payment.RefundedAmount >= payment.CapturedAmount {
+			payment.Status = models.PaymentRefunded
+			orderStatus = models.OrderStatusRefunded
+		}
+		payment.UpdatedAt = time.Now()
+		refunded, err := s.update(payment)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Payment{}, err
+		}
+		_, err = s.orderService.transitionStatus(orderID,
+			[]string{models.OrderStatusPaid, models.OrderStatusPartiallyRefunded}, orderStatus)
+		return refunded, err
+	}
+	return models.Payment{}, repositories.ErrVersionConflict
+}
+
+// ExpireAuthorizations releases the orders whose authorization ran out
+// before being captured
+func (s *PaymentService) ExpireAuthorizations(now time.Time) {
+	payments, err := s.paymentRepo.Search(models.SearchCriteria{
+		Filters: map[string]interface{}{"status": models.PaymentAuthorized},
+	})
+	if err != nil {
+		log.Printf("PaymentService.ExpireAuthorizations: %v", err)
+		return
+	}
+	for _, payment := range payments {
+		if now.After(payment.ExpiresAt) {
+			s.expire(payment)
+		}
+	}
+}
+
+// StartExpiryWatcher checks for expired authorizations every interval,
+// holding gate while it does
+func (s *PaymentService) StartExpiryWatcher(interval time.Duration, gate sync.Locker) {
+	go func() {
+		for now := range time.Tick(interval) {
+			gate.Lock()
+			s.ExpireAuthorizations(now)
+			gate.Unlock()
+		}
+	}()
+}
+
+// expire marks an authorization as expired and gives the stock of its order back
+func (s *PaymentService) expire(payment models.Payment) {
+	payment.Status = models.PaymentExpired
+	payment.UpdatedAt = time.Now()
+	if _, err := s.update(payment); err != nil {
+		// Captured, voided or expired in the meantime
+		return
+	}
+	order, err := s.orderService.transitionStatus(payment.OrderID, []string{models.OrderStatusAuthorized}, models.OrderStatusPaymentExpired)
+	if err != nil {
+		log.Printf("PaymentService.expire: payment %d: %v", payment.ID, err)
+		return
+	}
+	s.orderService.restockCancelled(order, "payment expired")
+	log.Printf("PaymentService.expire: payment %d expired, stock of order %d released", payment.ID, order.ID)
+}
+
+// currentPayment returns the latest payment of an order
+func (s *PaymentService) currentPayment(orderID int) (models.Payment, error) {
+	payments, err := s.GetPayments(orderID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if len(payments) == 0 {
+		return models.Payment{}, fmt.Errorf("%w: order %d has no payment", ErrOrderStatus, orderID)
+	}
+	return payments[len(payments)-1], nil
+}
+
+// create stores a new payment and records it in the audit log
+func (s *PaymentService) create(payment models.Payment) (models.Payment, error) {
+	created, err := s.paymentRepo.Create(payment)
+	if err == nil {
+		s.auditLog.Record(s.actor, models.EntityPayment, created.ID, models.AuditCreate, nil, created)
+	}
+	return created, err
+}
+
+// update saves a payment read before, conditional on its version, and
+// records the change in the audit log
+func (s *PaymentService) update(payment models.Payment) (models.Payment, error) {
+	before, err := s.paymentRepo.Get(payment.ID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	updated, err := s.paymentRepo.Update(payment)
+	if err == nil {
+		s.auditLog.Record(s.actor, models.EntityPayment, updated.ID, models.AuditUpdate, before, updated)
+	}
+	return updated, err
+}
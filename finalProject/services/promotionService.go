@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type PromotionService struct {
+	promotionRepo repositories.PromotionStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewPromotionService(repo repositories.PromotionStore) *PromotionService {
+	return &PromotionService{promotionRepo: repo, auditLog: NewAuditService(memory.NewInMemoryAuditStore())}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *PromotionService) As(actor string) *PromotionService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+func (s *PromotionService) CreatePromotion(promotion models.Promotion) (models.Promotion, error) {
+	promotion.Code = normalizeCode(promotion.Code)
+	if err := validatePromotion(promotion); err != nil {
+		return models.Promotion{}, err
+	}
+	created, err := s.promotionRepo.Create(promotion)
+	if err == nil {
+		s.record(models.AuditCreate, created.ID, nil, created)
+	}
+	return created, err
+}
+
+func (s *PromotionService) GetPromotion(id int) (models.Promotion, error) {
+	return s.promotionRepo.Get(id)
+}
+
+func (s *PromotionService) UpdatePromotion(promotion models.Promotion) (models.Promotion, error) {
+	promotion.Code = normalizeCode(promotion.Code)
+	if err := validatePromotion(promotion); err != nil {
+		return models.Promotion{}, err
+	}
+	before, _ := s.promotionRepo.Get(promotion.ID)
+	updated, err := s.promotionRepo.Update(promotion)
+	if err == nil {
+		s.record(models.AuditUpdate, updated.ID, before, updated)
+	}
+	return updated, err
+}
+
+// DeletePromotion soft deletes a promotion. A non-zero version must match
+// the stored one, which the store checks as it deletes.
+func (s *PromotionService) DeletePromotion(id int, version int) error {
+	before, err := s.promotionRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.promotionRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	return nil
+}
+
+func (s *PromotionService) RestorePromotion(id int) (models.Promotion, error) {
+	restored, err := s.promotionRepo.Restore(id)
+	if err == nil {
+		s.record(models.AuditRestore, id, nil, restored)
+	}
+	return restored, err
+}
+
+func (s *PromotionService) SearchPromotions(query models.SearchCriteria) ([]models.Promotion, error) {
+	return s.promotionRepo.Search(query)
+}
+
+// Discounts works out the promotions an order gets: the codes entered by the
+// customer, which must all be valid, and the automatic promotions it
+// qualifies for. The order must be priced, with its items holding the
+// current books. Stackable promotions add up; a promotion that does not
+// stack is only used when it alone beats them.
+func (s *PromotionService) Discounts(order models.Order, now time.Time) ([]models.AppliedDiscount, error) {
+	var candidates []models.AppliedDiscount
+	var stackable []bool
+
+	for _, code := range order.PromotionCodes {
+		promotion, err := s.promotionRepo.FindByCode(code)
+		if err != nil {
+			return nil, invalid("unknown promotion code %q", code)
+		}
+		if reason := s.ineligible(promotion, order.Customer.ID, now); reason != "" {
+			return nil, invalid("promotion code %q %s", code, reason)
+		}
+		amount := discountAmount(promotion, order)
+		if amount <= 0 {
+			return nil, invalid("promotion code %q does not apply to this order", code)
+		}
+		candidates = append(candidates, appliedDiscount(promotion, amount))
+		stackable = append(stackable, promotion.Stackable)
+	}
+
+	promotions, err := s.promotionRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	for _, promotion := range promotions {
+		if promotion.Code != "" || s.ineligible(promotion, order.Customer.ID, now) != "" {
+			continue
+		}
+		if amount := discountAmount(promotion, order); amount > 0 {
+			candidates = append(candidates, appliedDiscount(promotion, amount))
+			stackable = append(stackable, promotion.Stackable)
+		}
+	}
+
+	applied := stackDiscounts(candidates, stackable)
+
+	// The discounts cannot take the order below zero
+	remaining := order.Subtotal
+	for i := range applied {
+		applied[i].Amount = roundPrice(min(applied[i].Amount, remaining))
+		remaining -= applied[i].Amount
+	}
+	return applied, nil
+}
+
+// stackDiscounts picks the discounts an order gets among the candidates:
+// the stackable ones together, unless a single one that does not stack
+// takes off more than all of them
+func stackDiscounts(candidates []models.AppliedDiscount, stackable []bool) []models.AppliedDiscount {
+	var stacked []models.AppliedDiscount
+	var stackedTotal float64
+	var best models.AppliedDiscount
+	for i, discount := range candidates {
+		if stackable[i] {
+			stacked = append(stacked, discount)
+			stackedTotal += discount.Amount
+		} else if discount.Amount > best.Amount {
+			best = discount
+		}
+	}
+	if best.Amount > stackedTotal {
+		return []models.AppliedDiscount{best}
+	}
+	return stacked
+}
+
+// Redeem counts the discounts of an order against the usage limits of their
+// promotions. It fails if a limit was reached since the order was priced.
+func (s *PromotionService) Redeem(customerID int, discounts []models.AppliedDiscount) ([]int, error) {
+	if len(discounts) == 0 {
+		return nil, nil
+	}
+	redemptions := make([]models.PromotionRedemption, 0, len(discounts))
+	for _, discount := range discounts {
+		redemptions = append(redemptions, models.PromotionRedemption{
+			PromotionID: discount.PromotionID,
+			CustomerID:  customerID,
+			Amount:      discount.Amount,
+			RedeemedAt:  time.Now(),
+		})
+	}
+
+	saved, err := s.promotionRepo.Redeem(redemptions)
+	if errors.Is(err, repositories.ErrUsageLimitReached) {
+		return nil, invalid("%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(saved))
+	for _, redemption := range saved {
+		ids = append(ids, redemption.ID)
+	}
+	return ids, nil
+}
+
+func (s *PromotionService) AttachOrder(redemptionIDs []int, orderID int) error {
+	return s.promotionRepo.AttachOrder(redemptionIDs, orderID)
+}
+
+func (s *PromotionService) CancelRedemptions(redemptionIDs []int) error {
+	return s.promotionRepo.CancelRedemptions(redemptionIDs)
+}
+
+// CancelOrderRedemptions gives the uses of its promotions back to an order
+// whose payment failed, expired or was voided
+func (s *PromotionService) CancelOrderRedemptions(orderID int) error {
+	return s.promotionRepo.CancelRedemptions(s.promotionRepo.OrderRedemptions(orderID))
+}
+
+// ineligible explains why a promotion cannot be used by a customer right
+// now, or returns an empty string if it can
+func (s *PromotionService) ineligible(promotion models.Promotion, customerID int, now time.Time) string {
+	switch {
+	case promotion.Disabled:
+		return "is disabled"
+	case now.Before(promotion.StartsAt):
+		return "is not valid yet"
+	case !promotion.EndsAt.IsZero() && now.After(promotion.EndsAt):
+		return "has expired"
+	}
+	total, byCustomer := s.promotionRepo.CountRedemptions(promotion.ID, customerID)
+	if promotion.UsageLimit > 0 && total >= promotion.UsageLimit {
+		return "has been used up"
+	}
+	if promotion.PerCustomerLimit > 0 && byCustomer >= promotion.PerCustomerLimit {
+		return "was already used the maximum number of times"
+	}
+	return ""
+}
+
+// discountAmount is what a promotion takes off an order, zero when it does
+// not apply
+func discountAmount(promotion models.Promotion, order models.Order) float64 {
+	switch promotion.Type {
+	case models.PromotionPercentage:
+		return roundPrice(order.Subtotal * promotion.Value / 100)
+	case models.PromotionFixedAmount:
+		return min(promotion.Value, order.Subtotal)
+	case models.PromotionAuthorSale:
+		var eligible float64
+		for _, item := range order.Items {
+			if item.Book.Author.ID == promotion.AuthorID {
+				eligible += item.Book.Price * float64(item.Quantity)
+			}
+		}
+		return roundPrice(eligible * promotion.Value / 100)
+	case models.PromotionBuyXGetY:
+		var prices []float64
+		for _, item := range order.Items {
+			if hasGenre(item.Book, promotion.Genre) {
+				for range item.Quantity {
+					prices = append(prices, item.Book.Price)
+				}
+			}
+		}
+		free := len(prices) / (promotion.BuyQuantity + promotion.FreeQuantity) * promotion.FreeQuantity
+		slices.Sort(prices)
+		var amount float64
+		for _, price := range prices[:free] {
+			amount += price
+		}
+		return roundPrice(amount)
+	}
+	return 0
+}
+
+func appliedDiscount(promotion models.Promotion, amount float64) models.AppliedDiscount {
+	return models.AppliedDiscount{
+		PromotionID: promotion.ID,
+		Code:        promotion.Code,
+		Name:        promotion.Name,
+		Amount:      amount,
+	}
+}
+
+func hasGenre(book models.Book, genre string) bool {
+	for _, g := range book.Genres {
+		if strings.EqualFold(g, genre) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCode makes codes case insensitive
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// normalizeCodes upper-cases the codes entered for an order and drops duplicates
+func normalizeCodes(codes []string) []string {
+	var normalized []string
+	for _, code := range codes {
+		code = normalizeCode(code)
+		if code != "" && !slices.Contains(normalized, code) {
+			normalized = append(normalized, code)
+		}
+	}
+	return normalized
+}
+
+func (s *PromotionService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityPromotion, id, action, before, after)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"bookstore.com/models"
+)
+
+func TestStackDiscounts(t *testing.T) {
+	tenOff := models.AppliedDiscount{PromotionID: 1, Name: "10 off", Amount: 10}
+	fiveOff := models.AppliedDiscount{PromotionID: 2, Name: "5 off", Amount: 5}
+	twelveOff := models.AppliedDiscount{PromotionID: 3, Name: "12 off", Amount: 12}
+	twentyOff := models.AppliedDiscount{PromotionID: 4, Name: "20 off", Amount: 20}
+
+	tests := []struct {
+		name       string
+		candidates []models.AppliedDiscount
+		stackable  []bool
+		want       []models.AppliedDiscount
+	}{
+		{
+			name: "no candidates",
+		},
+		{
+			name:       "stackable discounts add up",
+			candidates: []models.AppliedDiscount{tenOff, fiveOff},
+			stackable:  []bool{true, true},
+			want:       []models.AppliedDiscount{tenOff, fiveOff},
+		},
+		{
+			name:       "a single discount that does not stack",
+			candidates: []models.AppliedDiscount{twelveOff},
+			stackable:  []bool{false},
+			want:       []models.AppliedDiscount{twelveOff},
+		},
+		{
+			name:       "stacked discounts beat a bigger single one",
+			candidates: []models.AppliedDiscount{tenOff, twelveOff, fiveOff},
+			stackable:  []bool{true, false, true},
+			want:       []models.AppliedDiscount{tenOff, fiveOff},
+		},
+		{
+			name:       "a single discount beating the stacked ones is used alone",
+			candidates: []models.AppliedDiscount{tenOff, twentyOff, fiveOff},
+			stackable:  []bool{true, false, true},
+			want:       []models.AppliedDiscount{twentyOff},
+		},
+		{
+			name:       "stacked discounts win a tie",
+			candidates: []models.AppliedDiscount{tenOff, fiveOff, {PromotionID: 5, Amount: 15}},
+			stackable:  []bool{true, true, false},
+			want:       []models.AppliedDiscount{tenOff, fiveOff},
+		},
+		{
+			name:       "only the best of the discounts that do not stack",
+			candidates: []models.AppliedDiscount{twelveOff, twentyOff, tenOff},
+			stackable:  []bool{false, false, false},
+			want:       []models.AppliedDiscount{twentyOff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stackDiscounts(tt.candidates, tt.stackable)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stackDiscounts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
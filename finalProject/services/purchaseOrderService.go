@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+var (
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	// ErrPurchaseOrderStatus is returned when a purchase order is not in a
+	// status that allows the requested operation
+	ErrPurchaseOrderStatus = errors.New("operation not allowed in the current purchase order status")
+)
+
+type PurchaseOrderService struct {
+	purchaseRepo repositories.PurchaseOrderStore
+	supplierRepo repositories.SupplierStore
+	bookRepo     repositories.BookStore
+	inventory    *InventoryService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewPurchaseOrderService(repo repositories.PurchaseOrderStore) *PurchaseOrderService {
+	return &PurchaseOrderService{
+		purchaseRepo: repo,
+		supplierRepo: memory.NewInMemorySupplierStore(),
+		bookRepo:     memory.NewInMemoryBookStore(),
+		inventory:    NewInventoryService(memory.NewInMemoryStockMovementStore()),
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *PurchaseOrderService) As(actor string) *PurchaseOrderService {
+	copied := *s
+	copied.actor = actor
+	copied.inventory = s.inventory.As(actor)
+	return &copied
+}
+
+// CreatePurchaseOrder opens a purchase order for books of the catalog from a
+// supplier; nothing is received yet
+func (s *PurchaseOrderService) CreatePurchaseOrder(order models.PurchaseOrder) (models.PurchaseOrder, error) {
+	if err := validatePurchaseOrder(order); err != nil {
+		return models.PurchaseOrder{}, err
+	}
+	if _, err := s.supplierRepo.Get(order.SupplierID); err != nil {
+		return models.PurchaseOrder{}, invalid("supplier %d not found", order.SupplierID)
+	}
+
+	order.TotalCost = 0
+	for i, line := range order.Lines {
+		book, err := s.bookRepo.Get(line.BookID)
+		if err != nil {
+			return models.PurchaseOrder{}, invalid("book %d not found", line.BookID)
+		}
+		order.Lines[i].Title = book.Title
+		order.Lines[i].ReceivedQuantity = 0
+		order.TotalCost += line.UnitCost * float64(line.Quantity)
+	}
+	order.TotalCost = roundPrice(order.TotalCost)
+	order.Status = models.PurchaseOrderOpen
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+
+	created, err := s.purchaseRepo.Create(order)
+	if err == nil {
+		s.record(models.AuditCreate, created.ID, nil, created)
+	}
+	return created, err
+}
+
+func (s *PurchaseOrderService) GetPurchaseOrder(id int) (models.PurchaseOrder, error) {
+	order, err := s.purchaseRepo.Get(id)
+	if err != nil {
+		return models.PurchaseOrder{}, ErrPurchaseOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *PurchaseOrderService) SearchPurchaseOrders(query models.SearchCriteria) ([]models.PurchaseOrder, error) {
+	return s.purchaseRepo.Search(query)
+}
+
+// ReceivePurchaseOrder records a delivery: the received quantities are added
+// to the order, which cannot receive more than ordered, then to the stock of
+// the books at the unit cost of their line. The order is claimed first so
+// that concurrent deliveries cannot receive the same copies twice.
+func (s *PurchaseOrderService) ReceivePurchaseOrder(id int, receipt models.PurchaseOrderReceipt) (models.PurchaseOrder, error) {
+	if len(receipt.Lines) == 0 {
+		return models.PurchaseOrder{}, invalid("a receipt needs at least one line")
+	}
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.GetPurchaseOrder(id)
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		order, err := receiveLines(current, receipt)
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		order.UpdatedAt = time.Now()
+		updated, err := s.purchaseRepo.Update(order)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		s.record(models.AuditUpdate, id, current, updated)
+
+		for _, received := range receipt.Lines {
+			line := updated.Lines[slices.IndexFunc(updated.Lines, func(line models.PurchaseOrderLine) bool {
+				return line.BookID == received.BookID
+			})]
+			_, _, err := s.inventory.move(models.StockMovement{
+				BookID:          received.BookID,
+				Type:            models.MovementReceipt,
+				Quantity:        received.Quantity,
+				Reason:          fmt.Sprintf("purchase order %d", id),
+				UnitCost:        line.UnitCost,
+				PurchaseOrderID: id,
+			})
+			if err != nil {
+				return models.PurchaseOrder{}, fmt.Errorf("purchase order %d is received but book %d is not restocked: %w", id, received.BookID, err)
+			}
+		}
+		return updated, nil
+	}
+	return models.PurchaseOrder{}, repositories.ErrVersionConflict
+}
+
+// CancelPurchaseOrder closes a purchase order; the copies already received
+// stay in stock
+func (s *PurchaseOrderService) CancelPurchaseOrder(id int) (models.PurchaseOrder, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.GetPurchaseOrder(id)
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		if !receivable(current) {
+			return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order %d is %s", ErrPurchaseOrderStatus, id, current.Status)
+		}
+		order := current
+		order.Status = models.PurchaseOrderCancelled
+		order.UpdatedAt = time.Now()
+		updated, err := s.purchaseRepo.Update(order)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+		}
+		return updated, err
+	}
+	return models.PurchaseOrder{}, repositories.ErrVersionConflict
+}
+
+// receiveLines adds the received quantities to the lines of a purchase
+// order and works out its new status
+func receiveLines(order models.PurchaseOrder, receipt models.PurchaseOrderReceipt) (models.PurchaseOrder, error) {
+	if !receivable(order) {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order %d is %s", ErrPurchaseOrderStatus, order.ID, order.Status)
+	}
+	order.Lines = slices.Clone(order.Lines)
+	for _, received := range receipt.Lines {
+		i := slices.IndexFunc(order.Lines, func(line models.PurchaseOrderLine) bool { return line.BookID == received.BookID })
+		if i < 0 {
+			return models.PurchaseOrder{}, invalid("book %d is not on purchase order %d", received.BookID, order.ID)
+		}
+		if received.Quantity <= 0 {
+			return models.PurchaseOrder{}, invalid("invalid quantity %d for book %d", received.Quantity, received.BookID)
+		}
+		line := &order.Lines[i]
+		if line.ReceivedQuantity+received.Quantity > line.Quantity {
+			return models.PurchaseOrder{}, invalid("only %d copies of book %d are still expected", line.Quantity-line.ReceivedQuantity, line.BookID)
+		}
+		line.ReceivedQuantity += received.Quantity
+	}
+
+	order.Status = models.PurchaseOrderReceived
+	for _, line := range order.Lines {
+		if line.ReceivedQuantity < line.Quantity {
+			order.Status = models.PurchaseOrderPartiallyReceived
+		}
+	}
+	return order, nil
+}
+
+func receivable(order models.PurchaseOrder) bool {
+	return order.Status == models.PurchaseOrderOpen || order.Status == models.PurchaseOrderPartiallyReceived
+}
+
+func (s *PurchaseOrderService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityPurchaseOrder, id, action, before, after)
+}
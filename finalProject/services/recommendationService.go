@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// maxRecommendations caps the number of books asked for with limit
+const maxRecommendations = 50
+
+type RecommendationService struct {
+	coPurchaseRepo repositories.CoPurchaseStore
+	bookRepo       repositories.BookStore
+	orderRepo      repositories.OrderStore
+	customerRepo   repositories.CustomerStore
+}
+
+func NewRecommendationService(repo repositories.CoPurchaseStore) *RecommendationService {
+	return &RecommendationService{
+		coPurchaseRepo: repo,
+		bookRepo:       memory.NewInMemoryBookStore(),
+		orderRepo:      memory.NewInMemoryOrderStore(),
+		customerRepo:   memory.NewInMemoryCustomerStore(),
+	}
+}
+
+// RecommendBooks suggests up to limit books to the readers of a book: first
+// those most often ordered along with it, then books of the same author and
+// books sharing the most genres with it, the newest first. Books out of
+// stock are left out.
+func (s *RecommendationService) RecommendBooks(bookID int, limit int) ([]models.Recommendation, error) {
+	if limit < 1 || limit > maxRecommendations {
+		return nil, invalid("limit must be between 1 and %d", maxRecommendations)
+	}
+	book, err := s.bookRepo.Get(bookID)
+	if err != nil {
+		return nil, ErrBookNotFound
+	}
+	catalog, err := s.bookRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	related, err := s.coPurchaseRepo.Related(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	picks := newRecommendationPicks(limit, bookID)
+	byID := make(map[int]models.Book, len(catalog))
+	for _, candidate := range catalog {
+		byID[candidate.ID] = candidate
+	}
+	boughtTogether := make([]int, 0, len(related))
+	for id := range related {
+		if _, exists := byID[id]; exists {
+			boughtTogether = append(boughtTogether, id)
+		}
+	}
+	sort.Slice(boughtTogether, func(i, j int) bool {
+		a, b := boughtTogether[i], boughtTogether[j]
+		if related[a] != related[b] {
+			return related[a] > related[b]
+		}
+		return a < b
+	})
+	for _, id := range boughtTogether {
+		picks.add(byID[id], models.RecommendedBoughtTogether, related[id])
+	}
+
+	sortNewestBooksFirst(catalog)
+	if book.Author.ID != 0 {
+		for _, candidate := range catalog {
+			if candidate.Author.ID == book.Author.ID {
+				picks.add(candidate, models.RecommendedSameAuthor, 0)
+			}
+		}
+	}
+	sameGenre := make([]models.Book, 0, len(catalog))
+	for _, candidate := range catalog {
+		if sharedGenres(book, candidate) > 0 {
+			sameGenre = append(sameGenre, candidate)
+		}
+	}
+	sort.SliceStable(sameGenre, func(i, j int) bool {
+		return sharedGenres(book, sameGenre[i]) > sharedGenres(book, sameGenre[j])
+	})
+	for _, candidate := range sameGenre {
+		picks.add(candidate, models.RecommendedSameGenre, 0)
+	}
+	return picks.recommendations, nil
+}
+
+// recommendationPicks collects up to limit recommendations, each book once
+// and only books in stock
+type recommendationPicks struct {
+	limit           int
+	picked          map[int]bool
+	recommendations []models.Recommendation
+}
+
+// newRecommendationPicks starts a selection leaving out the excluded books
+func newRecommendationPicks(limit int, excluded ...int) *recommendationPicks {
+	picks := &recommendationPicks{
+		limit:           limit,
+		picked:          make(map[int]bool),
+		recommendations: []models.Recommendation{},
+	}
+	for _, id := range excluded {
+		picks.picked[id] = true
+	}
+	return picks
+}
+
+func (p *recommendationPicks) add(book models.Book, reason string, boughtTogether int) {
+	if len(p.recommendations) >= p.limit || p.picked[book.ID] || book.Stock <= 0 {
+		return
+	}
+	p.picked[book.ID] = true
+	p.recommendations = append(p.recommendations, models.Recommendation{Book: book, Reason: reason, BoughtTogether: boughtTogether})
+}
+
+// sharedGenres counts the genres of a book that another one has too
+func sharedGenres(book, other models.Book) int {
+	shared := 0
+	for _, genre := range book.Genres {
+		for _, otherGenre := range other.Genres {
+			if strings.EqualFold(genre, otherGenre) {
+				shared++
+				break
+			}
+		}
+	}
+	return shared
+}
+
+func sortNewestBooksFirst(books []models.Book) {
+	sort.Slice(books, func(i, j int) bool {
+		if !books[i].PublishedAt.Equal(books[j].PublishedAt) {
+			return books[i].PublishedAt.After(books[j].PublishedAt)
+		}
+		return books[i].ID < books[j].ID
+	})
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Purger is implemented by the stores keeping soft deleted records
+type Purger interface {
+	Purge(before time.Time) (int, error)
+}
+
+// RetentionService removes for good the records deleted longer ago than the
+// retention window; until then they can be restored
+type RetentionService struct {
+	retention time.Duration
+	stores    map[string]Purger
+}
+
+func NewRetentionService(retention time.Duration, stores map[string]Purger) *RetentionService {
+	return &RetentionService{retention: retention, stores: stores}
+}
+
+// PurgeDeleted purges every store of the records deleted before now less the
+// retention window
+func (s *RetentionService) PurgeDeleted(now time.Time) {
+	before := now.Add(-s.retention)
+	for name, store := range s.stores {
+		purged, err := store.Purge(before)
+		if err != nil {
+			log.Printf("RetentionService.PurgeDeleted: %s: %v", name, err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("RetentionService.PurgeDeleted: purged %d %s deleted before %v", purged, name, before.Format(time.RFC3339))
+		}
+	}
+}
+
+// StartPurgeJob purges the deleted records every interval, holding gate
+// while it does
+func (s *RetentionService) StartPurgeJob(interval time.Duration, gate sync.Locker) {
+	go func() {
+		for now := range time.Tick(interval) {
+			gate.Lock()
+			s.PurgeDeleted(now)
+			gate.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+var (
+	ErrReturnNotFound = errors.New("return not found")
+	// ErrReturnStatus is returned when a return has already been decided
+	ErrReturnStatus = errors.New("operation not allowed in the current return status")
+)
+
+type ReturnService struct {
+	// mu keeps concurrent requests from returning the same copies twice
+	mu             *sync.Mutex
+	returnRepo     repositories.ReturnStore
+	orderRepo      repositories.OrderStore
+	bookSaleRepo   repositories.BookSaleStore
+	orderService   *OrderService
+	paymentService *PaymentService
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewReturnService(repo repositories.ReturnStore, orderService *OrderService, paymentService *PaymentService) *ReturnService {
+	return &ReturnService{
+		mu:             &sync.Mutex{},
+		returnRepo:     repo,
+		orderRepo:      memory.NewInMemoryOrderStore(),
+		bookSaleRepo:   memory.NewInMemoryBookSaleStore(),
+		orderService:   orderService,
+		paymentService: paymentService,
+		auditLog:       NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes, and the order and
+// stock changes they make, in the audit log on behalf of actor
+func (s *ReturnService) As(actor string) *ReturnService {
+	copied := *s
+	copied.actor = actor
+	copied.orderService = s.orderService.As(actor)
+	copied.paymentService = s.paymentService.As(actor)
+	return &copied
+}
+
+// RequestReturn records the return of items of a paid order, waiting for an
+// admin to approve it. Items cannot be returned more often than they were
+// ordered, counting the returns not rejected. Refunds are priced from the
+// items as placed, which updates of the order cannot change (see
+// keepPlacedOrder).
+func (s *ReturnService) RequestReturn(orderID int, request models.ReturnRequest) (models.Return, error) {
+	order, err := s.orderRepo.Get(orderID)
+	if err != nil {
+		return models.Return{}, ErrOrderNotFound
+	}
+	if order.Status != models.OrderStatusPaid && order.Status != models.OrderStatusPartiallyRefunded {
+		return models.Return{}, fmt.Errorf("%w: order %d is %s", ErrOrderStatus, orderID, order.Status)
+	}
+	if len(request.Items) == 0 {
+		return models.Return{}, invalid("a return needs at least one item")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, err := s.activeReturns(orderID)
+	if err != nil {
+		return models.Return{}, err
+	}
+	returned := make(map[int]int)
+	for _, other := range previous {
+		for _, item := range other.Items {
+			returned[item.OrderItemID] += item.Quantity
+		}
+	}
+	ret := models.Return{
+		OrderID:   orderID,
+		Reason:    request.Reason,
+		Status:    models.ReturnRequested,
+		CreatedAt: time.Now(),
+	}
+	for _, requested := range request.Items {
+		item, found := findOrderItem(order, requested.OrderItemID)
+		if !found {
+			return models.Return{}, invalid("order %d has no item %d", orderID, requested.OrderItemID)
+		}
+		if requested.Quantity <= 0 {
+			return models.Return{}, invalid("quantity of item %d must be positive", item.ID)
+		}
+		returned[item.ID] += requested.Quantity
+		if returned[item.ID] > item.Quantity {
+			return models.Return{}, invalid("only %d of item %d can be returned",
+				item.Quantity-(returned[item.ID]-requested.Quantity), item.ID)
+		}
+		ret.Items = append(ret.Items, models.ReturnItem{
+			OrderItemID: item.ID,
+			BookID:      item.Book.ID,
+			Title:       item.Book.Title,
+			Quantity:    requested.Quantity,
+			UnitPrice:   item.Book.Price,
+		})
+		ret.Subtotal += item.Book.Price * float64(requested.Quantity)
+	}
+	ret = priceReturn(ret, order, previous, returnsEverything(order, returned))
+	created, err := s.returnRepo.Create(ret)
+	if err != nil {
+		return models.Return{}, err
+	}
+	s.record(models.AuditCreate, created.ID, nil, created)
+	return created, nil
+}
+
+func (s *ReturnService) GetReturn(id int) (models.Return, error) {
+	ret, err := s.returnRepo.Get(id)
+	if err != nil {
+		return models.Return{}, ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) GetOrderReturns(orderID int) ([]models.Return, error) {
+	if _, err := s.orderRepo.Get(orderID); err != nil {
+		return nil, ErrOrderNotFound
+	}
+	return s.returnRepo.FindByOrder(orderID)
+}
+
+func (s *ReturnService) SearchReturns(query models.SearchCriteria) ([]models.Return, error) {
+	return s.returnRepo.Search(query)
+}
+
+// ApproveReturn refunds the return through the payment of the order, puts
+// the books back in stock and records the copies returned as negative book
+// sales. The return is claimed first so that it is refunded only once.
+func (s *ReturnService) ApproveReturn(id int) (models.Return, error) {
+	ret, err := s.decide(id, models.ReturnApproved, "")
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	if _, err := s.paymentService.Refund(ret.OrderID, ret.RefundAmount, fmt.Sprintf("return-%d", ret.ID)); err != nil {
+		undone := ret
+		undone.Status = models.ReturnRequested
+		undone.DecidedAt = nil
+		if undone, undoErr := s.returnRepo.Update(undone); undoErr != nil {
+			log.Printf("ReturnService.ApproveReturn: return %d: %v", ret.ID, undoErr)
+		} else {
+			s.record(models.AuditUpdate, ret.ID, ret, undone)
+		}
+		return models.Return{}, err
+	}
+
+	order, err := s.orderRepo.Get(ret.OrderID)
+	if err != nil {
+		return models.Return{}, ErrOrderNotFound
+	}
+	restocked := make([]models.OrderItem, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		orderItem, _ := findOrderItem(order, item.OrderItemID)
+		restocked = append(restocked, models.OrderItem{Book: orderItem.Book, Quantity: item.Quantity})
+		sale := models.BookSale{Book: orderItem.Book, Quantity: -item.Quantity, OrderID: order.ID, ReturnID: ret.ID, SoldAt: *ret.DecidedAt}
+		if _, err := s.bookSaleRepo.Create(sale); err != nil {
+			return models.Return{}, err
+		}
+	}
+	s.orderService.releaseStock(restocked, models.StockMovement{Type: models.MovementReturn, OrderID: order.ID, ReturnID: ret.ID})
+	return ret, nil
+}
+
+// RejectReturn closes a return without refund
+func (s *ReturnService) RejectReturn(id int, decision models.ReturnDecision) (models.Return, error) {
+	return s.decide(id, models.ReturnRejected, decision.Reason)
+}
+
+// decide moves a requested return to its final status
+func (s *ReturnService) decide(id int, status string, reason string) (models.Return, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.GetReturn(id)
+		if err != nil {
+			return models.Return{}, err
+		}
+		if current.Status != models.ReturnRequested {
+			return models.Return{}, fmt.Errorf("%w: return %d is %s", ErrReturnStatus, id, current.Status)
+		}
+		now := time.Now()
+		ret := current
+		ret.Status = status
+		ret.RejectionReason = reason
+		ret.DecidedAt = &now
+		updated, err := s.returnRepo.Update(ret)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err == nil {
+			s.record(models.AuditUpdate, id, current, updated)
+		}
+		return updated, err
+	}
+	return models.Return{}, repositories.ErrVersionConflict
+}
+
+// activeReturns lists the returns of an order that were not rejected
+func (s *ReturnService) activeReturns(orderID int) ([]models.Return, error) {
+	returns, err := s.returnRepo.FindByOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	active := returns[:0]
+	for _, ret := range returns {
+		if ret.Status != models.ReturnRejected {
+			active = append(active, ret)
+		}
+	}
+	return active, nil
+}
+
+// priceReturn gives the returned items their share of the order discounts
+// and taxes, in proportion to their price. The return completing the order
+// gets what the previous ones left, so rounding never refunds a cent more
+// than was paid.
+func priceReturn(ret models.Return, order models.Order, previous []models.Return, last bool) models.Return {
+	ret.Subtotal = roundPrice(ret.Subtotal)
+	if last {
+		ret.DiscountTotal, ret.TaxTotal = order.DiscountTotal, order.TaxTotal
+		for _, other := range previous {
+			ret.DiscountTotal -= other.DiscountTotal
+			ret.TaxTotal -= other.TaxTotal
+		}
+		ret.DiscountTotal = roundPrice(ret.DiscountTotal)
+		ret.TaxTotal = roundPrice(ret.TaxTotal)
+	} else if order.Subtotal > 0 {
+		share := ret.Subtotal / order.Subtotal
+		ret.DiscountTotal = roundPrice(order.DiscountTotal * share)
+		ret.TaxTotal = roundPrice(order.TaxTotal * share)
+	}
+	ret.RefundAmount = roundPrice(ret.Subtotal - ret.DiscountTotal + ret.TaxTotal)
+	return ret
+}
+
+// returnsEverything tells whether the returned quantities cover every item
+func returnsEverything(order models.Order, returned map[int]int) bool {
+	for _, item := range order.Items {
+		if returned[item.ID] < item.Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+func findOrderItem(order models.Order, itemID int) (models.OrderItem, bool) {
+	for _, item := range order.Items {
+		if item.ID == itemID {
+			return item, true
+		}
+	}
+	return models.OrderItem{}, false
+}
+
+func (s *ReturnService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityReturn, id, action, before, after)
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"bookstore.com/models"
+)
+
+func TestPriceReturn(t *testing.T) {
+	// 100 of books less a 10 discount, taxed 10% on the discounted 90
+	order := models.Order{Subtotal: 100, DiscountTotal: 10, TaxTotal: 9}
+	// 30 of books less 10, taxed 20% on the discounted 20, returned in thirds
+	thirds := models.Order{Subtotal: 30, DiscountTotal: 10, TaxTotal: 4}
+	third := models.Return{Subtotal: 10, DiscountTotal: 3.33, TaxTotal: 1.33}
+
+	tests := []struct {
+		name     string
+		ret      models.Return
+		order    models.Order
+		previous []models.Return
+		last     bool
+		want     models.Return
+	}{
+		{
+			name:  "part of the order gets its share",
+			ret:   models.Return{Subtotal: 30},
+			order: order,
+			want:  models.Return{Subtotal: 30, DiscountTotal: 3, TaxTotal: 2.7, RefundAmount: 29.7},
+		},
+		{
+			name:  "the whole order at once",
+			ret:   models.Return{Subtotal: 100},
+			order: order,
+			last:  true,
+			want:  models.Return{Subtotal: 100, DiscountTotal: 10, TaxTotal: 9, RefundAmount: 99},
+		},
+		{
+			name:     "the last return gets what the others left",
+			ret:      models.Return{Subtotal: 70},
+			order:    order,
+			previous: []models.Return{{Subtotal: 30, DiscountTotal: 3, TaxTotal: 2.7}},
+			last:     true,
+			want:     models.Return{Subtotal: 70, DiscountTotal: 7, TaxTotal: 6.3, RefundAmount: 69.3},
+		},
+		{
+			name:     "rounded shares",
+			ret:      models.Return{Subtotal: 10},
+			order:    thirds,
+			previous: []models.Return{third},
+			want:     models.Return{Subtotal: 10, DiscountTotal: 3.33, TaxTotal: 1.33, RefundAmount: 8},
+		},
+		{
+			name:     "the last return takes the rounding difference",
+			ret:      models.Return{Subtotal: 10},
+			order:    thirds,
+			previous: []models.Return{third, third},
+			last:     true,
+			want:     models.Return{Subtotal: 10, DiscountTotal: 3.34, TaxTotal: 1.34, RefundAmount: 8},
+		},
+		{
+			name:  "free order",
+			ret:   models.Return{Subtotal: 0},
+			order: models.Order{},
+			want:  models.Return{},
+		},
+		{
+			name:  "subtotal is rounded to the cent",
+			ret:   models.Return{Subtotal: 10.004},
+			order: models.Order{Subtotal: 20},
+			want:  models.Return{Subtotal: 10, RefundAmount: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := priceReturn(tt.ret, tt.order, tt.previous, tt.last)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("priceReturn() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceReturnNeverRefundsMoreThanPaid(t *testing.T) {
+	order := models.Order{Subtotal: 29.97, DiscountTotal: 5, TaxTotal: 2.5}
+	order.TotalPrice = order.Subtotal - order.DiscountTotal + order.TaxTotal
+
+	var previous []models.Return
+	var refunded float64
+	for i := 0; i < 3; i++ {
+		ret := priceReturn(models.Return{Subtotal: 9.99}, order, previous, i == 2)
+		previous = append(previous, ret)
+		refunded += ret.RefundAmount
+	}
+	if roundPrice(refunded) != roundPrice(order.TotalPrice) {
+		t.Errorf("refunded %v in total, want %v", roundPrice(refunded), roundPrice(order.TotalPrice))
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrReviewNotOwned is returned when a customer edits the review of
+	// another one
+	ErrReviewNotOwned = errors.New("review written by another customer")
+)
+
+type ReviewService struct {
+	reviewRepo   repositories.ReviewStore
+	bookRepo     repositories.BookStore
+	customerRepo repositories.CustomerStore
+	orderRepo    repositories.OrderStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewReviewService(repo repositories.ReviewStore) *ReviewService {
+	return &ReviewService{
+		reviewRepo:   repo,
+		bookRepo:     memory.NewInMemoryBookStore(),
+		customerRepo: memory.NewInMemoryCustomerStore(),
+		orderRepo:    memory.NewInMemoryOrderStore(),
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *ReviewService) As(actor string) *ReviewService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+// CreateReview adds the review of a customer on a book, flagged as a
+// verified purchase when the customer ordered the book, and updates the
+// rating of the book
+func (s *ReviewService) CreateReview(bookID int, review models.Review) (models.Review, error) {
+	if err := validateReview(review); err != nil {
+		return models.Review{}, err
+	}
+	if _, err := s.bookRepo.Get(bookID); err != nil {
+		return models.Review{}, ErrBookNotFound
+	}
+	if _, err := s.customerRepo.Get(review.CustomerID); err != nil {
+		return models.Review{}, invalid("customer %d not found", review.CustomerID)
+	}
+
+	verified, err := s.purchased(review.CustomerID, bookID)
+	if err != nil {
+		return models.Review{}, err
+	}
+	review.BookID = bookID
+	review.Text = strings.TrimSpace(review.Text)
+	review.VerifiedPurchase = verified
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+
+	created, err := s.reviewRepo.Create(review)
+	if err != nil {
+		return models.Review{}, err
+	}
+	s.record(models.AuditCreate, created.ID, nil, created)
+	return created, s.refreshRating(bookID)
+}
+
+// GetReviews returns a page of the reviews of a book, newest first
+func (s *ReviewService) GetReviews(bookID int, page, perPage int) (models.ReviewPage, error) {
+	if page < 1 || perPage < 1 || perPage > maxPerPage {
+		return models.ReviewPage{}, invalid("page must be at least 1 and per_page between 1 and %d", maxPerPage)
+	}
+	if _, err := s.bookRepo.Get(bookID); err != nil {
+		return models.ReviewPage{}, ErrBookNotFound
+	}
+	reviews, err := s.reviewRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"book_id": bookID}})
+	if err != nil {
+		return models.ReviewPage{}, err
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].CreatedAt.After(reviews[j].CreatedAt) })
+
+	first, last := pageBounds(page, perPage, len(reviews))
+	return models.ReviewPage{
+		Reviews:    append([]models.Review{}, reviews[first:last]...),
+		Pagination: models.Pagination{Page: page, PerPage: perPage, Total: len(reviews)},
+	}, nil
+}
+
+// UpdateReview changes the rating and text of a review; only the customer
+// who wrote it may. The verified purchase flag is checked again.
+func (s *ReviewService) UpdateReview(bookID int, review models.Review) (models.Review, error) {
+	if err := validateReview(review); err != nil {
+		return models.Review{}, err
+	}
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.getReview(bookID, review.ID)
+		if err != nil {
+			return models.Review{}, err
+		}
+		if current.CustomerID != review.CustomerID {
+			return models.Review{}, ErrReviewNotOwned
+		}
+		verified, err := s.purchased(current.CustomerID, bookID)
+		if err != nil {
+			return models.Review{}, err
+		}
+
+		updated := current
+		updated.Rating = review.Rating
+		updated.Text = strings.TrimSpace(review.Text)
+		updated.VerifiedPurchase = verified
+		updated.UpdatedAt = time.Now()
+		updated, err = s.reviewRepo.Update(updated)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Review{}, err
+		}
+		s.record(models.AuditUpdate, updated.ID, current, updated)
+		return updated, s.refreshRating(bookID)
+	}
+	return models.Review{}, repositories.ErrVersionConflict
+}
+
+// DeleteReview removes a review for good and updates the rating of the book
+func (s *ReviewService) DeleteReview(bookID, id int) error {
+	before, err := s.getReview(bookID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.reviewRepo.Delete(id); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	return s.refreshRating(bookID)
+}
+
+// getReview returns a review of a book
+func (s *ReviewService) getReview(bookID, id int) (models.Review, error) {
+	review, err := s.reviewRepo.Get(id)
+	if err != nil || review.BookID != bookID {
+		return models.Review{}, ErrReviewNotFound
+	}
+	return review, nil
+}
+
+// purchased reports whether a customer ordered a book, leaving out the
+// orders whose copies went back to stock unpaid
+func (s *ReviewService) purchased(customerID, bookID int) (bool, error) {
+	orders, err := s.orderRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{
+		"customer_id": customerID,
+		"book_id":     bookID,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(orders, func(order models.Order) bool {
+		return !slices.Contains(models.ReleasedOrderStatuses, order.Status)
+	}), nil
+}
+
+// refreshRating works out the average rating and the number of reviews of
+// a book again from its reviews and saves them on the book
+func (s *ReviewService) refreshRating(bookID int) error {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		reviews, err := s.reviewRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"book_id": bookID}})
+		if err != nil {
+			return err
+		}
+		book, err := s.bookRepo.Get(bookID)
+		if err != nil {
+			return err
+		}
+
+		total := 0
+		for _, review := range reviews {
+			total += review.Rating
+		}
+		book.RatingCount, book.AverageRating = len(reviews), 0
+		if len(reviews) > 0 {
+			book.AverageRating = math.Round(float64(total)/float64(len(reviews))*100) / 100
+		}
+		_, err = s.bookRepo.Update(book)
+		if !errors.Is(err, repositories.ErrVersionConflict) {
+			return err
+		}
+	}
+	return repositories.ErrVersionConflict
+}
+
+func (s *ReviewService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityReview, id, action, before, after)
+}
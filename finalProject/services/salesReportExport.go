@@ -0,0 +1,242 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore.com/models"
+)
+
+const (
+	FormatJSON = "json"
+	FormatHTML = "html"
+	// FormatExcel is CSV that spreadsheets open as is, with a byte order
+	// mark so they read it as UTF-8
+	FormatExcel = "excel"
+)
+
+// reportSection is a table of a sales report. The CSV and HTML exports both
+// write the sections of salesReportSections, so they share their columns.
+type reportSection struct {
+	Title   string
+	Columns []string
+	Rows    [][]string
+}
+
+// ExportSalesReport writes a report as CSV, spreadsheet flavoured CSV or a
+// printable HTML page
+func (s *SalesReportService) ExportSalesReport(w io.Writer, report models.SalesReport, format string) error {
+	sections := salesReportSections(report)
+	switch format {
+	case FormatCSV:
+		return writeReportCSV(w, sections)
+	case FormatExcel:
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+		return writeReportCSV(w, sections)
+	case FormatHTML:
+		return salesReportPage.Execute(w, struct {
+			Report   models.SalesReport
+			Sections []reportSection
+		}{report, sections})
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// writeReportCSV writes every section as its title, its header and its
+// rows, followed by an empty line. Whatever the flavour, the CSV ends up in
+// spreadsheets, so the text cells are escaped as they would be run as
+// formulas otherwise.
+func writeReportCSV(w io.Writer, sections []reportSection) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	for _, section := range sections {
+		if err := writer.Write([]string{section.Title}); err != nil {
+			return err
+		}
+		if err := writer.Write(section.Columns); err != nil {
+			return err
+		}
+		for _, row := range section.Rows {
+			if err := writer.Write(spreadsheetSafe(row)); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(nil); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// spreadsheetSafe quotes the text cells a spreadsheet would run as a formula
+func spreadsheetSafe(row []string) []string {
+	safe := make([]string, len(row))
+	for i, cell := range row {
+		safe[i] = cell
+		if cell == "" || !strings.ContainsAny(cell[:1], "=+-@\t\r") {
+			continue
+		}
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			safe[i] = "'" + cell
+		}
+	}
+	return safe
+}
+
+// spreadsheetText undoes spreadsheetSafe on a cell read back from a file
+func spreadsheetText(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsAny(cell[1:2], "=+-@\t\r") {
+		return cell[1:]
+	}
+	return cell
+}
+
+func salesReportSections(report models.SalesReport) []reportSection {
+	period := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	revenue, margin := report.Revenue, report.Margin
+
+	sections := []reportSection{
+		{
+			Title:   "Summary",
+			Columns: []string{"metric", "value"},
+			Rows: [][]string{
+				{"timestamp", report.Timestamp.Format(time.RFC3339)},
+				{"from", period(report.From)},
+				{"to", period(report.To)},
+				{"total_revenue", money(report.TotalRevenue)},
+				{"total_orders", strconv.Itoa(report.TotalOrders)},
+				{"total_returns", strconv.Itoa(report.TotalReturns)},
+				{"average_order_value", money(report.AverageOrderValue)},
+			},
+		},
+		{
+			Title:   "Revenue",
+			Columns: []string{"metric", "amount"},
+			Rows: [][]string{
+				{"subtotal", money(revenue.Subtotal)},
+				{"discounts", money(revenue.Discounts)},
+				{"returns", money(revenue.Returns)},
+				{"net_sales", money(revenue.NetSales)},
+				{"shipping", money(revenue.Shipping)},
+				{"tax", money(revenue.Tax)},
+				{"tax_refunded", money(revenue.TaxRefunded)},
+			},
+		},
+		{
+			Title:   "Shipping by zone",
+			Columns: []string{"zone", "amount"},
+			Rows:    amountRows(revenue.ShippingByZone),
+		},
+		{
+			Title:   "Tax by jurisdiction",
+			Columns: []string{"jurisdiction", "amount"},
+			Rows:    amountRows(revenue.TaxByJurisdiction),
+		},
+		{
+			Title:   "Margin",
+			Columns: []string{"metric", "value"},
+			Rows: [][]string{
+				{"net_sales", money(margin.NetSales)},
+				{"cost_of_goods", money(margin.CostOfGoods)},
+				{"gross_margin", money(margin.GrossMargin)},
+				{"gross_margin_percent", money(margin.GrossMarginPercent)},
+				{"uncosted_quantity", strconv.Itoa(margin.UncostedQuantity)},
+			},
+		},
+	}
+
+	genres := reportSection{Title: "Revenue by genre", Columns: []string{"genre", "quantity", "net_sales"}}
+	for _, genre := range report.RevenueByGenre {
+		genres.Rows = append(genres.Rows, []string{genre.Genre, strconv.Itoa(genre.Quantity), money(genre.NetSales)})
+	}
+	authors := reportSection{Title: "Revenue by author", Columns: []string{"author_id", "name", "quantity", "net_sales"}}
+	for _, author := range report.RevenueByAuthor {
+		authors.Rows = append(authors.Rows, []string{strconv.Itoa(author.AuthorID), author.Name, strconv.Itoa(author.Quantity), money(author.NetSales)})
+	}
+	books := reportSection{Title: "Top selling books", Columns: []string{"book_id", "title", "price", "quantity_sold"}}
+	for _, sale := range report.TopSellingBooks {
+		books.Rows = append(books.Rows, []string{strconv.Itoa(sale.Book.ID), sale.Book.Title, money(sale.Book.Price), strconv.Itoa(sale.Quantity)})
+	}
+	customers := reportSection{Title: "Top customers", Columns: []string{"customer_id", "name", "orders", "total_spent"}}
+	for _, customer := range report.TopCustomers {
+		customers.Rows = append(customers.Rows, []string{strconv.Itoa(customer.CustomerID), customer.Name, strconv.Itoa(customer.Orders), money(customer.TotalSpent)})
+	}
+	return append(sections, genres, authors, books, customers)
+}
+
+// amountRows lists the amounts of a breakdown ordered by name
+func amountRows(amounts map[string]float64) [][]string {
+	names := make([]string, 0, len(amounts))
+	for name := range amounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []string{name, money(amounts[name])})
+	}
+	return rows
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+var salesReportPage = template.Must(template.New("salesReport").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Sales report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+.period { color: #555; margin-top: .3em; }
+section { break-inside: avoid; margin-top: 1.5em; }
+table { border-collapse: collapse; min-width: 24em; }
+th, td { border: 1px solid #bbb; padding: .25em .6em; text-align: left; }
+th { background: #eee; }
+.empty { color: #777; font-style: italic; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Sales report</h1>
+<p class="period">
+{{- if .Report.From}}From {{.Report.From.Format "2006-01-02 15:04"}} {{else}}From the first order {{end}}
+{{- if .Report.To}}to {{.Report.To.Format "2006-01-02 15:04"}}{{else}}to now{{end}},
+generated {{.Report.Timestamp.Format "2006-01-02 15:04 MST"}}</p>
+{{- range .Sections}}
+<section>
+<h2>{{.Title}}</h2>
+{{- if .Rows}}
+<table>
+<thead><tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p class="empty">Nothing in this period</p>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+// topCustomersLimit is the number of customers listed in a sales report
+const topCustomersLimit = 10
+
+type SalesReportService struct {
+	reportRepo repositories.SalesReportStore
+	orderRepo  repositories.OrderStore
+	returnRepo repositories.ReturnStore
+	authorRepo repositories.AuthorStore
+}
+
+func NewSalesReportService(repo repositories.SalesReportStore) *SalesReportService {
+	return &SalesReportService{
+		reportRepo: repo,
+		orderRepo:  memory.NewInMemoryOrderStore(),
+		returnRepo: memory.NewInMemoryReturnStore(),
+		authorRepo: memory.NewInMemoryAuthorStore(),
+	}
+}
+
+// GenerateSalesReport sums up the orders created in [from, to), a zero bound
+// being open, takes off the returns approved in that period and keeps the
+// report in the store. Books are counted at the price charged when they were
+// ordered, less their share of the order discounts.
+func (s *SalesReportService) GenerateSalesReport(from, to time.Time) (models.SalesReport, error) {
+	orders, err := s.orderRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+
+	returns, err := s.returnRepo.Search(models.SearchCriteria{
+		Filters: map[string]interface{}{"status": models.ReturnApproved},
+	})
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+
+	report := models.SalesReport{Timestamp: time.Now()}
+	if !from.IsZero() {
+		report.From = &from
+	}
+	if !to.IsZero() {
+		report.To = &to
+	}
+	revenue := &report.Revenue
+	revenue.ShippingByZone = make(map[string]float64)
+	revenue.TaxByJurisdiction = make(map[string]float64)
+	tally := newSalesTally()
+	ordersByID := make(map[int]models.Order, len(orders))
+
+	inPeriod := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+
+	for _, order := range orders {
+		ordersByID[order.ID] = order
+		if !inPeriod(order.CreatedAt) || slices.Contains(models.ReleasedOrderStatuses, order.Status) {
+			continue
+		}
+		report.TotalOrders++
+		report.TotalRevenue += order.TotalPrice
+		revenue.Subtotal += order.Subtotal
+		revenue.Discounts += order.DiscountTotal
+		revenue.Shipping += order.Shipping.Amount
+		if order.Shipping.Zone != "" {
+			revenue.ShippingByZone[order.Shipping.Zone] += order.Shipping.Amount
+		}
+		for _, line := range order.TaxLines {
+			revenue.Tax += line.Amount
+			revenue.TaxByJurisdiction[line.Jurisdiction] += line.Amount
+		}
+
+		for _, item := range order.Items {
+			subtotal := item.Book.Price * float64(item.Quantity)
+			tally.sell(item.Book, item.Quantity, subtotal-shareOf(order.DiscountTotal, subtotal, order.Subtotal))
+		}
+		tally.spend(order.Customer, 1, order.TotalPrice)
+	}
+	if report.TotalOrders > 0 {
+		report.AverageOrderValue = roundPrice(report.TotalRevenue / float64(report.TotalOrders))
+	}
+
+	for _, ret := range returns {
+		if ret.DecidedAt == nil || !inPeriod(*ret.DecidedAt) {
+			continue
+		}
+		report.TotalReturns++
+		report.TotalRevenue -= ret.RefundAmount
+		revenue.Returns += ret.Subtotal - ret.DiscountTotal
+		revenue.TaxRefunded += ret.TaxTotal
+
+		order, found := ordersByID[ret.OrderID]
+		for _, item := range ret.Items {
+			book := models.Book{ID: item.BookID, Title: item.Title, Price: item.UnitPrice}
+			if orderItem, exists := findOrderItem(order, item.OrderItemID); exists {
+				book = orderItem.Book
+			}
+			subtotal := item.UnitPrice * float64(item.Quantity)
+			tally.sell(book, -item.Quantity, -(subtotal - shareOf(ret.DiscountTotal, subtotal, ret.Subtotal)))
+		}
+		if found {
+			tally.spend(order.Customer, 0, -ret.RefundAmount)
+		}
+	}
+
+	report.TotalRevenue = roundPrice(report.TotalRevenue)
+	revenue.Subtotal = roundPrice(revenue.Subtotal)
+	revenue.Discounts = roundPrice(revenue.Discounts)
+	revenue.Returns = roundPrice(revenue.Returns)
+	revenue.NetSales = roundPrice(revenue.Subtotal - revenue.Discounts - revenue.Returns)
+	revenue.Shipping = roundPrice(revenue.Shipping)
+	revenue.Tax = roundPrice(revenue.Tax)
+	revenue.TaxRefunded = roundPrice(revenue.TaxRefunded)
+	for zone, amount := range revenue.ShippingByZone {
+		revenue.ShippingByZone[zone] = roundPrice(amount)
+	}
+	for jurisdiction, amount := range revenue.TaxByJurisdiction {
+		revenue.TaxByJurisdiction[jurisdiction] = roundPrice(amount)
+	}
+
+	margin := &report.Margin
+	margin.NetSales = revenue.NetSales
+	margin.CostOfGoods = roundPrice(tally.costOfGoods)
+	margin.GrossMargin = roundPrice(margin.NetSales - margin.CostOfGoods)
+	if margin.NetSales != 0 {
+		margin.GrossMarginPercent = math.Round(margin.GrossMargin/margin.NetSales*10000) / 100
+	}
+	margin.UncostedQuantity = tally.uncosted
+
+	report.TopSellingBooks = tally.topSellingBooks()
+	report.RevenueByGenre = tally.genreRevenue()
+	report.RevenueByAuthor = tally.authorRevenue()
+	for i, author := range report.RevenueByAuthor {
+		if stored, err := s.authorRepo.Get(author.AuthorID); err == nil {
+			report.RevenueByAuthor[i].Name = authorName(stored)
+		}
+	}
+	report.TopCustomers = tally.topCustomers(topCustomersLimit)
+
+	return s.reportRepo.Create(report)
+}
+
+// salesTally adds up the copies sold and their net sales by book, genre,
+// author and customer, along with the cost of the copies
+type salesTally struct {
+	books       map[int]*models.BookSale
+	genres      map[string]*models.GenreRevenue
+	authors     map[int]*models.AuthorRevenue
+	customers   map[int]*models.CustomerSpend
+	costOfGoods float64
+	uncosted    int
+}
+
+func newSalesTally() *salesTally {
+	return &salesTally{
+		books:     make(map[int]*models.BookSale),
+		genres:    make(map[string]*models.GenreRevenue),
+		authors:   make(map[int]*models.AuthorRevenue),
+		customers: make(map[int]*models.CustomerSpend),
+	}
+}
+
+// sell records copies of a book sold for netSales; returned copies are
+// recorded with a negative quantity and amount
+func (t *salesTally) sell(book models.Book, quantity int, netSales float64) {
+	if sale, exists := t.books[book.ID]; exists {
+		sale.Quantity += quantity
+	} else {
+		t.books[book.ID] = &models.BookSale{Book: book, Quantity: quantity}
+	}
+
+	for _, genre := range book.Genres {
+		revenue, exists := t.genres[genre]
+		if !exists {
+			revenue = &models.GenreRevenue{Genre: genre}
+			t.genres[genre] = revenue
+		}
+		revenue.Quantity += quantity
+		revenue.NetSales += netSales
+	}
+
+	if book.Author.ID != 0 {
+		revenue, exists := t.authors[book.Author.ID]
+		if !exists {
+			revenue = &models.AuthorRevenue{AuthorID: book.Author.ID, Name: authorName(book.Author)}
+			t.authors[book.Author.ID] = revenue
+		}
+		revenue.Quantity += quantity
+		revenue.NetSales += netSales
+	}
+
+	if book.CostPrice > 0 {
+		t.costOfGoods += book.CostPrice * float64(quantity)
+	} else {
+		t.uncosted += quantity
+	}
+}
+
+// spend records what a customer paid for orders, or got refunded with a
+// negative amount
+func (t *salesTally) spend(customer models.Customer, orders int, amount float64) {
+	spend, exists := t.customers[customer.ID]
+	if !exists {
+		spend = &models.CustomerSpend{CustomerID: customer.ID, Name: customer.Name}
+		t.customers[customer.ID] = spend
+	}
+	spend.Orders += orders
+	spend.TotalSpent += amount
+}
+
+// topSellingBooks lists the books by copies sold, most first
+func (t *salesTally) topSellingBooks() []models.BookSale {
+	sales := make([]models.BookSale, 0, len(t.books))
+	for _, sale := range t.books {
+		sales = append(sales, *sale)
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		if sales[i].Quantity != sales[j].Quantity {
+			return sales[i].Quantity > sales[j].Quantity
+		}
+		return sales[i].Book.ID < sales[j].Book.ID
+	})
+	return sales
+}
+
+// genreRevenue lists the genres by net sales, most first
+func (t *salesTally) genreRevenue() []models.GenreRevenue {
+	genres := make([]models.GenreRevenue, 0, len(t.genres))
+	for _, revenue := range t.genres {
+		revenue.NetSales = roundPrice(revenue.NetSales)
+		genres = append(genres, *revenue)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if genres[i].NetSales != genres[j].NetSales {
+			return genres[i].NetSales > genres[j].NetSales
+		}
+		return genres[i].Genre < genres[j].Genre
+	})
+	return genres
+}
+
+// authorRevenue lists the authors by net sales, most first
+func (t *salesTally) authorRevenue() []models.AuthorRevenue {
+	authors := make([]models.AuthorRevenue, 0, len(t.authors))
+	for _, revenue := range t.authors {
+		revenue.NetSales = roundPrice(revenue.NetSales)
+		authors = append(authors, *revenue)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].NetSales != authors[j].NetSales {
+			return authors[i].NetSales > authors[j].NetSales
+		}
+		return authors[i].AuthorID < authors[j].AuthorID
+	})
+	return authors
+}
+
+// topCustomers lists the limit customers who spent the most
+func (t *salesTally) topCustomers(limit int) []models.CustomerSpend {
+	customers := make([]models.CustomerSpend, 0, len(t.customers))
+	for _, spend := range t.customers {
+		spend.TotalSpent = roundPrice(spend.TotalSpent)
+		customers = append(customers, *spend)
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		if customers[i].TotalSpent != customers[j].TotalSpent {
+			return customers[i].TotalSpent > customers[j].TotalSpent
+		}
+		return customers[i].CustomerID < customers[j].CustomerID
+	})
+	return customers[:min(limit, len(customers))]
+}
+
+// shareOf returns the part of total that amount stands for out of whole
+func shareOf(total, amount, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return total * amount / whole
+}
+
+func authorName(author models.Author) string {
+	return strings.TrimSpace(author.FirstName + " " + author.LastName)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"bookstore.com/models"
+)
+
+// ShippingCalculator works out the shipping charge of a priced order
+type ShippingCalculator interface {
+	Shipping(order models.Order) (models.ShippingLine, error)
+}
+
+// ShippingZone prices shipping to a group of countries; "*" matches any
+// country not listed in another zone. Shipping is free when the order,
+// after discounts, reaches FreeOver.
+type ShippingZone struct {
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+	Base      float64  `json:"base"`
+	PerItem   float64  `json:"per_item"`
+	PerKg     float64  `json:"per_kg"`
+	FreeOver  float64  `json:"free_over,omitempty"`
+}
+
+// ShippingZones is a ShippingCalculator charging a base fee plus a fee per
+// copy and per kilogram, depending on the zone of the shipping country.
+// Books without a weight count as DefaultWeightGrams. The zero value ships
+// everything for free.
+type ShippingZones struct {
+	DefaultWeightGrams int            `json:"default_weight_grams"`
+	Zones              []ShippingZone `json:"zones"`
+}
+
+// LoadShippingZones reads shipping zones from a JSON file
+func LoadShippingZones(path string) (*ShippingZones, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var zones ShippingZones
+	if err := json.Unmarshal(data, &zones); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, zone := range zones.Zones {
+		if zone.Base < 0 || zone.PerItem < 0 || zone.PerKg < 0 || zone.FreeOver < 0 {
+			return nil, fmt.Errorf("%s: zone %q has a negative fee", path, zone.Name)
+		}
+	}
+	return &zones, nil
+}
+
+func (z *ShippingZones) Shipping(order models.Order) (models.ShippingLine, error) {
+	if len(z.Zones) == 0 {
+		return models.ShippingLine{}, nil
+	}
+	country := strings.TrimSpace(order.Customer.Address.Country)
+	zone, found := z.zone(country)
+	if !found {
+		return models.ShippingLine{}, invalid("no shipping to country %q", country)
+	}
+
+	line := models.ShippingLine{Zone: zone.Name}
+	quantity := 0
+	for _, item := range order.Items {
+		weight := item.Book.WeightGrams
+		if weight == 0 {
+			weight = z.DefaultWeightGrams
+		}
+		line.WeightGrams += weight * item.Quantity
+		quantity += item.Quantity
+	}
+	if zone.FreeOver > 0 && order.Subtotal-order.DiscountTotal >= zone.FreeOver {
+		return line, nil
+	}
+	line.Amount = roundPrice(zone.Base + zone.PerItem*float64(quantity) + zone.PerKg*float64(line.WeightGrams)/1000)
+	return line, nil
+}
+
+// zone finds the zone listing the country, falling back to the "*" zone
+func (z *ShippingZones) zone(country string) (ShippingZone, bool) {
+	var fallback ShippingZone
+	hasFallback := false
+	for _, zone := range z.Zones {
+		for _, listed := range zone.Countries {
+			if listed == "*" && !hasFallback {
+				fallback, hasFallback = zone, true
+			} else if country != "" && strings.EqualFold(listed, country) {
+				return zone, true
+			}
+		}
+	}
+	return fallback, hasFallback
+}
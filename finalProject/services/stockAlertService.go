@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+const (
+	// salesVelocityWindow is the period of recent sales the daily sales of a
+	// book are averaged over
+	salesVelocityWindow = 30 * 24 * time.Hour
+	// reorderCoverage is how long the suggested reorder quantity should last
+	// at the recent pace of sales, on top of the reorder threshold
+	reorderCoverage = 30 * 24 * time.Hour
+)
+
+// StockAlertService raises an alert when the stock of a book drops below its
+// reorder threshold and clears it once the book is restocked
+type StockAlertService struct {
+	alertRepo    repositories.StockAlertStore
+	bookRepo     repositories.BookStore
+	bookSaleRepo repositories.BookSaleStore
+}
+
+func NewStockAlertService(repo repositories.StockAlertStore) *StockAlertService {
+	return &StockAlertService{
+		alertRepo:    repo,
+		bookRepo:     memory.NewInMemoryBookStore(),
+		bookSaleRepo: memory.NewInMemoryBookSaleStore(),
+	}
+}
+
+// GetAlerts returns the open alerts with the current stock of the books and
+// reorder quantities suggested from their recent sales
+func (s *StockAlertService) GetAlerts(now time.Time) ([]models.StockAlert, error) {
+	alerts, err := s.alertRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return nil, err
+	}
+	for i, alert := range alerts {
+		book, err := s.bookRepo.Get(alert.BookID)
+		if err != nil {
+			continue
+		}
+		if alerts[i], err = s.suggest(alert, book, now); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
+}
+
+// CheckBook raises or refreshes the alert of a book whose stock is below its
+// threshold, and clears it otherwise. Deleted books lose their alert.
+func (s *StockAlertService) CheckBook(bookID int, now time.Time) error {
+	book, err := s.bookRepo.Get(bookID)
+	if err != nil || book.ReorderThreshold == 0 || book.Stock >= book.ReorderThreshold {
+		return s.alertRepo.Delete(bookID)
+	}
+
+	alert, err := s.alertRepo.Get(bookID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		alert = models.StockAlert{BookID: bookID, RaisedAt: now}
+		log.Printf("StockAlertService.CheckBook: book %d %q is low on stock: %d left, threshold %d",
+			book.ID, book.Title, book.Stock, book.ReorderThreshold)
+	} else if err != nil {
+		return err
+	}
+	alert, err = s.suggest(alert, book, now)
+	if err != nil {
+		return err
+	}
+	_, err = s.alertRepo.Save(alert)
+	return err
+}
+
+// CheckAll checks every book, such as the books loaded at startup. A book
+// that fails is logged and skipped so the others still get checked.
+func (s *StockAlertService) CheckAll(now time.Time) error {
+	books, err := s.bookRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return err
+	}
+	alerts, err := s.alertRepo.Search(models.SearchCriteria{})
+	if err != nil {
+		return err
+	}
+	bookIDs := make(map[int]bool)
+	for _, book := range books {
+		bookIDs[book.ID] = true
+	}
+	for _, alert := range alerts {
+		bookIDs[alert.BookID] = true
+	}
+	for bookID := range bookIDs {
+		if err := s.CheckBook(bookID, now); err != nil {
+			log.Printf("StockAlertService.CheckAll: book %d: %v", bookID, err)
+		}
+	}
+	return nil
+}
+
+// suggest fills the alert from the book and suggests reordering enough
+// copies to get back to the threshold and cover the sales expected over
+// reorderCoverage at the pace of the last salesVelocityWindow
+func (s *StockAlertService) suggest(alert models.StockAlert, book models.Book, now time.Time) (models.StockAlert, error) {
+	sales, err := s.bookSaleRepo.Search(models.SearchCriteria{Filters: map[string]interface{}{"book_id": book.ID}})
+	if err != nil {
+		return models.StockAlert{}, err
+	}
+	sold := 0
+	since := now.Add(-salesVelocityWindow)
+	for _, sale := range sales {
+		if !sale.SoldAt.Before(since) && !sale.SoldAt.After(now) {
+			sold += sale.Quantity
+		}
+	}
+
+	alert.Title = book.Title
+	alert.Stock = book.Stock
+	alert.ReorderThreshold = book.ReorderThreshold
+	dailySales := max(float64(sold), 0) / salesVelocityWindow.Hours() * 24
+	alert.DailySales = math.Round(dailySales*100) / 100
+	expected := int(math.Ceil(dailySales * reorderCoverage.Hours() / 24))
+	alert.SuggestedQuantity = max(book.ReorderThreshold+expected-book.Stock, 0)
+	return alert, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+type SupplierService struct {
+	supplierRepo repositories.SupplierStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewSupplierService(repo repositories.SupplierStore) *SupplierService {
+	return &SupplierService{supplierRepo: repo, auditLog: NewAuditService(memory.NewInMemoryAuditStore())}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *SupplierService) As(actor string) *SupplierService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+func (s *SupplierService) CreateSupplier(supplier models.Supplier) (models.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return models.Supplier{}, err
+	}
+	created, err := s.supplierRepo.Create(supplier)
+	if err == nil {
+		s.record(models.AuditCreate, created.ID, nil, created)
+	}
+	return created, err
+}
+
+func (s *SupplierService) GetSupplier(id int) (models.Supplier, error) {
+	return s.supplierRepo.Get(id)
+}
+
+func (s *SupplierService) UpdateSupplier(supplier models.Supplier) (models.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return models.Supplier{}, err
+	}
+	before, _ := s.supplierRepo.Get(supplier.ID)
+	updated, err := s.supplierRepo.Update(supplier)
+	if err == nil {
+		s.record(models.AuditUpdate, updated.ID, before, updated)
+	}
+	return updated, err
+}
+
+// DeleteSupplier soft deletes a supplier. A non-zero version must match
+// the stored one, which the store checks as it deletes.
+func (s *SupplierService) DeleteSupplier(id int, version int) error {
+	before, err := s.supplierRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.supplierRepo.Delete(id, version); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, id, before, nil)
+	return nil
+}
+
+func (s *SupplierService) RestoreSupplier(id int) (models.Supplier, error) {
+	restored, err := s.supplierRepo.Restore(id)
+	if err == nil {
+		s.record(models.AuditRestore, id, nil, restored)
+	}
+	return restored, err
+}
+
+func (s *SupplierService) SearchSuppliers(query models.SearchCriteria) ([]models.Supplier, error) {
+	return s.supplierRepo.Search(query)
+}
+
+func (s *SupplierService) record(action string, id int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntitySupplier, id, action, before, after)
+}
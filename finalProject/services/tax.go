@@ -0,0 +1,78 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"bookstore.com/models"
+)
+
+// TaxCalculator works out the taxes of a priced order, after discounts
+type TaxCalculator interface {
+	Tax(order models.Order) ([]models.TaxLine, error)
+}
+
+// TaxRate is a tax levied in a country, or in one state of it when State is
+// set. Rate is a fraction: 0.0725 for 7.25%.
+type TaxRate struct {
+	Country string  `json:"country"`
+	State   string  `json:"state,omitempty"`
+	Name    string  `json:"name"`
+	Rate    float64 `json:"rate"`
+}
+
+// TaxTable is a TaxCalculator based on a table of rates. An order is taxed
+// with every rate of its shipping country that applies country-wide or to
+// its state, so national and state taxes each get a line. The zero value
+// taxes nothing.
+type TaxTable struct {
+	Rates []TaxRate `json:"rates"`
+}
+
+// LoadTaxTable reads a tax table from a JSON file
+func LoadTaxTable(path string) (*TaxTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var table TaxTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, rate := range table.Rates {
+		if strings.TrimSpace(rate.Country) == "" {
+			return nil, fmt.Errorf("%s: rate %d has no country", path, i)
+		}
+		if rate.Rate < 0 || rate.Rate >= 1 {
+			return nil, fmt.Errorf("%s: rate %d must be a fraction between 0 and 1", path, i)
+		}
+	}
+	return &table, nil
+}
+
+func (t *TaxTable) Tax(order models.Order) ([]models.TaxLine, error) {
+	address := order.Customer.Address
+	taxable := order.Subtotal - order.DiscountTotal
+	var lines []models.TaxLine
+	for _, rate := range t.Rates {
+		if !strings.EqualFold(rate.Country, strings.TrimSpace(address.Country)) {
+			continue
+		}
+		jurisdiction := strings.ToUpper(rate.Country)
+		if rate.State != "" {
+			if !strings.EqualFold(rate.State, strings.TrimSpace(address.State)) {
+				continue
+			}
+			jurisdiction += "-" + strings.ToUpper(rate.State)
+		}
+		lines = append(lines, models.TaxLine{
+			Name:         rate.Name,
+			Jurisdiction: jurisdiction,
+			Rate:         rate.Rate,
+			Amount:       roundPrice(taxable * rate.Rate),
+		})
+	}
+	return lines, nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bookstore.com/models"
+)
+
+// ErrValidation wraps every error caused by an entity failing the checks below
+var ErrValidation = errors.New("validation failed")
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrValidation, fmt.Sprintf(format, args...))
+}
+
+func validateBook(book models.Book) error {
+	if err := validateBookDetails(book); err != nil {
+		return err
+	}
+	if book.Author.ID <= 0 {
+		return invalid("author id is required")
+	}
+	return nil
+}
+
+// validateBookDetails checks everything but the author reference
+func validateBookDetails(book models.Book) error {
+	if strings.TrimSpace(book.Title) == "" {
+		return invalid("title is required")
+	}
+	if book.Price < 0 {
+		return invalid("price cannot be negative")
+	}
+	if book.Stock < 0 {
+		return invalid("stock cannot be negative")
+	}
+	if book.WeightGrams < 0 {
+		return invalid("weight cannot be negative")
+	}
+	if book.CostPrice < 0 {
+		return invalid("cost price cannot be negative")
+	}
+	if book.ReorderThreshold < 0 {
+		return invalid("reorder threshold cannot be negative")
+	}
+	return nil
+}
+
+func validateAuthor(author models.Author) error {
+	if strings.TrimSpace(author.FirstName) == "" && strings.TrimSpace(author.LastName) == "" {
+		return invalid("first or last name is required")
+	}
+	return nil
+}
+
+func validateCustomer(customer models.Customer) error {
+	if strings.TrimSpace(customer.Name) == "" {
+		return invalid("name is required")
+	}
+	if !strings.Contains(customer.Email, "@") {
+		return invalid("a valid email is required")
+	}
+	return nil
+}
+
+func validateOrder(order models.Order) error {
+	if order.Customer.ID <= 0 {
+		return invalid("customer id is required")
+	}
+	if len(order.Items) == 0 {
+		return invalid("an order needs at least one item")
+	}
+	for _, item := range order.Items {
+		if item.Book.ID <= 0 {
+			return invalid("every item needs a book id")
+		}
+		if item.Quantity <= 0 {
+			return invalid("invalid quantity %d for book %d", item.Quantity, item.Book.ID)
+		}
+	}
+	if order.TotalPrice < 0 {
+		return invalid("total price cannot be negative")
+	}
+	return nil
+}
+
+func validatePromotion(promotion models.Promotion) error {
+	if strings.TrimSpace(promotion.Name) == "" {
+		return invalid("name is required")
+	}
+	if strings.ContainsFunc(promotion.Code, unicode.IsSpace) {
+		return invalid("code cannot contain spaces")
+	}
+	switch promotion.Type {
+	case models.PromotionPercentage, models.PromotionAuthorSale:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return invalid("value must be a percentage between 0 and 100")
+		}
+	case models.PromotionFixedAmount:
+		if promotion.Value <= 0 {
+			return invalid("value must be a positive amount")
+		}
+	case models.PromotionBuyXGetY:
+		if strings.TrimSpace(promotion.Genre) == "" {
+			return invalid("genre is required for %s promotions", promotion.Type)
+		}
+		if promotion.BuyQuantity < 1 || promotion.FreeQuantity < 1 {
+			return invalid("buy and free quantities must be at least 1")
+		}
+	default:
+		return invalid("unknown promotion type %q", promotion.Type)
+	}
+	if promotion.Type == models.PromotionAuthorSale && promotion.AuthorID <= 0 {
+		return invalid("author id is required for %s promotions", promotion.Type)
+	}
+	if !promotion.EndsAt.IsZero() && promotion.EndsAt.Before(promotion.StartsAt) {
+		return invalid("the promotion ends before it starts")
+	}
+	if promotion.UsageLimit < 0 || promotion.PerCustomerLimit < 0 {
+		return invalid("usage limits cannot be negative")
+	}
+	return nil
+}
+
+func validateStockMovement(request models.StockMovementRequest) error {
+	switch request.Type {
+	case models.MovementReceipt:
+		if request.Quantity <= 0 {
+			return invalid("a receipt needs a positive quantity")
+		}
+		if request.UnitCost < 0 {
+			return invalid("unit cost cannot be negative")
+		}
+	case models.MovementDamage:
+		if request.Quantity >= 0 {
+			return invalid("damage needs a negative quantity")
+		}
+	case models.MovementAdjustment:
+		if request.Quantity == 0 {
+			return invalid("an adjustment cannot be zero")
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			return invalid("an adjustment needs a reason")
+		}
+	case models.MovementSale, models.MovementReturn, models.MovementCancellation:
+		return invalid("%s movements are recorded by orders and returns", request.Type)
+	default:
+		return invalid("unknown movement type %q", request.Type)
+	}
+	if request.UnitCost != 0 && request.Type != models.MovementReceipt {
+		return invalid("only receipts have a unit cost")
+	}
+	return nil
+}
+
+func validateSupplier(supplier models.Supplier) error {
+	if strings.TrimSpace(supplier.Name) == "" {
+		return invalid("name is required")
+	}
+	if supplier.Email != "" && !strings.Contains(supplier.Email, "@") {
+		return invalid("email is not valid")
+	}
+	if supplier.LeadTimeDays < 0 {
+		return invalid("lead time cannot be negative")
+	}
+	return nil
+}
+
+func validatePurchaseOrder(order models.PurchaseOrder) error {
+	if order.SupplierID <= 0 {
+		return invalid("supplier id is required")
+	}
+	if len(order.Lines) == 0 {
+		return invalid("a purchase order needs at least one line")
+	}
+	books := make(map[int]bool)
+	for _, line := range order.Lines {
+		if line.BookID <= 0 {
+			return invalid("every line needs a book id")
+		}
+		if books[line.BookID] {
+			return invalid("book %d is on several lines", line.BookID)
+		}
+		books[line.BookID] = true
+		if line.Quantity <= 0 {
+			return invalid("invalid quantity %d for book %d", line.Quantity, line.BookID)
+		}
+		if line.UnitCost < 0 {
+			return invalid("unit cost cannot be negative for book %d", line.BookID)
+		}
+	}
+	return nil
+}
+
+// maxReviewLength caps the text of a review, in characters
+const maxReviewLength = 5000
+
+func validateReview(review models.Review) error {
+	if review.Rating < 1 || review.Rating > 5 {
+		return invalid("rating must be between 1 and 5")
+	}
+	if utf8.RuneCountInString(review.Text) > maxReviewLength {
+		return invalid("text is longer than %d characters", maxReviewLength)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"bookstore.com/memory"
+	"bookstore.com/models"
+	"bookstore.com/repositories"
+)
+
+var ErrWishlistItemNotFound = errors.New("book is not in the wishlist")
+
+// WishlistService keeps the books customers want to buy later. The customers
+// are notified when one of them is back in stock, see
+// InventoryService.queueBackInStock.
+type WishlistService struct {
+	wishlistRepo repositories.WishlistStore
+	bookRepo     repositories.BookStore
+	customerRepo repositories.CustomerStore
+	// actor is who the audit log records as making the changes, see As
+	actor    string
+	auditLog *AuditService
+}
+
+func NewWishlistService(repo repositories.WishlistStore) *WishlistService {
+	return &WishlistService{
+		wishlistRepo: repo,
+		bookRepo:     memory.NewInMemoryBookStore(),
+		customerRepo: memory.NewInMemoryCustomerStore(),
+		auditLog:     NewAuditService(memory.NewInMemoryAuditStore()),
+	}
+}
+
+// As returns a copy of the service recording its changes in the audit log
+// on behalf of actor
+func (s *WishlistService) As(actor string) *WishlistService {
+	copied := *s
+	copied.actor = actor
+	return &copied
+}
+
+// GetWishlist returns the wishlist of a customer with the current stock of
+// the books; a customer without a wishlist gets an empty one
+func (s *WishlistService) GetWishlist(customerID int) (models.Wishlist, error) {
+	wishlist, err := s.loadWishlist(customerID)
+	if err != nil {
+		return models.Wishlist{}, err
+	}
+	return s.evaluate(wishlist), nil
+}
+
+// AddItem puts a book in the wishlist; a book already there keeps the date
+// it was first added
+func (s *WishlistService) AddItem(customerID int, request models.WishlistItemRequest) (models.Wishlist, error) {
+	if _, err := s.bookRepo.Get(request.BookID); err != nil {
+		return models.Wishlist{}, invalid("book %d not found", request.BookID)
+	}
+	return s.updateWishlist(customerID, func(wishlist *models.Wishlist) error {
+		if !slices.ContainsFunc(wishlist.Items, func(item models.WishlistItem) bool { return item.BookID == request.BookID }) {
+			wishlist.Items = append(wishlist.Items, models.WishlistItem{BookID: request.BookID, AddedAt: time.Now()})
+		}
+		return nil
+	})
+}
+
+func (s *WishlistService) RemoveItem(customerID int, bookID int) (models.Wishlist, error) {
+	return s.updateWishlist(customerID, func(wishlist *models.Wishlist) error {
+		for i := range wishlist.Items {
+			if wishlist.Items[i].BookID == bookID {
+				wishlist.Items = append(wishlist.Items[:i], wishlist.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrWishlistItemNotFound
+	})
+}
+
+// mergeWishlist adds the books of the wishlist of a customer to the wishlist
+// of another one, keeping the earliest date each was added, and deletes the
+// first wishlist
+func (s *WishlistService) mergeWishlist(fromID, toID int) error {
+	from, err := s.wishlistRepo.Get(fromID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.updateWishlist(toID, func(wishlist *models.Wishlist) error {
+		for _, item := range from.Items {
+			i := slices.IndexFunc(wishlist.Items, func(existing models.WishlistItem) bool { return existing.BookID == item.BookID })
+			if i < 0 {
+				wishlist.Items = append(wishlist.Items, models.WishlistItem{BookID: item.BookID, AddedAt: item.AddedAt})
+			} else if item.AddedAt.Before(wishlist.Items[i].AddedAt) {
+				wishlist.Items[i].AddedAt = item.AddedAt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.wishlistRepo.Delete(fromID); err != nil {
+		return err
+	}
+	s.record(models.AuditDelete, fromID, from, nil)
+	return nil
+}
+
+// loadWishlist returns a copy of the stored wishlist, or a new empty one,
+// that can be changed without touching the store
+func (s *WishlistService) loadWishlist(customerID int) (models.Wishlist, error) {
+	if _, err := s.customerRepo.Get(customerID); err != nil {
+		return models.Wishlist{}, ErrCustomerNotFound
+	}
+	wishlist, err := s.wishlistRepo.Get(customerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Wishlist{CustomerID: customerID, Items: []models.WishlistItem{}}, nil
+	}
+	if err != nil {
+		return models.Wishlist{}, err
+	}
+	wishlist.Items = slices.Clone(wishlist.Items)
+	return wishlist, nil
+}
+
+// updateWishlist applies change to the stored wishlist and saves it,
+// starting over if another request changed the wishlist in between
+func (s *WishlistService) updateWishlist(customerID int, change func(wishlist *models.Wishlist) error) (models.Wishlist, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		wishlist, err := s.loadWishlist(customerID)
+		if err != nil {
+			return models.Wishlist{}, err
+		}
+		before := wishlist
+		before.Items = slices.Clone(wishlist.Items)
+		if err := change(&wishlist); err != nil {
+			return models.Wishlist{}, err
+		}
+
+		wishlist.UpdatedAt = time.Now()
+		saved, err := s.wishlistRepo.Save(wishlist)
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Wishlist{}, err
+		}
+		if before.Version == 0 {
+			s.record(models.AuditCreate, customerID, nil, saved)
+		} else {
+			s.record(models.AuditUpdate, customerID, before, saved)
+		}
+		return s.evaluate(saved), nil
+	}
+	return models.Wishlist{}, repositories.ErrVersionConflict
+}
+
+// record logs a change of the wishlist of a customer, which wishlists are
+// identified by
+func (s *WishlistService) record(action string, customerID int, before, after interface{}) {
+	s.auditLog.Record(s.actor, models.EntityWishlist, customerID, action, before, after)
+}
+
+// evaluate fills the titles and the stock of the books from the catalog;
+// books removed from the catalog show no copies available
+func (s *WishlistService) evaluate(wishlist models.Wishlist) models.Wishlist {
+	wishlist.Items = slices.Clone(wishlist.Items)
+	for i, item := range wishlist.Items {
+		item.Title, item.Available = "", 0
+		if book, err := s.bookRepo.Get(item.BookID); err == nil {
+			item.Title, item.Available = book.Title, book.Stock
+		}
+		wishlist.Items[i] = item
+	}
+	return wishlist
+}